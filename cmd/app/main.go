@@ -4,57 +4,244 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
-	"html/template"
+	"flag"
+	"io/fs"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
+	_ "time/tzdata" // пояса из cookie tz и в образе без tzdata
 
 	_ "github.com/lib/pq"
 
+	"learny/internal/auth"
+	"learny/internal/config"
 	httpx "learny/internal/http"
+	"learny/internal/i18n"
+	"learny/internal/logx"
+	"learny/internal/mail"
+	"learny/internal/migrate"
+	"learny/internal/ratelimit"
 	"learny/internal/repo"
+	"learny/migrations"
+	"learny/web"
 )
 
 func main() {
-	dsn := os.Getenv("DATABASE_URL")
-	if dsn == "" {
-		dsn = "postgres://postgres:postgres@db:5432/edu?sslmode=disable"
+	// -config или LEARNY_CONFIG — JSON-файл настроек; окружение его перекрывает
+	configPath := flag.String("config", os.Getenv("LEARNY_CONFIG"), "JSON-файл настроек")
+	flag.Parse()
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("config: %v", err)
+	}
+
+	// learny migrate up|down|status — миграции встроены в бинарь; нужна только БД
+	args := flag.Args()
+	if len(args) > 0 {
+		if args[0] != "migrate" {
+			log.Fatalf("неизвестная команда %q (есть только migrate)", args[0])
+		}
+		if cfg.DatabaseURL == "" {
+			log.Fatal("config: database_url не задан (DATABASE_URL)")
+		}
+		db, err := openDB(cfg)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := runMigrate(db, args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
 	}
 
-	db, err := sql.Open("postgres", dsn)
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("config:\n%v", err)
+	}
+	// дальше всё пишется через slog, в том числе log.Printf сторонних пакетов
+	logger, err := logx.New(os.Stderr, cfg.Log.Level, cfg.Log.Format)
 	if err != nil {
-		log.Fatal(err)
+		log.Fatalf("config: %v", err)
 	}
-	if err := db.Ping(); err != nil {
-		log.Fatal(err)
+	slog.SetDefault(logger)
+	slog.Info("config", "config", cfg)
+
+	db, err := openDB(cfg)
+	if err != nil {
+		fatal("database", err)
+	}
+
+	// со старой схемой не стартуем: сначала learny migrate up
+	mig, err := migrate.New(db, migrations.FS)
+	if err != nil {
+		fatal("migrations", err)
+	}
+	if err := checkSchema(mig); err != nil {
+		fatal("schema", err)
 	}
 
-	// ---- авто-сид вопросов из questions_all.json ----
-	if err := autoSeedQuestions(db); err != nil {
-		log.Printf("autoSeedQuestions error: %v", err)
+	// ---- авто-сид вопросов из seed_file ----
+	if cfg.SeedFile != "" {
+		if err := autoSeedQuestions(db, cfg.SeedFile); err != nil {
+			slog.Error("auto-seed", "err", err)
+		}
 	}
 
 	rp := repo.New(db)
 
-	// БЕЗ FuncMap, просто парсим шаблоны
-	tpl := template.Must(
-		template.New("").ParseGlob("web/templates/*.tmpl.html"),
-	)
+	auth.SetTokenKey(cfg.SessionSecret)
+	sessions := auth.NewManager(rp, cfg.CookieSecure)
+	go cleanupSessions(rp)
+
+	// каталоги сообщений: одинаковые ключи во всех языках (ключи шаблонов проверит LoadTemplates)
+	if err := i18n.Check(); err != nil {
+		fatal("i18n", err)
+	}
+	templates, err := httpx.LoadTemplates(webFS(cfg.TemplateDir, "templates"), cfg.TemplateReload)
+	if err != nil {
+		fatal("templates", err)
+	}
+
+	// ошибки уже отсеяны в Validate
+	rules, _ := cfg.RateRules()
+	proxies, _ := cfg.Proxies()
 
-	srv := &httpx.Server{DB: db, Repo: rp, T: tpl}
+	srv := &httpx.Server{
+		DB:       db,
+		Repo:     rp,
+		Sessions: sessions,
+		Mailer:   newMailer(cfg.Mail),
+		BaseURL:  cfg.BaseURL,
+
+		Templates:       templates,
+		RequireVerified: cfg.RequireVerified,
+		TwoFactorRoles:  cfg.TOTPRequiredRoles,
+
+		Limiter:        ratelimit.New(rp, rules),
+		TrustedProxies: proxies,
+		Migrator:       mig,
+
+		Metrics:      httpx.NewMetrics(db),
+		MetricsToken: cfg.MetricsToken,
+	}
 
 	mux := http.NewServeMux()
 	srv.Routes(mux)
-	mux.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("web/static"))))
+	mux.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.FS(webFS(cfg.StaticDir, "static")))))
+
+	hs := &http.Server{
+		Addr:              cfg.Listen,
+		Handler:           httpx.Instrument(srv.Metrics, mux)(httpx.LogRequests(mux, srv.ClientIP)(httpx.WithUser(sessions)(httpx.CSRF(sessions)(httpx.RequireTwoFactor(rp, srv.TwoFactorRoles...)(mux))))),
+		ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+		ReadHeaderTimeout: time.Duration(cfg.HTTP.ReadHeaderTimeout),
+		ReadTimeout:       time.Duration(cfg.HTTP.ReadTimeout),
+		WriteTimeout:      time.Duration(cfg.HTTP.WriteTimeout),
+		IdleTimeout:       time.Duration(cfg.HTTP.IdleTimeout),
+	}
+
+	// SIGTERM (docker stop, деплой): новые соединения не принимаем,
+	// начатые запросы — например, сдачу квиза — дожидаемся
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	log.Println("Listening on :8080")
-	log.Fatal(http.ListenAndServe(":8080", httpx.WithUser(mux)))
+	errc := make(chan error, 1)
+	go func() {
+		slog.Info("listening", "addr", cfg.Listen)
+		errc <- hs.ListenAndServe()
+	}()
+	select {
+	case err := <-errc:
+		fatal("listen", err)
+	case <-ctx.Done():
+	}
+	stop()
+
+	slog.Info("shutdown: ждём начатые запросы", "timeout", cfg.HTTP.ShutdownTimeout)
+	srv.Drain()
+	sctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.HTTP.ShutdownTimeout))
+	defer cancel()
+	if err := hs.Shutdown(sctx); err != nil {
+		slog.Error("shutdown", "err", err)
+	}
+	db.Close()
+	slog.Info("shutdown: done")
+}
+
+// fatal пишет ошибку в лог и завершает процесс.
+func fatal(msg string, err error) {
+	slog.Error(msg, "err", err)
+	os.Exit(1)
+}
+
+// openDB открывает пул с настройками из cfg.DB и проверяет соединение.
+func openDB(cfg config.Config) (*sql.DB, error) {
+	db, err := sql.Open("postgres", cfg.DatabaseURL)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(cfg.DB.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.DB.MaxIdleConns)
+	db.SetConnMaxLifetime(time.Duration(cfg.DB.ConnMaxLifetime))
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// webFS — каталог dir на диске или встроенный в бинарь web/<sub>, если dir пуст.
+func webFS(dir, sub string) fs.FS {
+	if dir != "" {
+		return os.DirFS(dir)
+	}
+	f, err := fs.Sub(web.FS, sub)
+	if err != nil {
+		panic(err) // sub — константа, встроенная через go:embed
+	}
+	return f
+}
+
+// newMailer: SMTP, если задан smtp_addr, иначе письма пишутся
+// в каталог mail.dir (или во временный каталог learny-mail, если и он пуст).
+func newMailer(c config.Mail) mail.Mailer {
+	if c.SMTPAddr != "" {
+		return &mail.SMTPMailer{
+			Addr:     c.SMTPAddr,
+			From:     c.From,
+			Username: c.SMTPUser,
+			Password: c.SMTPPass,
+		}
+	}
+	return &mail.FileMailer{Dir: c.Dir, From: c.From}
+}
+
+// cleanupSessions раз в час удаляет просроченные сессии.
+func cleanupSessions(rp *repo.Repo) {
+	t := time.NewTicker(time.Hour)
+	defer t.Stop()
+	for range t.C {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		if n, err := rp.DeleteExpiredSessions(ctx); err != nil {
+			slog.Error("sessions cleanup", "err", err)
+		} else if n > 0 {
+			slog.Info("sessions cleanup", "removed", n)
+		}
+		if err := rp.DeleteExpiredLoginChallenges(ctx); err != nil {
+			slog.Error("login challenges cleanup", "err", err)
+		}
+		if err := rp.DeleteExpiredRateLimits(ctx); err != nil {
+			slog.Error("rate limits cleanup", "err", err)
+		}
+		cancel()
+	}
 }
 
-// autoSeedQuestions читает questions_all.json и заливает вопросы в БД,
+// autoSeedQuestions читает path (seed_file) и заливает вопросы в БД,
 // если таблица questions пока пустая.
-func autoSeedQuestions(db *sql.DB) error {
+func autoSeedQuestions(db *sql.DB, path string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
@@ -63,11 +250,11 @@ func autoSeedQuestions(db *sql.DB) error {
 		return err
 	}
 	if cnt > 0 {
-		log.Printf("auto-seed: questions already exist (%d), skip", cnt)
+		slog.Info("auto-seed: questions already exist, skip", "count", cnt)
 		return nil
 	}
 
-	raw, err := os.ReadFile("questions_all.json")
+	raw, err := os.ReadFile(path)
 	if err != nil {
 		return err
 	}
@@ -116,6 +303,6 @@ func autoSeedQuestions(db *sql.DB) error {
 		return err
 	}
 
-	log.Printf("auto-seed: inserted %d questions from questions_all.json", len(items))
+	slog.Info("auto-seed: inserted questions", "count", len(items), "file", path)
 	return nil
 }
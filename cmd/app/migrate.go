@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"learny/internal/migrate"
+	"learny/migrations"
+)
+
+const migrateUsage = `использование:
+  learny migrate up          применить все новые миграции
+  learny migrate down [N]    откатить N последних (по умолчанию 1)
+  learny migrate status      список миграций и их состояние`
+
+// runMigrate — подкоманда learny migrate up|down|status.
+func runMigrate(db *sql.DB, args []string) error {
+	m, err := migrate.New(db, migrations.FS)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	if len(args) == 0 {
+		return fmt.Errorf("%s", migrateUsage)
+	}
+
+	switch args[0] {
+	case "up":
+		done, err := m.Up(ctx)
+		for _, mg := range done {
+			fmt.Printf("applied %03d_%s\n", mg.Version, mg.Name)
+		}
+		if err == nil && len(done) == 0 {
+			fmt.Println("schema is up to date")
+		}
+		return err
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return fmt.Errorf("down: N должно быть положительным числом")
+			}
+		}
+		done, err := m.Down(ctx, steps)
+		for _, mg := range done {
+			fmt.Printf("reverted %03d_%s\n", mg.Version, mg.Name)
+		}
+		return err
+
+	case "status":
+		sts, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED\tNOTE")
+		for _, st := range sts {
+			applied := "-"
+			if st.AppliedAt != nil {
+				applied = st.AppliedAt.Local().Format("2006-01-02 15:04:05")
+			}
+			var notes []string
+			if st.Modified {
+				notes = append(notes, "изменена после применения")
+			}
+			if st.Down == "" {
+				notes = append(notes, "без отката")
+			}
+			fmt.Fprintf(tw, "%03d\t%s\t%s\t%s\n", st.Version, st.Name, applied, strings.Join(notes, ", "))
+		}
+		return tw.Flush()
+	}
+	return fmt.Errorf("неизвестная команда migrate %q\n%s", args[0], migrateUsage)
+}
+
+// checkSchema — все ли миграции бинаря применены к базе.
+func checkSchema(m *migrate.Migrator) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return m.Check(ctx)
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"learny/internal/repo"
+)
+
+// bankFormat — формат из -format или по расширению файла.
+func bankFormat(format, path string) (string, error) {
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	}
+	switch format {
+	case "csv", "json":
+		return format, nil
+	}
+	return "", fmt.Errorf("неизвестный формат %q (csv или json)", format)
+}
+
+func cmdImportQuestions(ctx context.Context, rp *repo.Repo, args []string) error {
+	fs := flag.NewFlagSet("import-questions", flag.ExitOnError)
+	courseID := fs.Int64("course", 0, "id курса")
+	path := fs.String("file", "", "файл банка")
+	format := fs.String("format", "", "csv | json (по умолчанию — по расширению)")
+	fs.Parse(args)
+	if *courseID == 0 || *path == "" {
+		return errors.New("нужны -course и -file")
+	}
+	f, err := bankFormat(*format, *path)
+	if err != nil {
+		return err
+	}
+	if _, err := rp.GetCourse(ctx, *courseID); err != nil {
+		return fmt.Errorf("курс %d: %w", *courseID, err)
+	}
+
+	var n int
+	switch f {
+	case "csv":
+		// как /admin/questions/upload: разделитель ';', число полей не фиксировано
+		file, err := os.Open(*path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		reader := csv.NewReader(file)
+		reader.Comma = ';'
+		reader.FieldsPerRecord = -1
+		n, err = rp.ImportQuestionsCSV(ctx, reader, *courseID)
+		if err != nil {
+			return fmt.Errorf("импортировано %d, затем ошибка: %w", n, err)
+		}
+	case "json":
+		raw, err := os.ReadFile(*path)
+		if err != nil {
+			return err
+		}
+		n, err = rp.ImportQuestionsJSON(ctx, raw, *courseID)
+		if err != nil {
+			return fmt.Errorf("импортировано %d, затем ошибка: %w", n, err)
+		}
+	}
+	fmt.Printf("импортировано вопросов: %d\n", n)
+	return nil
+}
+
+func cmdExportQuestions(ctx context.Context, rp *repo.Repo, args []string) error {
+	fs := flag.NewFlagSet("export-questions", flag.ExitOnError)
+	courseID := fs.Int64("course", 0, "id курса")
+	format := fs.String("format", "json", "json | csv")
+	out := fs.String("o", "", "файл; по умолчанию stdout")
+	fs.Parse(args)
+	if *courseID == 0 {
+		return errors.New("нужен -course")
+	}
+	if _, err := bankFormat(*format, ""); err != nil {
+		return err
+	}
+
+	qs, err := rp.ExportQuestions(ctx, *courseID)
+	if err != nil {
+		return err
+	}
+	w, err := output(*out)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	if *format == "json" {
+		items := make([]repo.QuestionBankItem, 0, len(qs))
+		for _, q := range qs {
+			items = append(items, repo.BankItem(q))
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(items)
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Comma = ';'
+	for _, q := range qs {
+		rec, err := repo.QuestionCSVRecord(q)
+		if err != nil {
+			return err
+		}
+		if err := cw.Write(rec); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func cmdRecalcScores(ctx context.Context, rp *repo.Repo, args []string) error {
+	fs := flag.NewFlagSet("recalc-scores", flag.ExitOnError)
+	courseID := fs.Int64("course", 0, "только курс")
+	quizID := fs.Int64("quiz", 0, "только квиз")
+	refresh := fs.Bool("refresh", false, "оценивать по текущим вопросам (обновить снапшоты попыток)")
+	dryRun := fs.Bool("dry-run", false, "только показать изменения")
+	fs.Parse(args)
+
+	changed, err := rp.RecalcScores(ctx, optID(*courseID), optID(*quizID), *refresh, *dryRun)
+	if err != nil {
+		return err
+	}
+	for _, c := range changed {
+		old := "-"
+		if c.Old != nil {
+			old = fmt.Sprint(*c.Old)
+		}
+		fmt.Printf("attempt %d (%s): %s → %v\n", c.AttemptID, c.UserEmail, old, c.New)
+	}
+	if *dryRun {
+		fmt.Printf("изменится попыток: %d (dry-run, ничего не записано)\n", len(changed))
+	} else {
+		fmt.Printf("пересчитано попыток: %d\n", len(changed))
+	}
+	return nil
+}
+
+// attemptDump — попытка в JSON-выгрузке.
+type attemptDump struct {
+	AttemptID   int64        `json:"attempt_id"`
+	UserEmail   string       `json:"user_email"`
+	CourseID    int64        `json:"course_id"`
+	QuizID      int64        `json:"quiz_id"`
+	QuizTitle   string       `json:"quiz_title"`
+	Status      string       `json:"status"`
+	StartedAt   time.Time    `json:"started_at"`
+	FinishedAt  *time.Time   `json:"finished_at"`
+	Score       *float64     `json:"score"`
+	DurationSec *int         `json:"duration_sec"`
+	Overtime    bool         `json:"overtime"`
+	Answers     []answerDump `json:"answers,omitempty"`
+}
+
+type answerDump struct {
+	QuestionID int64           `json:"question_id"`
+	Topic      string          `json:"topic"`
+	QType      string          `json:"qtype"`
+	IsCorrect  *bool           `json:"is_correct"`
+	Answer     json.RawMessage `json:"answer"`
+}
+
+func cmdDumpAttempts(ctx context.Context, rp *repo.Repo, args []string) error {
+	fs := flag.NewFlagSet("dump-attempts", flag.ExitOnError)
+	courseID := fs.Int64("course", 0, "только курс")
+	quizID := fs.Int64("quiz", 0, "только квиз")
+	format := fs.String("format", "csv", "csv (как /admin/results/export) | json")
+	withAnswers := fs.Bool("answers", false, "json: добавить ответы по вопросам")
+	out := fs.String("o", "", "файл; по умолчанию stdout")
+	fs.Parse(args)
+	if _, err := bankFormat(*format, ""); err != nil {
+		return err
+	}
+
+	rows, err := rp.ExportAttempts(ctx, optID(*courseID), optID(*quizID))
+	if err != nil {
+		return err
+	}
+	w, err := output(*out)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	if *format == "csv" {
+		cw := csv.NewWriter(w)
+		_ = cw.Write(repo.AttemptCSVHeader)
+		for _, r0 := range rows {
+			_ = cw.Write(repo.AttemptCSVRecord(r0))
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+
+	list := make([]attemptDump, 0, len(rows))
+	for _, r0 := range rows {
+		d := attemptDump{
+			AttemptID:   r0.AttemptID,
+			UserEmail:   r0.UserEmail,
+			CourseID:    r0.CourseID,
+			QuizID:      r0.QuizID,
+			QuizTitle:   r0.QuizTitle,
+			Status:      r0.Status,
+			StartedAt:   r0.StartedAt,
+			FinishedAt:  r0.FinishedAt,
+			Score:       r0.Score,
+			DurationSec: r0.Duration,
+			Overtime:    r0.Overtime,
+		}
+		if *withAnswers {
+			_, answers, err := rp.GetAttemptWithAnswers(ctx, r0.AttemptID)
+			if err != nil {
+				return fmt.Errorf("попытка %d: %w", r0.AttemptID, err)
+			}
+			for _, a := range answers {
+				d.Answers = append(d.Answers, answerDump{
+					QuestionID: a.QuestionID,
+					Topic:      a.Topic,
+					QType:      a.QType,
+					IsCorrect:  a.IsCorrect,
+					Answer:     a.Answer,
+				})
+			}
+		}
+		list = append(list, d)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(list)
+}
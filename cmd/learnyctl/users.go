@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"learny/internal/repo"
+	"learny/internal/util"
+)
+
+// minPassword — как в веб-форме регистрации.
+const minPassword = 8
+
+func cmdCreateAdmin(ctx context.Context, rp *repo.Repo, args []string) error {
+	fs := flag.NewFlagSet("create-admin", flag.ExitOnError)
+	email := fs.String("email", "", "email администратора")
+	passwordStdin := fs.Bool("password-stdin", false, "прочитать пароль из stdin (иначе LEARNY_PASSWORD, иначе сгенерировать)")
+	fs.Parse(args)
+	if *email == "" {
+		return errors.New("нужен -email")
+	}
+
+	if _, err := rp.FindUserByEmail(ctx, *email); err == nil {
+		return fmt.Errorf("%s уже есть: используйте set-role и reset-password", *email)
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	pw, generated, err := passwordOrGenerate(*passwordStdin)
+	if err != nil {
+		return err
+	}
+	hash, err := util.HashPassword(pw)
+	if err != nil {
+		return err
+	}
+	id, err := rp.CreateAdmin(ctx, *email, hash)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("создан администратор %s (id %d)\n", *email, id)
+	if generated {
+		fmt.Printf("пароль: %s\n", pw)
+	}
+	return nil
+}
+
+func cmdResetPassword(ctx context.Context, rp *repo.Repo, args []string) error {
+	fs := flag.NewFlagSet("reset-password", flag.ExitOnError)
+	email := fs.String("email", "", "email пользователя")
+	passwordStdin := fs.Bool("password-stdin", false, "прочитать пароль из stdin (иначе LEARNY_PASSWORD, иначе сгенерировать)")
+	disable2FA := fs.Bool("disable-2fa", false, "заодно выключить 2FA (потерян телефон и коды восстановления)")
+	fs.Parse(args)
+	if *email == "" {
+		return errors.New("нужен -email")
+	}
+
+	u, err := rp.FindUserByEmail(ctx, *email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("пользователь %s не найден", *email)
+	}
+	if err != nil {
+		return err
+	}
+	pw, generated, err := passwordOrGenerate(*passwordStdin)
+	if err != nil {
+		return err
+	}
+	hash, err := util.HashPassword(pw)
+	if err != nil {
+		return err
+	}
+	if err := rp.UpdateUserPass(ctx, u.ID, hash); err != nil {
+		return err
+	}
+	// как при сбросе по ссылке: старые сессии недействительны, блокировка снята
+	if _, err := rp.DeleteUserSessions(ctx, u.ID, 0); err != nil {
+		return err
+	}
+	if err := rp.UnlockUser(ctx, u.ID); err != nil {
+		return err
+	}
+	if *disable2FA {
+		if err := rp.DisableTOTP(ctx, u.ID); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("пароль %s изменён, сессии завершены\n", u.Email)
+	if generated {
+		fmt.Printf("пароль: %s\n", pw)
+	}
+	return nil
+}
+
+func cmdSetRole(ctx context.Context, rp *repo.Repo, args []string) error {
+	fs := flag.NewFlagSet("set-role", flag.ExitOnError)
+	email := fs.String("email", "", "email пользователя")
+	role := fs.String("role", "", "student | teacher | admin")
+	fs.Parse(args)
+	if *email == "" || *role == "" {
+		return errors.New("нужны -email и -role")
+	}
+
+	u, err := rp.FindUserByEmail(ctx, *email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("пользователь %s не найден", *email)
+	}
+	if err != nil {
+		return err
+	}
+	if err := rp.UpdateUserRole(ctx, u.ID, *role); err != nil {
+		return err
+	}
+	fmt.Printf("%s: %s → %s\n", u.Email, u.Role, *role)
+	return nil
+}
+
+func cmdListUsers(ctx context.Context, rp *repo.Repo, args []string) error {
+	fs := flag.NewFlagSet("list-users", flag.ExitOnError)
+	fs.Parse(args)
+
+	users, err := rp.ListUsers(ctx)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tEMAIL\tROLE\tFLAGS")
+	for _, u := range users {
+		var flags []string
+		if !u.Verified() {
+			flags = append(flags, "не подтверждён")
+		}
+		if u.TwoFactor() {
+			flags = append(flags, "2FA")
+		}
+		if u.Locked() {
+			flags = append(flags, "заблокирован до "+u.LockedUntil.Local().Format("2006-01-02 15:04"))
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", u.ID, u.Email, u.Role, strings.Join(flags, ", "))
+	}
+	return tw.Flush()
+}
+
+// passwordOrGenerate — заданный пароль (не короче minPassword) или случайный.
+// Пароль берётся из stdin (первая строка) или LEARNY_PASSWORD, но не из
+// флага: аргументы команды видны в ps и остаются в истории shell.
+func passwordOrGenerate(fromStdin bool) (string, bool, error) {
+	pw := os.Getenv("LEARNY_PASSWORD")
+	if fromStdin {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return "", false, err
+		}
+		if pw = strings.TrimRight(line, "\r\n"); pw == "" {
+			return "", false, errors.New("-password-stdin: пустой пароль")
+		}
+	}
+	if pw != "" {
+		if len(pw) < minPassword {
+			return "", false, fmt.Errorf("пароль короче %d символов", minPassword)
+		}
+		return pw, false, nil
+	}
+	const alphabet = "abcdefghijkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	b := make([]byte, 16)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(alphabet))))
+		if err != nil {
+			return "", false, err
+		}
+		b[i] = alphabet[n.Int64()]
+	}
+	return string(b), true, nil
+}
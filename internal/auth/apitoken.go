@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"learny/internal/repo"
+)
+
+// Права (scopes) персональных API-токенов.
+const (
+	ScopeCoursesRead    = "courses:read"
+	ScopeCoursesWrite   = "courses:write"
+	ScopeQuizzesRead    = "quizzes:read"
+	ScopeQuizzesWrite   = "quizzes:write"
+	ScopeQuestionsRead  = "questions:read"
+	ScopeQuestionsWrite = "questions:write"
+	ScopeAttemptsRead   = "attempts:read"
+	ScopeAttemptsWrite  = "attempts:write"
+	ScopeResultsRead    = "results:read"
+)
+
+// Scope — право токена и роли, которым его можно выдать (пусто — всем).
+// Описание для страницы настроек — в каталоге сообщений, ключ scope.<Name>.
+type Scope struct {
+	Name  string
+	Roles []string
+}
+
+var staff = []string{"teacher", "admin"}
+
+// Scopes — все права, которые можно выдать токену.
+var Scopes = []Scope{
+	{ScopeCoursesRead, nil},
+	{ScopeQuizzesRead, nil},
+	{ScopeAttemptsRead, nil},
+	{ScopeAttemptsWrite, nil},
+	{ScopeCoursesWrite, staff},
+	{ScopeQuizzesWrite, staff},
+	{ScopeQuestionsRead, staff},
+	{ScopeQuestionsWrite, staff},
+	{ScopeResultsRead, staff},
+}
+
+// AllowedFor — доступно ли право пользователю с ролью role.
+func (s Scope) AllowedFor(role string) bool {
+	if len(s.Roles) == 0 {
+		return true
+	}
+	for _, r := range s.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// ScopesFor — права, которые может выдать себе пользователь с ролью role.
+func ScopesFor(role string) []Scope {
+	var out []Scope
+	for _, s := range Scopes {
+		if s.AllowedFor(role) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// ScopeAllowed — известно ли право и доступно ли оно роли.
+func ScopeAllowed(name, role string) bool {
+	for _, s := range Scopes {
+		if s.Name == name {
+			return s.AllowedFor(role)
+		}
+	}
+	return false
+}
+
+// APITokenPrefix помогает узнать токен Learny в логах и сканерах секретов.
+const APITokenPrefix = "lrn_"
+
+const ctxAPIToken ctxKey = ctxCSRF + 1
+
+// NewAPIToken — новый токен с префиксом.
+func NewAPIToken() (string, error) {
+	t, err := NewToken()
+	if err != nil {
+		return "", err
+	}
+	return APITokenPrefix + t, nil
+}
+
+// BearerToken — токен из заголовка Authorization: Bearer.
+func BearerToken(r *http.Request) (string, bool) {
+	h := r.Header.Get("Authorization")
+	scheme, token, ok := strings.Cut(h, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+func WithAPIToken(ctx context.Context, t *repo.APITokenRow) context.Context {
+	return context.WithValue(ctx, ctxAPIToken, t)
+}
+
+// CurrentAPIToken — токен, которым авторизован запрос (его кладёт RequireScope).
+func CurrentAPIToken(r *http.Request) (*repo.APITokenRow, bool) {
+	t, ok := r.Context().Value(ctxAPIToken).(*repo.APITokenRow)
+	return t, ok && t != nil
+}
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"net/http"
+	"time"
+
+	"learny/internal/logx"
+	"learny/internal/repo"
+)
+
+// Второй шаг входа: после верного пароля пользователь с 2FA получает не сессию,
+// а короткоживущий челлендж (cookie mfa), который обменивается на сессию по коду.
+const (
+	challengeCookie = "mfa"
+	ChallengeTTL    = 5 * time.Minute
+)
+
+// BeginChallenge заводит челлендж для пользователя и выдаёт cookie.
+func (m *Manager) BeginChallenge(w http.ResponseWriter, r *http.Request, userID int64) error {
+	token, err := NewToken()
+	if err != nil {
+		return err
+	}
+	expires := time.Now().Add(ChallengeTTL)
+	if err := m.Repo.CreateLoginChallenge(r.Context(), userID, HashToken(token), expires); err != nil {
+		return err
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     challengeCookie,
+		Value:    token,
+		Path:     "/login",
+		Expires:  expires,
+		MaxAge:   int(ChallengeTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   m.Secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+// ChallengeUser — чей челлендж в cookie. Каждый вызов расходует одну попытку ввода кода.
+func (m *Manager) ChallengeUser(r *http.Request) (int64, error) {
+	c, err := r.Cookie(challengeCookie)
+	if err != nil || c.Value == "" {
+		return 0, repo.ErrTokenInvalid
+	}
+	return m.Repo.LoginChallengeUser(r.Context(), HashToken(c.Value))
+}
+
+// EndChallenge удаляет челлендж (после успешного входа или отмены).
+func (m *Manager) EndChallenge(w http.ResponseWriter, r *http.Request) {
+	if c, err := r.Cookie(challengeCookie); err == nil && c.Value != "" {
+		if err := m.Repo.DeleteLoginChallenge(r.Context(), HashToken(c.Value)); err != nil {
+			logx.Logger(r.Context()).Warn("delete login challenge", "err", err)
+		}
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     challengeCookie,
+		Value:    "",
+		Path:     "/login",
+		HttpOnly: true,
+		Secure:   m.Secure,
+		MaxAge:   -1,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+)
+
+// Имя поля формы и заголовка с CSRF-токеном.
+const (
+	CSRFField  = "csrf_token"
+	CSRFHeader = "X-CSRF-Token"
+)
+
+// для анонимных форм (вход, регистрация) токен живёт в отдельной cookie
+const csrfCookie = "csrf"
+
+const ctxCSRF ctxKey = ctxSession + 1
+
+// CSRFToken возвращает ожидаемый токен для запроса: у залогиненного —
+// токен его сессии, у анонима — из cookie csrf (выдаёт её при отсутствии).
+func (m *Manager) CSRFToken(w http.ResponseWriter, r *http.Request) (string, error) {
+	if s, ok := CurrentSession(r); ok && s.CSRFToken != "" {
+		return s.CSRFToken, nil
+	}
+	if c, err := r.Cookie(csrfCookie); err == nil && c.Value != "" {
+		return c.Value, nil
+	}
+	token, err := NewToken()
+	if err != nil {
+		return "", err
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookie,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   m.Secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return token, nil
+}
+
+// ValidCSRF сравнивает присланный токен (поле формы или заголовок) с ожидаемым.
+func ValidCSRF(r *http.Request, expected string) bool {
+	got := r.Header.Get(CSRFHeader)
+	if got == "" {
+		got = r.FormValue(CSRFField)
+	}
+	return got != "" && subtle.ConstantTimeCompare([]byte(got), []byte(expected)) == 1
+}
+
+// WithCSRF кладёт токен в контекст, чтобы render мог отдать его шаблонам.
+func WithCSRF(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, ctxCSRF, token)
+}
+
+func CSRFFromContext(r *http.Request) string {
+	s, _ := r.Context().Value(ctxCSRF).(string)
+	return s
+}
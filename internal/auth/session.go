@@ -1,42 +1,185 @@
 package auth
 
 import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"net/http"
-	"strconv"
+	"time"
+
+	"learny/internal/logx"
+	"learny/internal/repo"
 )
 
 const cookieName = "sid"
 
-// Демоверсия: просто кладём userID в cookie (в проде использовать подпись/шифрование).
-func SetSession(w http.ResponseWriter, userID int64) {
+// DefaultTTL — срок жизни сессии без активности.
+const DefaultTTL = 7 * 24 * time.Hour
+
+// как часто обновлять last_seen_at, чтобы не писать в БД на каждый запрос
+const touchEvery = time.Minute
+
+type ctxKey int
+
+const ctxSession ctxKey = iota + 1
+
+// Manager — серверные сессии: в cookie лежит случайный непрозрачный токен,
+// в таблице sessions — его sha256, срок действия и метаданные устройства.
+type Manager struct {
+	Repo   *repo.Repo
+	TTL    time.Duration // скользящий срок: продлевается при активности
+	Secure bool          // выставлять cookie с флагом Secure (только HTTPS)
+}
+
+func NewManager(rp *repo.Repo, secure bool) *Manager {
+	return &Manager{Repo: rp, TTL: DefaultTTL, Secure: secure}
+}
+
+// tokenKey — ключ HMAC для HashToken (SESSION_SECRET); пустой — простой sha256.
+var tokenKey []byte
+
+// SetTokenKey задаёт ключ HashToken. Вызывается один раз при старте,
+// до обработки запросов.
+func SetTokenKey(key string) { tokenKey = []byte(key) }
+
+// HashToken — sha256 (или HMAC-SHA256 с ключом SetTokenKey) токена;
+// в БД храним только его.
+func HashToken(token string) string {
+	if len(tokenKey) > 0 {
+		mac := hmac.New(sha256.New, tokenKey)
+		mac.Write([]byte(token))
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewToken — случайный токен (32 байта, base64url).
+func NewToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func (m *Manager) setCookie(w http.ResponseWriter, token string, expires time.Time) {
 	http.SetCookie(w, &http.Cookie{
 		Name:     cookieName,
-		Value:    strconv.FormatInt(userID, 10),
+		Value:    token,
 		Path:     "/",
+		Expires:  expires,
+		MaxAge:   int(time.Until(expires).Seconds()),
 		HttpOnly: true,
+		Secure:   m.Secure,
 		SameSite: http.SameSiteLaxMode,
 	})
 }
 
-func ClearSession(w http.ResponseWriter) {
+func (m *Manager) clearCookie(w http.ResponseWriter) {
 	http.SetCookie(w, &http.Cookie{
 		Name:     cookieName,
 		Value:    "",
 		Path:     "/",
 		HttpOnly: true,
+		Secure:   m.Secure,
 		MaxAge:   -1,
 		SameSite: http.SameSiteLaxMode,
 	})
 }
 
-func CurrentUserID(r *http.Request) (int64, bool) {
+// Start создаёт новую сессию пользователя и выдаёт cookie.
+// Сессия из текущей cookie (если была) отзывается — защита от фиксации сессии.
+func (m *Manager) Start(w http.ResponseWriter, r *http.Request, userID int64, ip string) error {
+	if c, err := r.Cookie(cookieName); err == nil && c.Value != "" {
+		if err := m.Repo.DeleteSessionByTokenHash(r.Context(), HashToken(c.Value)); err != nil {
+			logx.Logger(r.Context()).Warn("revoke previous session", "err", err)
+		}
+	}
+
+	token, err := NewToken()
+	if err != nil {
+		return err
+	}
+	csrf, err := NewToken()
+	if err != nil {
+		return err
+	}
+	s := &repo.SessionRow{
+		UserID:    userID,
+		TokenHash: HashToken(token),
+		CSRFToken: csrf,
+		ExpiresAt: time.Now().Add(m.TTL),
+		IP:        ip,
+		UserAgent: r.UserAgent(),
+	}
+	if err := m.Repo.CreateSession(r.Context(), s); err != nil {
+		return err
+	}
+	m.setCookie(w, token, s.ExpiresAt)
+	return nil
+}
+
+// Resolve находит действующую сессию по cookie. Если до истечения осталось
+// меньше половины срока — продлевает её и перевыдаёт cookie.
+func (m *Manager) Resolve(w http.ResponseWriter, r *http.Request) (*repo.SessionRow, bool) {
 	c, err := r.Cookie(cookieName)
 	if err != nil || c.Value == "" {
-		return 0, false
+		return nil, false
 	}
-	id, err := strconv.ParseInt(c.Value, 10, 64)
+	s, err := m.Repo.SessionByTokenHash(r.Context(), HashToken(c.Value))
 	if err != nil {
-		return 0, false
+		return nil, false
+	}
+
+	now := time.Now()
+	switch {
+	case s.ExpiresAt.Sub(now) < m.TTL/2:
+		s.ExpiresAt = now.Add(m.TTL)
+		s.LastSeenAt = now
+		if err := m.Repo.TouchSession(r.Context(), s.ID, s.LastSeenAt, s.ExpiresAt); err == nil {
+			m.setCookie(w, c.Value, s.ExpiresAt)
+		}
+	case now.Sub(s.LastSeenAt) > touchEvery:
+		s.LastSeenAt = now
+		if err := m.Repo.TouchSession(r.Context(), s.ID, s.LastSeenAt, s.ExpiresAt); err != nil {
+			logx.Logger(r.Context()).Warn("touch session", "err", err)
+		}
+	}
+	return s, true
+}
+
+// End отзывает текущую сессию на сервере и удаляет cookie.
+func (m *Manager) End(w http.ResponseWriter, r *http.Request) error {
+	var err error
+	if c, e := r.Cookie(cookieName); e == nil && c.Value != "" {
+		err = m.Repo.DeleteSessionByTokenHash(r.Context(), HashToken(c.Value))
+	}
+	m.clearCookie(w)
+	return err
+}
+
+// WithSession кладёт найденную сессию в контекст запроса.
+func WithSession(ctx context.Context, s *repo.SessionRow) context.Context {
+	return context.WithValue(ctx, ctxSession, s)
+}
+
+// CurrentSession — сессия текущего запроса (её кладёт middleware WithUser).
+func CurrentSession(r *http.Request) (*repo.SessionRow, bool) {
+	s, ok := r.Context().Value(ctxSession).(*repo.SessionRow)
+	return s, ok && s != nil
+}
+
+// CurrentUserID — пользователь по сессии или по API-токену.
+func CurrentUserID(r *http.Request) (int64, bool) {
+	if s, ok := CurrentSession(r); ok {
+		return s.UserID, true
+	}
+	if t, ok := CurrentAPIToken(r); ok {
+		return t.UserID, true
 	}
-	return id, true
+	return 0, false
 }
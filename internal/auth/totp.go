@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP (RFC 6238): 6 цифр, шаг 30 секунд, HMAC-SHA1 — как у Google Authenticator и аналогов.
+const (
+	totpStep   = 30
+	totpDigits = 6
+	totpSkew   = 1 // допускаем ±1 шаг на рассинхрон часов
+)
+
+var b32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewTOTPSecret — случайный секрет (160 бит) в base32.
+func NewTOTPSecret() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return b32.EncodeToString(b), nil
+}
+
+// TOTPStep — номер 30-секундного шага для момента t.
+func TOTPStep(t time.Time) int64 { return t.Unix() / totpStep }
+
+func totpCode(key []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	off := sum[len(sum)-1] & 0x0f
+	v := binary.BigEndian.Uint32(sum[off:off+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, v%1_000_000)
+}
+
+// VerifyTOTP проверяет код и возвращает шаг, на котором он совпал
+// (его надо запомнить, чтобы один и тот же код нельзя было использовать дважды).
+func VerifyTOTP(secret, code string, now time.Time) (int64, bool) {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(code) != totpDigits {
+		return 0, false
+	}
+	key, err := b32.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return 0, false
+	}
+	cur := TOTPStep(now)
+	for d := int64(-totpSkew); d <= totpSkew; d++ {
+		if hmac.Equal([]byte(totpCode(key, cur+d)), []byte(code)) {
+			return cur + d, true
+		}
+	}
+	return 0, false
+}
+
+// TOTPURI — otpauth:// ссылка для приложений-аутентификаторов.
+func TOTPURI(issuer, account, secret string) string {
+	v := url.Values{}
+	v.Set("secret", secret)
+	v.Set("issuer", issuer)
+	v.Set("digits", fmt.Sprint(totpDigits))
+	v.Set("period", fmt.Sprint(totpStep))
+	return "otpauth://totp/" + url.PathEscape(issuer+":"+account) + "?" + v.Encode()
+}
+
+// NewRecoveryCodes — n одноразовых кодов восстановления вида xxxxx-xxxxx.
+func NewRecoveryCodes(n int) ([]string, error) {
+	const alphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+	out := make([]string, 0, n)
+	buf := make([]byte, 10)
+	for i := 0; i < n; i++ {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		var b strings.Builder
+		for j, c := range buf {
+			if j == 5 {
+				b.WriteByte('-')
+			}
+			b.WriteByte(alphabet[int(c)%len(alphabet)])
+		}
+		out = append(out, b.String())
+	}
+	return out, nil
+}
+
+// NormalizeRecoveryCode приводит введённый код к виду, в котором он хэшировался.
+func NormalizeRecoveryCode(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
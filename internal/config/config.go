@@ -0,0 +1,399 @@
+// Package config — настройки сервера: JSON-файл, поверх него переменные
+// окружения, затем проверка. Всё, что раньше читалось через os.Getenv
+// по месту, теперь приходит отсюда.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"learny/internal/logx"
+	"learny/internal/ratelimit"
+)
+
+// Config — все настройки. Тег json — ключ в файле, env — переменная
+// окружения (перекрывает файл), secret — значение не печатается.
+type Config struct {
+	Listen  string `json:"listen_addr" env:"LISTEN_ADDR"`
+	BaseURL string `json:"base_url" env:"BASE_URL"` // внешний адрес для ссылок в письмах
+	HTTP    HTTP   `json:"http"`
+	Log     Log    `json:"log"`
+
+	DatabaseURL string `json:"database_url" env:"DATABASE_URL" secret:"dsn"`
+	DB          DB     `json:"db"`
+
+	// SessionSecret — ключ HMAC для хешей токенов (сессии, ссылки, API-токены).
+	// Пусто — простой sha256, как раньше. Смена ключа завершает все сессии
+	// и отзывает ссылки и API-токены.
+	SessionSecret string `json:"session_secret" env:"SESSION_SECRET" secret:"true"`
+	CookieSecure  bool   `json:"cookie_secure" env:"COOKIE_SECURE"` // cookie только по HTTPS (за TLS-прокси)
+
+	// Шаблоны и статика встроены в бинарь; каталоги на диске — для разработки.
+	// TemplateReload — перечитывать шаблон страницы при каждом показе (нужен template_dir).
+	TemplateDir    string `json:"template_dir" env:"TEMPLATE_DIR"`
+	TemplateReload bool   `json:"template_reload" env:"TEMPLATE_RELOAD"`
+	StaticDir      string `json:"static_dir" env:"STATIC_DIR"`
+	SeedFile       string `json:"seed_file" env:"SEED_FILE"` // вопросы для пустой БД; пусто — не сидировать
+
+	RequireVerified   bool     `json:"require_verified" env:"REQUIRE_VERIFIED"`       // квизы только для подтвердивших email
+	TOTPRequiredRoles []string `json:"totp_required_roles" env:"TOTP_REQUIRED_ROLES"` // этим ролям 2FA обязательна
+	RateLimits        string   `json:"rate_limits" env:"RATE_LIMITS"`                 // "login=5/15m,register=10/1h"
+	TrustedProxies    string   `json:"trusted_proxies" env:"TRUSTED_PROXIES"`         // "10.0.0.0/8,127.0.0.1"
+
+	Mail Mail `json:"mail"`
+
+	// MetricsToken — если задан, /metrics отдаётся только с Authorization: Bearer <token>
+	MetricsToken string `json:"metrics_token" env:"METRICS_TOKEN" secret:"true"`
+}
+
+// HTTP — таймауты http.Server и время на остановку по SIGTERM.
+type HTTP struct {
+	ReadHeaderTimeout Duration `json:"read_header_timeout" env:"HTTP_READ_HEADER_TIMEOUT"`
+	ReadTimeout       Duration `json:"read_timeout" env:"HTTP_READ_TIMEOUT"`   // всё тело запроса, включая загрузку CSV
+	WriteTimeout      Duration `json:"write_timeout" env:"HTTP_WRITE_TIMEOUT"` // весь ответ, включая выгрузки
+	IdleTimeout       Duration `json:"idle_timeout" env:"HTTP_IDLE_TIMEOUT"`
+	ShutdownTimeout   Duration `json:"shutdown_timeout" env:"HTTP_SHUTDOWN_TIMEOUT"` // сколько ждать начатые запросы
+}
+
+// Log — журнал log/slog.
+type Log struct {
+	Level  string `json:"level" env:"LOG_LEVEL"`   // debug | info | warn | error
+	Format string `json:"format" env:"LOG_FORMAT"` // text | json
+}
+
+// DB — пул соединений database/sql.
+type DB struct {
+	MaxOpenConns    int      `json:"max_open_conns" env:"DB_MAX_OPEN_CONNS"`
+	MaxIdleConns    int      `json:"max_idle_conns" env:"DB_MAX_IDLE_CONNS"`
+	ConnMaxLifetime Duration `json:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME"`
+}
+
+// Mail — SMTP, если задан SMTPAddr, иначе письма пишутся в Dir
+// (или во временный каталог learny-mail, если и он пуст).
+type Mail struct {
+	From     string `json:"from" env:"MAIL_FROM"`
+	SMTPAddr string `json:"smtp_addr" env:"SMTP_ADDR"`
+	SMTPUser string `json:"smtp_user" env:"SMTP_USER"`
+	SMTPPass string `json:"smtp_pass" env:"SMTP_PASS" secret:"true"`
+	Dir      string `json:"dir" env:"MAIL_DIR"`
+}
+
+// Duration — time.Duration, в файле и окружении строкой: "30m", "1h".
+type Duration time.Duration
+
+func (d Duration) String() string { return time.Duration(d).String() }
+
+func (d Duration) MarshalText() ([]byte, error) { return []byte(d.String()), nil }
+
+func (d *Duration) UnmarshalText(b []byte) error {
+	v, err := time.ParseDuration(string(b))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Default — значения, если ни файл, ни окружение их не задали.
+// DSN по умолчанию нет: его нужно указать явно.
+func Default() Config {
+	return Config{
+		Listen:  ":8080",
+		BaseURL: "http://localhost:8080",
+		HTTP: HTTP{
+			ReadHeaderTimeout: Duration(5 * time.Second),
+			ReadTimeout:       Duration(30 * time.Second),
+			WriteTimeout:      Duration(60 * time.Second),
+			IdleTimeout:       Duration(2 * time.Minute),
+			ShutdownTimeout:   Duration(30 * time.Second),
+		},
+		Log:      Log{Level: "info", Format: "text"},
+		DB:       DB{MaxOpenConns: 20, MaxIdleConns: 5, ConnMaxLifetime: Duration(30 * time.Minute)},
+		SeedFile: "questions_all.json",
+		Mail:     Mail{From: "Learny <no-reply@learny.local>"},
+	}
+}
+
+// Load — умолчания, затем файл path (если задан), затем окружение.
+// Проверку делает Validate.
+func Load(path string) (Config, error) {
+	c := Default()
+	if path != "" {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return c, err
+		}
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&c); err != nil {
+			return c, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if err := applyEnv(reflect.ValueOf(&c).Elem()); err != nil {
+		return c, err
+	}
+	c.BaseURL = strings.TrimRight(c.BaseURL, "/")
+	return c, nil
+}
+
+var textUnmarshaler = reflect.TypeOf((*interface{ UnmarshalText([]byte) error })(nil)).Elem()
+
+// applyEnv перекрывает поля с тегом env заданными переменными окружения.
+// Заданная, но пустая переменная тоже перекрывает (SEED_FILE= — без сида).
+func applyEnv(v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f, fv := t.Field(i), v.Field(i)
+		name := f.Tag.Get("env")
+		if name == "" {
+			if f.Type.Kind() == reflect.Struct {
+				if err := applyEnv(fv); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		s, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		if err := setValue(fv, strings.TrimSpace(s)); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func setValue(fv reflect.Value, s string) error {
+	if fv.Addr().Type().Implements(textUnmarshaler) {
+		if s == "" {
+			fv.Set(reflect.Zero(fv.Type()))
+			return nil
+		}
+		return fv.Addr().Interface().(interface{ UnmarshalText([]byte) error }).UnmarshalText([]byte(s))
+	}
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(s)
+	case reflect.Bool:
+		if s == "" {
+			fv.SetBool(false)
+			return nil
+		}
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(n))
+	case reflect.Slice:
+		fv.Set(reflect.ValueOf(SplitList(s)))
+	default:
+		return fmt.Errorf("неподдерживаемый тип %s", fv.Type())
+	}
+	return nil
+}
+
+// SplitList разбирает список через запятую, пропуская пустые элементы.
+func SplitList(s string) []string {
+	var out []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// Validate проверяет настройки целиком и возвращает все ошибки разом.
+func (c Config) Validate() error {
+	var errs []error
+	add := func(format string, args ...any) { errs = append(errs, fmt.Errorf(format, args...)) }
+
+	if _, _, err := net.SplitHostPort(c.Listen); err != nil {
+		add("listen_addr %q: %v", c.Listen, err)
+	}
+	if u, err := url.Parse(c.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		add("base_url %q: нужен абсолютный http(s) адрес", c.BaseURL)
+	}
+	h := c.HTTP
+	if h.ReadHeaderTimeout < 0 || h.ReadTimeout < 0 || h.WriteTimeout < 0 || h.IdleTimeout < 0 {
+		add("http: таймауты не могут быть отрицательными")
+	}
+	if h.ShutdownTimeout <= 0 {
+		add("http.shutdown_timeout должен быть больше нуля")
+	}
+	if _, err := logx.New(io.Discard, c.Log.Level, c.Log.Format); err != nil {
+		add("log: %v", err)
+	}
+	if c.DatabaseURL == "" {
+		add("database_url не задан (DATABASE_URL)")
+	}
+	if c.DB.MaxOpenConns < 0 || c.DB.MaxIdleConns < 0 || c.DB.ConnMaxLifetime < 0 {
+		add("db: размеры пула и время жизни не могут быть отрицательными")
+	}
+	if c.DB.MaxOpenConns > 0 && c.DB.MaxIdleConns > c.DB.MaxOpenConns {
+		add("db: max_idle_conns (%d) больше max_open_conns (%d)", c.DB.MaxIdleConns, c.DB.MaxOpenConns)
+	}
+	if c.SessionSecret != "" && len(c.SessionSecret) < 32 {
+		add("session_secret: нужно не меньше 32 символов")
+	}
+	for _, d := range [][2]string{{"template_dir", c.TemplateDir}, {"static_dir", c.StaticDir}} {
+		if d[1] == "" {
+			continue
+		}
+		if st, err := os.Stat(d[1]); err != nil || !st.IsDir() {
+			add("%s %q: каталог не найден", d[0], d[1])
+		}
+	}
+	if c.TemplateReload && c.TemplateDir == "" {
+		add("template_reload: нужен template_dir (встроенные шаблоны не меняются)")
+	}
+	if c.SeedFile != "" {
+		if _, err := os.Stat(c.SeedFile); err != nil {
+			add("seed_file: %v", err)
+		}
+	}
+	for _, role := range c.TOTPRequiredRoles {
+		if role != "student" && role != "teacher" && role != "admin" {
+			add("totp_required_roles: неизвестная роль %q", role)
+		}
+	}
+	if _, err := c.RateRules(); err != nil {
+		add("rate_limits: %v", err)
+	}
+	if _, err := c.Proxies(); err != nil {
+		add("trusted_proxies: %v", err)
+	}
+	if c.Mail.From == "" {
+		add("mail.from не задан")
+	}
+	return errors.Join(errs...)
+}
+
+// RateRules — лимиты по умолчанию с переопределениями из RateLimits.
+func (c Config) RateRules() (ratelimit.Rules, error) {
+	rules := ratelimit.DefaultRules()
+	if err := rules.Set(c.RateLimits); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// Proxies — TrustedProxies в виде подсетей.
+func (c Config) Proxies() ([]*net.IPNet, error) {
+	return ParseTrustedProxies(c.TrustedProxies)
+}
+
+// ParseTrustedProxies разбирает список адресов/подсетей через запятую:
+// "10.0.0.0/8, 127.0.0.1". Одиночный адрес считается подсетью /32 (/128).
+func ParseTrustedProxies(s string) ([]*net.IPNet, error) {
+	var out []*net.IPNet
+	for _, p := range SplitList(s) {
+		if !strings.Contains(p, "/") {
+			ip := net.ParseIP(p)
+			if ip == nil {
+				return nil, fmt.Errorf("trusted proxy %q: неверный адрес", p)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			out = append(out, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(p)
+		if err != nil {
+			return nil, fmt.Errorf("trusted proxy %q: %w", p, err)
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
+
+// String — настройки построчно "ключ = значение"; секреты скрыты,
+// у DSN скрыт пароль.
+func (c Config) String() string {
+	var b strings.Builder
+	for _, kv := range c.describe() {
+		fmt.Fprintf(&b, "%s = %s\n", kv[0], kv[1])
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// LogValue — те же пары для slog: slog.Info("config", "config", cfg).
+func (c Config) LogValue() slog.Value {
+	pairs := c.describe()
+	attrs := make([]slog.Attr, len(pairs))
+	for i, kv := range pairs {
+		attrs[i] = slog.String(kv[0], kv[1])
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// describe — пары ключ/значение по тегам json, вложенные через точку.
+func (c Config) describe() [][2]string {
+	var out [][2]string
+	var walk func(prefix string, v reflect.Value)
+	walk = func(prefix string, v reflect.Value) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f, fv := t.Field(i), v.Field(i)
+			key := prefix + strings.Split(f.Tag.Get("json"), ",")[0]
+			if f.Type.Kind() == reflect.Struct && f.Tag.Get("env") == "" {
+				walk(key+".", fv)
+				continue
+			}
+			var val string
+			switch x := fv.Interface().(type) {
+			case []string:
+				val = strings.Join(x, ",")
+			default:
+				val = fmt.Sprint(x)
+			}
+			switch f.Tag.Get("secret") {
+			case "true":
+				if val != "" {
+					val = "xxxxx"
+				}
+			case "dsn":
+				val = RedactDSN(val)
+			}
+			out = append(out, [2]string{key, val})
+		}
+	}
+	walk("", reflect.ValueOf(c))
+	return out
+}
+
+var dsnPassword = regexp.MustCompile(`(password\s*=\s*)('[^']*'|\S+)`)
+
+// RedactDSN скрывает пароль в DSN: и в URL, и в формате "key=value".
+func RedactDSN(dsn string) string {
+	if u, err := url.Parse(dsn); err == nil && u.Scheme != "" {
+		q := u.Query()
+		if q.Has("password") {
+			q.Set("password", "xxxxx")
+			u.RawQuery = q.Encode()
+		}
+		return u.Redacted()
+	}
+	return dsnPassword.ReplaceAllString(dsn, "${1}xxxxx")
+}
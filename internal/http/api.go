@@ -0,0 +1,861 @@
+package httpx
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	a "learny/internal/auth"
+	"learny/internal/i18n"
+	"learny/internal/logx"
+	"learny/internal/ratelimit"
+	"learny/internal/repo"
+)
+
+/*** JSON API /api/v1 ***/
+
+// apiRoutes — JSON API поверх тех же методов repo.Repo, что и HTML-страницы.
+// Авторизация — Bearer-токен с нужным правом или обычная cookie-сессия.
+func (s *Server) apiRoutes(mux *routeMux) {
+	staff := []string{"teacher", "admin"}
+
+	mux.Handle("GET /api/v1/courses", s.api(a.ScopeCoursesRead, nil, s.apiListCourses))
+	mux.Handle("POST /api/v1/courses", s.api(a.ScopeCoursesWrite, staff, s.apiCreateCourse))
+	mux.Handle("GET /api/v1/courses/{id}", s.api(a.ScopeCoursesRead, nil, s.apiGetCourse))
+	mux.Handle("PATCH /api/v1/courses/{id}", s.api(a.ScopeCoursesWrite, staff, s.apiUpdateCourse))
+	mux.Handle("DELETE /api/v1/courses/{id}", s.api(a.ScopeCoursesWrite, staff, s.apiDeleteCourse))
+
+	mux.Handle("GET /api/v1/courses/{id}/quizzes", s.api(a.ScopeQuizzesRead, nil, s.apiListQuizzes))
+	mux.Handle("POST /api/v1/courses/{id}/quizzes", s.api(a.ScopeQuizzesWrite, staff, s.apiCreateQuiz))
+	mux.Handle("GET /api/v1/quizzes/{id}", s.api(a.ScopeQuizzesRead, nil, s.apiGetQuiz))
+	mux.Handle("PATCH /api/v1/quizzes/{id}", s.api(a.ScopeQuizzesWrite, staff, s.apiUpdateQuiz))
+	mux.Handle("DELETE /api/v1/quizzes/{id}", s.api(a.ScopeQuizzesWrite, staff, s.apiDeleteQuiz))
+
+	mux.Handle("GET /api/v1/courses/{id}/questions", s.api(a.ScopeQuestionsRead, staff, s.apiListQuestions))
+	mux.Handle("POST /api/v1/courses/{id}/questions", s.api(a.ScopeQuestionsWrite, staff, s.apiCreateQuestion))
+	mux.Handle("GET /api/v1/questions/{id}", s.api(a.ScopeQuestionsRead, staff, s.apiGetQuestion))
+	mux.Handle("PATCH /api/v1/questions/{id}", s.api(a.ScopeQuestionsWrite, staff, s.apiUpdateQuestion))
+	mux.Handle("DELETE /api/v1/questions/{id}", s.api(a.ScopeQuestionsWrite, staff, s.apiDeleteQuestion))
+
+	mux.Handle("POST /api/v1/quizzes/{id}/attempts", s.api(a.ScopeAttemptsWrite, nil, s.apiStartAttempt))
+	mux.Handle("GET /api/v1/attempts/{id}", s.api(a.ScopeAttemptsRead, nil, s.apiGetAttempt))
+	mux.Handle("POST /api/v1/attempts/{id}/submit", s.api(a.ScopeAttemptsWrite, nil, s.apiSubmitAttempt))
+
+	mux.Handle("GET /api/v1/results", s.api(a.ScopeResultsRead, staff, s.apiListResults))
+
+	// всё остальное под /api/ — JSON 404, а не HTML-страница
+	mux.HandleFunc("/api/", func(w http.ResponseWriter, r *http.Request) {
+		writeAPIError(w, s.locale(r), &apiError{http.StatusNotFound, "not_found", "api.no_method", nil})
+	})
+}
+
+/* ---------- ошибки и ответы ---------- */
+
+// apiError — ошибка API. Клиент всегда получает
+// {"error": {"code": "...", "message": "..."}} с соответствующим статусом;
+// message — сообщение каталога по Key на языке запроса.
+type apiError struct {
+	Status int
+	Code   string
+	Key    string
+	Args   []any
+}
+
+func (e *apiError) Error() string            { return i18n.Default(e.Key, e.Args...) }
+func (e *apiError) Message() (string, []any) { return e.Key, e.Args }
+
+func errBadRequest(key string, args ...any) error {
+	return &apiError{http.StatusBadRequest, "bad_request", key, args}
+}
+func errValidation(key string, args ...any) error {
+	return &apiError{http.StatusUnprocessableEntity, "validation_failed", key, args}
+}
+
+// errInvalid — 422 с текстом ошибки проверки из repo.
+func errInvalid(err error) error { return errValidation("api.invalid", err) }
+
+func errNotFound(key string) error { return &apiError{http.StatusNotFound, "not_found", key, nil} }
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeAPIError пишет ошибку с сообщением на языке l.
+func writeAPIError(w http.ResponseWriter, l *i18n.Locale, e *apiError) {
+	writeJSON(w, e.Status, map[string]any{
+		"error": map[string]string{"code": e.Code, "message": l.T(e.Key, e.Args...)},
+	})
+}
+
+// toAPIError переводит ошибки repo в ответы API;
+// неизвестные — 500 с записью в лог и кодом запроса в сообщении.
+func toAPIError(r *http.Request, err error) *apiError {
+	var ae *apiError
+	var denied *startDenied
+	var closed *attemptClosed
+	switch {
+	case errors.As(err, &ae):
+		return ae
+	case errors.As(err, &denied):
+		return &apiError{http.StatusConflict, "start_denied", denied.Key, denied.Args}
+	case errors.As(err, &closed):
+		return &apiError{http.StatusConflict, "attempt_closed", closed.key(), nil}
+	case errors.Is(err, sql.ErrNoRows), errors.Is(err, repo.ErrNotFound), errors.Is(err, repo.ErrAttemptNotFound):
+		return &apiError{http.StatusNotFound, "not_found", "api.object_not_found", nil}
+	case errors.Is(err, repo.ErrAttemptNotOwned):
+		return &apiError{http.StatusForbidden, "forbidden", "attempt.not_owned", nil}
+	}
+	logx.Logger(r.Context()).Error("api error", "path", r.URL.Path, "err", err)
+	if id := logx.RequestID(r.Context()); id != "" {
+		return &apiError{http.StatusInternalServerError, "internal", "error.internal_id", []any{id}}
+	}
+	return &apiError{http.StatusInternalServerError, "internal", "error.internal", nil}
+}
+
+// apiFunc — обработчик API: сам пишет успешный ответ, ошибку возвращает.
+type apiFunc func(w http.ResponseWriter, r *http.Request) error
+
+// api оборачивает обработчик: Bearer-токен с правом scope (или cookie-сессия),
+// проверка роли, перевод ошибок в JSON.
+func (s *Server) api(scope string, roles []string, h apiFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token, ok := a.BearerToken(r); ok {
+			t, status, code := authorizeBearer(s.Repo, r, token, scope)
+			if status != 0 {
+				w.Header().Set("WWW-Authenticate", `Bearer error="`+code+`", scope="`+scope+`"`)
+				writeAPIError(w, s.locale(r), &apiError{status, code, "api.token_invalid", []any{scope}})
+				return
+			}
+			logx.SetUserID(r.Context(), t.UserID)
+			r = r.WithContext(a.WithAPIToken(r.Context(), t))
+		}
+		uid, ok := a.CurrentUserID(r)
+		if !ok {
+			writeAPIError(w, s.locale(r), &apiError{http.StatusUnauthorized, "unauthorized", "api.unauthorized", nil})
+			return
+		}
+		if len(roles) > 0 {
+			role, err := s.Repo.GetUserRole(r.Context(), uid)
+			if err != nil || !containsCI(roles, role) {
+				writeAPIError(w, s.locale(r), &apiError{http.StatusForbidden, "forbidden", "api.forbidden", nil})
+				return
+			}
+		}
+		if err := h(w, r); err != nil {
+			writeAPIError(w, s.locale(r), toAPIError(r, err))
+		}
+	})
+}
+
+// decodeJSON читает тело запроса; неизвестные поля — ошибка (ловим опечатки).
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) error {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		if errors.Is(err, io.EOF) {
+			return errBadRequest("api.empty_body")
+		}
+		return errBadRequest("api.bad_json", err.Error())
+	}
+	return nil
+}
+
+func pathID(r *http.Request) (int64, error) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, errBadRequest("api.bad_id")
+	}
+	return id, nil
+}
+
+// queryID — необязательный числовой параметр запроса.
+func queryID(r *http.Request, name string) (*int64, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return nil, nil
+	}
+	id, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return nil, errBadRequest("api.bad_param", name)
+	}
+	return &id, nil
+}
+
+/* ---------- пагинация ---------- */
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 200
+)
+
+// pageParams — ?limit=&offset= с ограничениями.
+func pageParams(r *http.Request) (limit, offset int, err error) {
+	limit, offset = defaultPageLimit, 0
+	q := r.URL.Query()
+	if v := q.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 || limit > maxPageLimit {
+			return 0, 0, errBadRequest("api.bad_limit", maxPageLimit)
+		}
+	}
+	if v := q.Get("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil || offset < 0 {
+			return 0, 0, errBadRequest("api.bad_offset")
+		}
+	}
+	return limit, offset, nil
+}
+
+// listPage — единый формат списков.
+type listPage[T any] struct {
+	Items  []T `json:"items"`
+	Total  int `json:"total"`
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+}
+
+// pageOf режет уже загруженный список (для небольших таблиц).
+func pageOf[T any](all []T, limit, offset int) listPage[T] {
+	lo := min(offset, len(all))
+	hi := min(lo+limit, len(all))
+	return listPage[T]{Items: append([]T{}, all[lo:hi]...), Total: len(all), Limit: limit, Offset: offset}
+}
+
+/* ---------- представления ---------- */
+
+type courseJSON struct {
+	ID          int64  `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+}
+
+func toCourseJSON(c repo.CourseRow) courseJSON {
+	return courseJSON{ID: c.ID, Title: c.Title, Description: c.Description}
+}
+
+type quizJSON struct {
+	ID       int64           `json:"id"`
+	CourseID int64           `json:"course_id"`
+	Title    string          `json:"title"`
+	Rules    json.RawMessage `json:"rules" openapi:"QuizRules"`
+}
+
+func toQuizJSON(q repo.QuizRow) quizJSON {
+	return quizJSON{ID: q.ID, CourseID: q.CourseID, Title: q.Title, Rules: q.Rules}
+}
+
+type questionJSON struct {
+	ID         int64           `json:"id"`
+	CourseID   int64           `json:"course_id"`
+	Topic      string          `json:"topic"`
+	QType      string          `json:"qtype"`
+	Difficulty int             `json:"difficulty"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+func toQuestionJSON(q repo.QuestionRow) questionJSON {
+	return questionJSON{ID: q.ID, CourseID: q.CourseID, Topic: q.Topic, QType: q.QType, Difficulty: q.Difficulty, Payload: q.Payload}
+}
+
+// issuedQuestionJSON — вопрос в попытке, без правильных ответов.
+type issuedQuestionJSON struct {
+	ID         int64           `json:"id"`
+	Ord        int             `json:"ord"`
+	Topic      string          `json:"topic"`
+	QType      string          `json:"qtype"`
+	Difficulty int             `json:"difficulty"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+// publicPayload убирает из payload всё, что выдаёт ответ.
+func publicPayload(payload json.RawMessage) json.RawMessage {
+	var p map[string]any
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return json.RawMessage(`{}`)
+	}
+	// ключи сравниваем без учёта регистра: json.Unmarshal в структуру вопроса
+	// тоже принимает "Correct", и такой ключ из импорта выдал бы ответ
+	for k := range p {
+		for _, secret := range []string{"correct", "correct_value", "accept"} {
+			if strings.EqualFold(k, secret) {
+				delete(p, k)
+			}
+		}
+	}
+	out, _ := json.Marshal(p)
+	return out
+}
+
+// startedAttemptJSON — ответ на старт попытки: сама попытка и выданные вопросы.
+type startedAttemptJSON struct {
+	Attempt      attemptJSON          `json:"attempt"`
+	Title        string               `json:"title"`
+	TimeLimitSec int                  `json:"time_limit_sec"`
+	Questions    []issuedQuestionJSON `json:"questions"`
+}
+
+type attemptJSON struct {
+	ID         int64      `json:"id"`
+	QuizID     int64      `json:"quiz_id"`
+	Status     string     `json:"status"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+	Score      *float64   `json:"score"`
+	LateReason string     `json:"late_reason,omitempty"`
+}
+
+func toAttemptJSON(l *i18n.Locale, at *repo.AttemptInfo) attemptJSON {
+	return attemptJSON{
+		ID:         at.ID,
+		QuizID:     at.QuizID,
+		Status:     at.Status,
+		StartedAt:  at.StartedAt,
+		FinishedAt: at.FinishedAt,
+		Score:      at.Score,
+		LateReason: lateReason(l, at.Late),
+	}
+}
+
+type resultJSON struct {
+	AttemptID   int64      `json:"attempt_id"`
+	UserEmail   string     `json:"user_email"`
+	CourseID    int64      `json:"course_id"`
+	QuizID      int64      `json:"quiz_id"`
+	QuizTitle   string     `json:"quiz_title"`
+	Status      string     `json:"status"`
+	StartedAt   time.Time  `json:"started_at"`
+	FinishedAt  *time.Time `json:"finished_at"`
+	Score       *float64   `json:"score"`
+	DurationSec *int       `json:"duration_sec"`
+	Overtime    bool       `json:"overtime"`
+}
+
+/* ---------- курсы ---------- */
+
+func (s *Server) apiListCourses(w http.ResponseWriter, r *http.Request) error {
+	limit, offset, err := pageParams(r)
+	if err != nil {
+		return err
+	}
+	cs, err := s.Repo.ListCourses(r.Context())
+	if err != nil {
+		return err
+	}
+	out := make([]courseJSON, 0, len(cs))
+	for _, c := range cs {
+		out = append(out, toCourseJSON(c))
+	}
+	writeJSON(w, http.StatusOK, pageOf(out, limit, offset))
+	return nil
+}
+
+type courseInput struct {
+	Title       *string `json:"title"`
+	Description *string `json:"description"`
+}
+
+func (s *Server) apiCreateCourse(w http.ResponseWriter, r *http.Request) error {
+	var in courseInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		return err
+	}
+	if in.Title == nil || strings.TrimSpace(*in.Title) == "" {
+		return errValidation("api.title_required")
+	}
+	desc := ""
+	if in.Description != nil {
+		desc = strings.TrimSpace(*in.Description)
+	}
+	id, err := s.Repo.CreateCourse(r.Context(), strings.TrimSpace(*in.Title), desc)
+	if err != nil {
+		return err
+	}
+	c, err := s.Repo.GetCourse(r.Context(), id)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusCreated, toCourseJSON(*c))
+	return nil
+}
+
+func (s *Server) apiGetCourse(w http.ResponseWriter, r *http.Request) error {
+	id, err := pathID(r)
+	if err != nil {
+		return err
+	}
+	c, err := s.Repo.GetCourse(r.Context(), id)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, toCourseJSON(*c))
+	return nil
+}
+
+func (s *Server) apiUpdateCourse(w http.ResponseWriter, r *http.Request) error {
+	id, err := pathID(r)
+	if err != nil {
+		return err
+	}
+	var in courseInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		return err
+	}
+	if _, err := s.Repo.GetCourse(r.Context(), id); err != nil {
+		return err
+	}
+	var title, desc string
+	if in.Title != nil {
+		if title = strings.TrimSpace(*in.Title); title == "" {
+			return errValidation("api.title_empty")
+		}
+	}
+	if in.Description != nil {
+		desc = strings.TrimSpace(*in.Description)
+	}
+	if err := s.Repo.UpdateCourse(r.Context(), id, title, desc); err != nil {
+		return err
+	}
+	c, err := s.Repo.GetCourse(r.Context(), id)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, toCourseJSON(*c))
+	return nil
+}
+
+func (s *Server) apiDeleteCourse(w http.ResponseWriter, r *http.Request) error {
+	id, err := pathID(r)
+	if err != nil {
+		return err
+	}
+	if _, err := s.Repo.GetCourse(r.Context(), id); err != nil {
+		return err
+	}
+	if err := s.Repo.DeleteCourse(r.Context(), id); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+/* ---------- квизы ---------- */
+
+func (s *Server) apiListQuizzes(w http.ResponseWriter, r *http.Request) error {
+	courseID, err := pathID(r)
+	if err != nil {
+		return err
+	}
+	limit, offset, err := pageParams(r)
+	if err != nil {
+		return err
+	}
+	if _, err := s.Repo.GetCourse(r.Context(), courseID); err != nil {
+		return err
+	}
+	qs, err := s.Repo.ListQuizzesByCourse(r.Context(), courseID)
+	if err != nil {
+		return err
+	}
+	out := make([]quizJSON, 0, len(qs))
+	for _, q := range qs {
+		out = append(out, toQuizJSON(q))
+	}
+	writeJSON(w, http.StatusOK, pageOf(out, limit, offset))
+	return nil
+}
+
+type quizInput struct {
+	Title *string         `json:"title"`
+	Rules json.RawMessage `json:"rules" openapi:"QuizRules"`
+}
+
+func (s *Server) apiCreateQuiz(w http.ResponseWriter, r *http.Request) error {
+	courseID, err := pathID(r)
+	if err != nil {
+		return err
+	}
+	var in quizInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		return err
+	}
+	if in.Title == nil || strings.TrimSpace(*in.Title) == "" {
+		return errValidation("api.title_required")
+	}
+	if _, err := repo.ParseQuizRules(in.Rules); err != nil {
+		return errInvalid(err)
+	}
+	if _, err := s.Repo.GetCourse(r.Context(), courseID); err != nil {
+		return err
+	}
+	id, err := s.Repo.CreateQuiz(r.Context(), courseID, strings.TrimSpace(*in.Title), in.Rules)
+	if err != nil {
+		return err
+	}
+	q, err := s.Repo.GetQuiz(r.Context(), id)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusCreated, toQuizJSON(*q))
+	return nil
+}
+
+func (s *Server) apiGetQuiz(w http.ResponseWriter, r *http.Request) error {
+	id, err := pathID(r)
+	if err != nil {
+		return err
+	}
+	q, err := s.Repo.GetQuiz(r.Context(), id)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, toQuizJSON(*q))
+	return nil
+}
+
+func (s *Server) apiUpdateQuiz(w http.ResponseWriter, r *http.Request) error {
+	id, err := pathID(r)
+	if err != nil {
+		return err
+	}
+	var in quizInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		return err
+	}
+	if _, err := s.Repo.GetQuiz(r.Context(), id); err != nil {
+		return err
+	}
+	title := ""
+	if in.Title != nil {
+		if title = strings.TrimSpace(*in.Title); title == "" {
+			return errValidation("api.title_empty")
+		}
+	}
+	if len(in.Rules) > 0 {
+		if _, err := repo.ParseQuizRules(in.Rules); err != nil {
+			return errInvalid(err)
+		}
+	}
+	if err := s.Repo.UpdateQuiz(r.Context(), id, title, in.Rules); err != nil {
+		return err
+	}
+	q, err := s.Repo.GetQuiz(r.Context(), id)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, toQuizJSON(*q))
+	return nil
+}
+
+func (s *Server) apiDeleteQuiz(w http.ResponseWriter, r *http.Request) error {
+	id, err := pathID(r)
+	if err != nil {
+		return err
+	}
+	if _, err := s.Repo.GetQuiz(r.Context(), id); err != nil {
+		return err
+	}
+	if err := s.Repo.DeleteQuiz(r.Context(), id); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+/* ---------- вопросы ---------- */
+
+func (s *Server) apiListQuestions(w http.ResponseWriter, r *http.Request) error {
+	courseID, err := pathID(r)
+	if err != nil {
+		return err
+	}
+	limit, offset, err := pageParams(r)
+	if err != nil {
+		return err
+	}
+	if _, err := s.Repo.GetCourse(r.Context(), courseID); err != nil {
+		return err
+	}
+	q := r.URL.Query()
+	rows, total, err := s.Repo.ListQuestionsPage(r.Context(), courseID, q.Get("topic"), q.Get("qtype"), limit, offset)
+	if err != nil {
+		return err
+	}
+	out := make([]questionJSON, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, toQuestionJSON(row))
+	}
+	writeJSON(w, http.StatusOK, listPage[questionJSON]{Items: out, Total: total, Limit: limit, Offset: offset})
+	return nil
+}
+
+type questionInput struct {
+	Topic      *string         `json:"topic"`
+	QType      *string         `json:"qtype"`
+	Difficulty *int            `json:"difficulty"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+// apply накладывает присланные поля на вопрос.
+func (in questionInput) apply(q *repo.QuestionRow) {
+	if in.Topic != nil {
+		q.Topic = strings.TrimSpace(*in.Topic)
+	}
+	if in.QType != nil {
+		q.QType = *in.QType
+	}
+	if in.Difficulty != nil {
+		q.Difficulty = *in.Difficulty
+	}
+	if len(in.Payload) > 0 {
+		q.Payload = in.Payload
+	}
+}
+
+func (s *Server) apiCreateQuestion(w http.ResponseWriter, r *http.Request) error {
+	courseID, err := pathID(r)
+	if err != nil {
+		return err
+	}
+	var in questionInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		return err
+	}
+	q := repo.QuestionRow{CourseID: courseID, Difficulty: 3}
+	in.apply(&q)
+	if err := repo.ValidateQuestion(q.Topic, q.QType, q.Difficulty, q.Payload); err != nil {
+		return errInvalid(err)
+	}
+	if _, err := s.Repo.GetCourse(r.Context(), courseID); err != nil {
+		return err
+	}
+	id, err := s.Repo.CreateQuestion(r.Context(), courseID, q.Topic, q.QType, q.Difficulty, q.Payload)
+	if err != nil {
+		return err
+	}
+	created, err := s.Repo.GetQuestion(r.Context(), id)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusCreated, toQuestionJSON(*created))
+	return nil
+}
+
+// getQuestion — вопрос по id из пути (GetQuestion возвращает nil, если его нет).
+func (s *Server) getQuestion(r *http.Request) (*repo.QuestionRow, error) {
+	id, err := pathID(r)
+	if err != nil {
+		return nil, err
+	}
+	q, err := s.Repo.GetQuestion(r.Context(), id)
+	if err != nil {
+		return nil, err
+	}
+	if q == nil {
+		return nil, errNotFound("api.question_not_found")
+	}
+	return q, nil
+}
+
+func (s *Server) apiGetQuestion(w http.ResponseWriter, r *http.Request) error {
+	q, err := s.getQuestion(r)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, toQuestionJSON(*q))
+	return nil
+}
+
+func (s *Server) apiUpdateQuestion(w http.ResponseWriter, r *http.Request) error {
+	q, err := s.getQuestion(r)
+	if err != nil {
+		return err
+	}
+	var in questionInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		return err
+	}
+	in.apply(q)
+	if err := repo.ValidateQuestion(q.Topic, q.QType, q.Difficulty, q.Payload); err != nil {
+		return errInvalid(err)
+	}
+	if err := s.Repo.UpdateQuestion(r.Context(), q.ID, q.Topic, q.QType, q.Difficulty, q.Payload); err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, toQuestionJSON(*q))
+	return nil
+}
+
+func (s *Server) apiDeleteQuestion(w http.ResponseWriter, r *http.Request) error {
+	q, err := s.getQuestion(r)
+	if err != nil {
+		return err
+	}
+	if err := s.Repo.DeleteQuestion(r.Context(), q.ID); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+/* ---------- попытки ---------- */
+
+func (s *Server) apiStartAttempt(w http.ResponseWriter, r *http.Request) error {
+	quizID, err := pathID(r)
+	if err != nil {
+		return err
+	}
+	uid, _ := a.CurrentUserID(r)
+
+	if s.RequireVerified {
+		if ok, err := s.Repo.IsUserVerified(r.Context(), uid); err != nil || !ok {
+			return &apiError{http.StatusForbidden, "email_not_verified", "api.email_not_verified", nil}
+		}
+	}
+	d, err := s.Limiter.Allow(r.Context(), ratelimit.QuizStart, strconv.FormatInt(uid, 10))
+	if err != nil {
+		return err
+	}
+	if !d.Allowed {
+		s.Metrics.RateLimitHits.With(ratelimit.QuizStart).Inc()
+		w.Header().Set("Retry-After", strconv.Itoa(int(d.RetryAfter.Seconds())))
+		return &apiError{http.StatusTooManyRequests, "rate_limited", "api.rate_limited", nil}
+	}
+
+	quiz, err := s.Repo.GetQuiz(r.Context(), quizID)
+	if err != nil {
+		return err
+	}
+	st, err := s.startAttempt(r.Context(), uid, quiz)
+	if err != nil {
+		return err
+	}
+	att, err := s.Repo.GetAttempt(r.Context(), st.AttemptID)
+	if err != nil {
+		return err
+	}
+
+	qs := make([]issuedQuestionJSON, 0, len(st.Questions))
+	for i, q := range st.Questions {
+		qs = append(qs, issuedQuestionJSON{
+			ID:         q.ID,
+			Ord:        i + 1,
+			Topic:      q.Topic,
+			QType:      q.QType,
+			Difficulty: q.Difficulty,
+			Payload:    publicPayload(q.Payload),
+		})
+	}
+	writeJSON(w, http.StatusCreated, startedAttemptJSON{
+		Attempt:      toAttemptJSON(s.locale(r), att),
+		Title:        st.Title,
+		TimeLimitSec: st.Rules.TimeLimitSec,
+		Questions:    qs,
+	})
+	return nil
+}
+
+// ownAttempt — попытка по id из пути, только своя.
+func (s *Server) ownAttempt(r *http.Request) (*repo.AttemptInfo, error) {
+	id, err := pathID(r)
+	if err != nil {
+		return nil, err
+	}
+	att, err := s.Repo.GetAttempt(r.Context(), id)
+	if err != nil {
+		return nil, err
+	}
+	if uid, _ := a.CurrentUserID(r); att.UserID != uid {
+		return nil, errNotFound("api.attempt_not_found")
+	}
+	return att, nil
+}
+
+func (s *Server) apiGetAttempt(w http.ResponseWriter, r *http.Request) error {
+	att, err := s.ownAttempt(r)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, toAttemptJSON(s.locale(r), att))
+	return nil
+}
+
+// submitInput — ответы по id вопроса: {"answers": {"12": 2, "13": [0, 2], "14": "TCP"}}.
+type submitInput struct {
+	Answers map[string]json.RawMessage `json:"answers"`
+}
+
+func (s *Server) apiSubmitAttempt(w http.ResponseWriter, r *http.Request) error {
+	att, err := s.ownAttempt(r)
+	if err != nil {
+		return err
+	}
+	var in submitInput
+	if err := decodeJSON(w, r, &in); err != nil {
+		return err
+	}
+	answers := make(map[int64][]string, len(in.Answers))
+	for k, raw := range in.Answers {
+		qid, err := strconv.ParseInt(k, 10, 64)
+		if err != nil {
+			return errValidation("api.answers_key", k)
+		}
+		vals, err := repo.AnswerValues(raw)
+		if err != nil {
+			return errValidation("api.answer_invalid", k, err)
+		}
+		answers[qid] = vals
+	}
+
+	uid, _ := a.CurrentUserID(r)
+	res, err := s.submitAttempt(r.Context(), uid, att.ID, answers)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, toAttemptJSON(s.locale(r), res))
+	return nil
+}
+
+/* ---------- результаты ---------- */
+
+func (s *Server) apiListResults(w http.ResponseWriter, r *http.Request) error {
+	limit, offset, err := pageParams(r)
+	if err != nil {
+		return err
+	}
+	courseID, err := queryID(r, "course_id")
+	if err != nil {
+		return err
+	}
+	quizID, err := queryID(r, "quiz_id")
+	if err != nil {
+		return err
+	}
+	rows, total, err := s.Repo.ExportAttemptsPage(r.Context(), courseID, quizID, limit, offset)
+	if err != nil {
+		return err
+	}
+	out := make([]resultJSON, 0, len(rows))
+	for _, x := range rows {
+		out = append(out, resultJSON{
+			AttemptID:   x.AttemptID,
+			UserEmail:   x.UserEmail,
+			CourseID:    x.CourseID,
+			QuizID:      x.QuizID,
+			QuizTitle:   x.QuizTitle,
+			Status:      x.Status,
+			StartedAt:   x.StartedAt,
+			FinishedAt:  x.FinishedAt,
+			Score:       x.Score,
+			DurationSec: x.Duration,
+			Overtime:    x.Overtime,
+		})
+	}
+	writeJSON(w, http.StatusOK, listPage[resultJSON]{Items: out, Total: total, Limit: limit, Offset: offset})
+	return nil
+}
@@ -0,0 +1,17 @@
+package httpx
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPublicPayload(t *testing.T) {
+	in := `{"options":["a","b"],"correct":[1],"Correct":[1],"CORRECT_VALUE":2.5,"Accept":["x"],"tolerance":0.1}`
+	var got map[string]any
+	if err := json.Unmarshal(publicPayload(json.RawMessage(in)), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got["options"] == nil || got["tolerance"] == nil {
+		t.Errorf("publicPayload = %v, ожидались только options и tolerance", got)
+	}
+}
@@ -0,0 +1,160 @@
+package httpx
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"learny/internal/repo"
+)
+
+// auditPageSize — событий журнала на странице /admin/audit.
+const auditPageSize = 100
+
+const auditDateLayout = "2006-01-02"
+
+// auditFilter разбирает фильтр журнала из query: actor, entity,
+// from и to (даты YYYY-MM-DD, to включительно) в часовом поясе zone.
+func auditFilter(q url.Values, zone *time.Location) (repo.AuditFilter, error) {
+	f := repo.AuditFilter{
+		Actor:      strings.TrimSpace(q.Get("actor")),
+		EntityType: q.Get("entity"),
+	}
+	if v := q.Get("from"); v != "" {
+		t, err := time.ParseInLocation(auditDateLayout, v, zone)
+		if err != nil {
+			return f, err
+		}
+		f.From = t
+	}
+	if v := q.Get("to"); v != "" {
+		t, err := time.ParseInLocation(auditDateLayout, v, zone)
+		if err != nil {
+			return f, err
+		}
+		f.To = t.AddDate(0, 0, 1)
+	}
+	return f, nil
+}
+
+type auditRow struct {
+	repo.AuditEvent
+	Changes []auditChange
+}
+
+type auditChange struct {
+	Field, Old, New string
+}
+
+// auditChanges — поля diff для таблицы, по алфавиту.
+func auditChanges(raw []byte) []auditChange {
+	var d map[string]struct {
+		Old, New any
+	}
+	if err := json.Unmarshal(raw, &d); err != nil {
+		return nil
+	}
+	out := make([]auditChange, 0, len(d))
+	for field, c := range d {
+		out = append(out, auditChange{Field: field, Old: auditValue(c.Old), New: auditValue(c.New)})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Field < out[j].Field })
+	return out
+}
+
+func auditValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "—"
+	case string:
+		return v
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// handleAdminAudit — журнал административных действий с фильтрами.
+func (s *Server) handleAdminAudit(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f, err := auditFilter(q, s.locale(r).Zone)
+	if err != nil {
+		http.Error(w, s.locale(r).T("error.bad_date"), http.StatusBadRequest)
+		return
+	}
+	page := 1
+	if v, err := strconv.Atoi(q.Get("page")); err == nil && v > 1 {
+		page = v
+	}
+	f.Limit, f.Offset = auditPageSize, (page-1)*auditPageSize
+
+	events, total, err := s.Repo.ListAuditEvents(r.Context(), f)
+	if err != nil {
+		s.serverError(w, r, err)
+		return
+	}
+	rows := make([]auditRow, 0, len(events))
+	for _, e := range events {
+		rows = append(rows, auditRow{
+			AuditEvent: e,
+			Changes:    auditChanges(e.Diff),
+		})
+	}
+
+	// тот же фильтр на другой странице или в выгрузке
+	link := func(path string, page int) string {
+		q.Del("page")
+		if page > 1 {
+			q.Set("page", strconv.Itoa(page))
+		}
+		if len(q) == 0 {
+			return path
+		}
+		return path + "?" + q.Encode()
+	}
+	data := map[string]any{
+		"Rows":        rows,
+		"Total":       total,
+		"Actor":       f.Actor,
+		"Entity":      f.EntityType,
+		"EntityTypes": repo.AuditEntityTypes,
+		"From":        q.Get("from"),
+		"To":          q.Get("to"),
+		"ExportURL":   link("/admin/audit/export", 1),
+	}
+	if page > 1 {
+		data["PrevURL"] = link("/admin/audit", page-1)
+	}
+	if page*auditPageSize < total {
+		data["NextURL"] = link("/admin/audit", page+1)
+	}
+	s.render(w, r, "admin_audit", data)
+}
+
+// handleAdminAuditExport — весь журнал по фильтру в CSV.
+func (s *Server) handleAdminAuditExport(w http.ResponseWriter, r *http.Request) {
+	f, err := auditFilter(r.URL.Query(), s.locale(r).Zone)
+	if err != nil {
+		http.Error(w, s.locale(r).T("error.bad_date"), http.StatusBadRequest)
+		return
+	}
+	events, _, err := s.Repo.ListAuditEvents(r.Context(), f)
+	if err != nil {
+		s.serverError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", "attachment; filename=\"audit.csv\"")
+	cw := csv.NewWriter(w)
+	_ = cw.Write(repo.AuditCSVHeader)
+	for _, e := range events {
+		_ = cw.Write(repo.AuditCSVRecord(e))
+	}
+	cw.Flush()
+	logError(r, "audit export", cw.Error())
+}
@@ -0,0 +1,47 @@
+package httpx
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+func (s *Server) trusted(ip net.IP) bool {
+	for _, n := range s.TrustedProxies {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP — адрес клиента. X-Forwarded-For учитывается, только если запрос
+// пришёл от доверенного прокси; цепочка разбирается справа налево до первого
+// недоверенного адреса (левые элементы клиент может подделать).
+func (s *Server) ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !s.trusted(ip) {
+		return host
+	}
+
+	var hops []string
+	for _, h := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(h, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		hip := net.ParseIP(hop)
+		if hip == nil {
+			break
+		}
+		if !s.trusted(hip) {
+			return hop
+		}
+		host = hop
+	}
+	return host
+}
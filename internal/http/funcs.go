@@ -0,0 +1,161 @@
+package httpx
+
+import (
+	"encoding/json"
+	"html/template"
+	"strconv"
+	"strings"
+	"time"
+
+	"learny/internal/i18n"
+	"learny/internal/repo"
+)
+
+// templateFuncs — общие функции шаблонов. Всё, что зависит от языка или часового
+// пояса пользователя, первым аргументом получает локаль страницы: {{ datetime $.L .When }}.
+// Пустые значения (nil, нулевое время) выводятся как «—».
+var templateFuncs = template.FuncMap{
+	"t":         func(l *i18n.Locale, key string, args ...any) string { return l.T(key, args...) },
+	"plural":    func(l *i18n.Locale, n int, key string) string { return strconv.Itoa(n) + " " + l.Plural(n, key) },
+	"date":      func(l *i18n.Locale, v any) string { return formatTime(l, v, "02.01.2006") },
+	"datetime":  func(l *i18n.Locale, v any) string { return formatTime(l, v, "02.01.2006 15:04") },
+	"timestamp": func(l *i18n.Locale, v any) string { return formatTime(l, v, "02.01.2006 15:04:05") },
+	"duration":  formatDuration,
+	"percent":   percent,
+	"score":     formatScore,
+	"mark":      mark,
+	"verdict":   verdict,
+	"yesno": func(l *i18n.Locale, b bool) string {
+		if b {
+			return l.T("common.yes")
+		}
+		return l.T("common.no")
+	},
+	"attemptStatus": func(l *i18n.Locale, st string) string {
+		if msg := l.T("attempt.status." + st); !strings.HasPrefix(msg, "attempt.status.") {
+			return msg
+		}
+		return st
+	},
+	"late": lateReason,
+	"inc":  func(i int) int { return i + 1 }, // номер строки в {{ range $i, $x := ... }}
+	"join": strings.Join,
+	"json": toJS,
+
+	// переключатель языка: название каждого языка — на нём самом
+	"langs":    i18n.Langs,
+	"langName": func(lang string) string { return i18n.New(lang, nil).T("lang.name") },
+}
+
+const noValue = "—"
+
+// lateReason — причина опоздания на языке читателя; "" — её нет.
+func lateReason(l *i18n.Locale, x repo.Lateness) string {
+	if key, args := x.Message(); key != "" {
+		return l.T(key, args...)
+	}
+	return x.Legacy
+}
+
+// formatTime — time.Time или *time.Time в поясе пользователя.
+func formatTime(l *i18n.Locale, v any, layout string) string {
+	var t time.Time
+	switch x := v.(type) {
+	case time.Time:
+		t = x
+	case *time.Time:
+		if x != nil {
+			t = *x
+		}
+	}
+	if t.IsZero() {
+		return noValue
+	}
+	return t.In(l.Zone).Format(layout)
+}
+
+// formatDuration — секунды (int, *int) или time.Duration: «1 ч 5 мин», «3 мин 20 с», «42 с».
+func formatDuration(l *i18n.Locale, v any) string {
+	var sec int
+	switch x := v.(type) {
+	case int:
+		sec = x
+	case *int:
+		if x == nil {
+			return noValue
+		}
+		sec = *x
+	case time.Duration:
+		sec = int(x.Seconds())
+	default:
+		return noValue
+	}
+	h, m, s := sec/3600, sec%3600/60, sec%60
+	var parts []string
+	if h > 0 {
+		parts = append(parts, l.T("duration.h", h))
+	}
+	if m > 0 {
+		parts = append(parts, l.T("duration.min", m))
+	}
+	if h == 0 && (s > 0 || m == 0) {
+		parts = append(parts, l.T("duration.sec", s))
+	}
+	return strings.Join(parts, " ")
+}
+
+// percent — доля part от total в целых процентах с округлением; total 0 — 0.
+func percent(part, total int) int {
+	if total <= 0 {
+		return 0
+	}
+	return int(float64(part)/float64(total)*100 + 0.5)
+}
+
+// formatScore — балл без лишних нулей: 7, 6.5; nil — «—».
+func formatScore(v any) string {
+	switch x := v.(type) {
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case *float64:
+		if x != nil {
+			return strconv.FormatFloat(*x, 'f', -1, 64)
+		}
+	case int:
+		return strconv.Itoa(x)
+	}
+	return noValue
+}
+
+// mark — ✔ / ✘ для результата проверки ответа; nil (не проверен) — «—».
+func mark(ok *bool) string {
+	switch {
+	case ok == nil:
+		return noValue
+	case *ok:
+		return "✔"
+	}
+	return "✘"
+}
+
+// verdict — mark со словом: «✔ Верно».
+func verdict(l *i18n.Locale, ok *bool) string {
+	switch {
+	case ok == nil:
+		return noValue
+	case *ok:
+		return "✔ " + l.T("answer.correct")
+	}
+	return "✘ " + l.T("answer.wrong")
+}
+
+// toJS — значение как литерал JavaScript для вставки в <script>.
+// json.Marshal экранирует <, > и &, поэтому </script> в данных не закроет тег;
+// json.RawMessage (payload вопроса) вставляется как есть, без повторного кодирования в строку.
+func toJS(v any) (template.JS, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return template.JS(b), nil
+}
@@ -1,66 +1,133 @@
 package httpx
 
 import (
+	"context"
 	"database/sql"
 	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"html/template"
 	"io"
 	"net"
 	"net/http"
-	"os"
-	"path/filepath"
+	netmail "net/mail"
 	"strconv"
 	"strings"
-	"sync"
+	"sync/atomic"
 	"time"
 
 	a "learny/internal/auth"
+	"learny/internal/i18n"
+	"learny/internal/mail"
+	"learny/internal/migrate"
+	"learny/internal/ratelimit"
 	"learny/internal/repo"
 	"learny/internal/util"
 )
 
 type Server struct {
-	DB   *sql.DB
-	Repo *repo.Repo
-	T    *template.Template
+	DB       *sql.DB
+	Repo     *repo.Repo
+	Sessions *a.Manager
+	Mailer   mail.Mailer
+	BaseURL  string // внешний адрес для ссылок в письмах, без завершающего /
 
-	loginLimiter sync.Map // IP -> *loginBucket
-}
+	// Templates — страницы, разобранные при старте (LoadTemplates)
+	Templates *Templates
+
+	// RequireVerified — без подтверждённого email нельзя начинать квизы
+	RequireVerified bool
+
+	// TwoFactorRoles — роли, для которых 2FA обязательна (её нельзя отключить)
+	TwoFactorRoles []string
+
+	Limiter *ratelimit.Limiter
+
+	// TrustedProxies — откуда принимать X-Forwarded-For (пусто — ниоткуда)
+	TrustedProxies []*net.IPNet
 
-func (s *Server) Routes(mux *http.ServeMux) {
-	mux.HandleFunc("/", s.handleIndex)
-	mux.HandleFunc("/register", s.handleRegister)
-	mux.HandleFunc("/login", s.handleLogin)
-	mux.HandleFunc("/logout", s.handleLogout)
+	// Migrator — для /readyz: все ли миграции применены (nil — не проверять)
+	Migrator *migrate.Migrator
 
-	mux.Handle("/settings/password", RequireAuth(http.HandlerFunc(s.handlePasswordChange)))
+	// Metrics — счётчики для /metrics (nil — Routes заведёт свои, без статистики БД);
+	// MetricsToken — если задан, /metrics требует Authorization: Bearer
+	Metrics      *Metrics
+	MetricsToken string
 
-	mux.Handle("/courses", RequireAuth(http.HandlerFunc(s.handleCourses)))
-	mux.Handle("/quiz/start", RequireAuth(http.HandlerFunc(s.handleQuizStart)))
-	mux.Handle("/quiz/finish", RequireAuth(http.HandlerFunc(s.handleQuizFinish)))
+	openapi  []byte      // /api/openapi.json, собирается в Routes
+	patterns []string    // шаблоны, зарегистрированные в Routes
+	draining atomic.Bool // сервер останавливается, см. Drain
+}
 
-	mux.Handle("/topics", RequireAuth(http.HandlerFunc(s.handleTopics)))
-	mux.Handle("/topic", RequireAuth(http.HandlerFunc(s.handleTopicProfile)))
+// Routes регистрирует маршруты. Каждый маршрут регистрируется с методами,
+// которые принимает обработчик, и должен быть описан в routeDocs (openapi.go) —
+// это проверяет TestRoutesDocumented.
+func (s *Server) Routes(root *http.ServeMux) {
+	mux := &routeMux{ServeMux: root}
+	if s.Metrics == nil {
+		s.Metrics = NewMetrics(nil)
+	}
+
+	mux.HandleMethods("GET", "/{$}", http.HandlerFunc(s.handleIndex))
+	mux.HandleMethods("GET POST", "/register", http.HandlerFunc(s.handleRegister))
+	mux.HandleMethods("GET POST", "/login", http.HandlerFunc(s.handleLogin))
+	mux.HandleMethods("GET POST", "/login/2fa", http.HandlerFunc(s.handleLogin2FA))
+	mux.HandleMethods("POST", "/logout", http.HandlerFunc(s.handleLogout))
+	mux.HandleMethods("POST", "/lang", http.HandlerFunc(s.handleLang))
+	mux.HandleMethods("GET POST", "/forgot", http.HandlerFunc(s.handleForgot))
+	mux.HandleMethods("GET POST", "/reset", http.HandlerFunc(s.handleReset))
+	mux.HandleMethods("GET", "/verify", http.HandlerFunc(s.handleVerify))
+	mux.HandleMethods("POST", "/verify/resend", RequireAuth(http.HandlerFunc(s.handleVerifyResend)))
+
+	mux.HandleMethods("GET POST", "/settings/password", RequireAuth(http.HandlerFunc(s.handlePasswordChange)))
+	mux.HandleMethods("GET POST", "/settings/sessions", RequireAuth(http.HandlerFunc(s.handleSessions)))
+	mux.HandleMethods("GET POST", "/settings/2fa", RequireAuth(http.HandlerFunc(s.handleTwoFactor)))
+	mux.HandleMethods("GET POST", "/settings/tokens", RequireAuth(http.HandlerFunc(s.handleAPITokens)))
+
+	mux.HandleMethods("GET", "/courses", RequireAuth(http.HandlerFunc(s.handleCourses)))
+	mux.HandleMethods("POST", "/quiz/start", RequireAuth(http.HandlerFunc(s.handleQuizStart)))
+	mux.HandleMethods("GET", "/quiz/attempt", RequireAuth(http.HandlerFunc(s.handleQuizAttempt)))
+	mux.HandleMethods("POST", "/quiz/finish", RequireAuth(http.HandlerFunc(s.handleQuizFinish)))
+
+	mux.HandleMethods("GET", "/topics", RequireAuth(http.HandlerFunc(s.handleTopics)))
+	mux.HandleMethods("GET", "/topic", RequireAuth(http.HandlerFunc(s.handleTopicProfile)))
 
 	// Админка
-	mux.Handle("/admin/questions", RequireRole(s.Repo, "teacher", "admin")(http.HandlerFunc(s.handleAdminQuestionsList)))
-	mux.Handle("/admin/questions/edit", RequireRole(s.Repo, "teacher", "admin")(http.HandlerFunc(s.handleAdminQuestionEdit)))
-	mux.Handle("/admin/questions/upload", RequireRole(s.Repo, "teacher", "admin")(http.HandlerFunc(s.handleAdminUploadGetPost)))
-	mux.Handle("/admin/questions/import-json", RequireRole(s.Repo, "teacher", "admin")(http.HandlerFunc(s.handleAdminUploadJSON)))
-
-	mux.Handle("/admin/users", RequireRole(s.Repo, "admin")(http.HandlerFunc(s.handleAdminUsers)))
-	mux.Handle("/admin/courses", RequireRole(s.Repo, "teacher", "admin")(http.HandlerFunc(s.handleAdminCourses)))
-	mux.Handle("/admin/quizzes", RequireRole(s.Repo, "teacher", "admin")(http.HandlerFunc(s.handleAdminQuizzes)))
-	mux.Handle("/admin/results", RequireRole(s.Repo, "teacher", "admin")(http.HandlerFunc(s.handleAdminResults)))
-	mux.Handle("/admin/results/export", RequireRole(s.Repo, "teacher", "admin")(http.HandlerFunc(s.handleAdminResultsExport)))
-	mux.Handle("/admin/attempt", RequireRole(s.Repo, "teacher", "admin")(http.HandlerFunc(s.handleAdminAttemptDetail)))
-	mux.Handle("/admin/logs", RequireRole(s.Repo, "teacher", "admin")(http.HandlerFunc(s.handleAdminLogsByUser)))
+	mux.HandleMethods("GET", "/admin/questions", RequireRole(s.Repo, "teacher", "admin")(http.HandlerFunc(s.handleAdminQuestionsList)))
+	mux.HandleMethods("GET POST", "/admin/questions/edit", RequireScope(s.Repo, a.ScopeQuestionsWrite)(RequireRole(s.Repo, "teacher", "admin")(http.HandlerFunc(s.handleAdminQuestionEdit))))
+	mux.HandleMethods("GET POST", "/admin/questions/upload", RequireScope(s.Repo, a.ScopeQuestionsWrite)(RequireRole(s.Repo, "teacher", "admin")(http.HandlerFunc(s.handleAdminUploadGetPost))))
+	mux.HandleMethods("GET POST", "/admin/questions/import-json", RequireScope(s.Repo, a.ScopeQuestionsWrite)(RequireRole(s.Repo, "teacher", "admin")(http.HandlerFunc(s.handleAdminUploadJSON))))
+
+	mux.HandleMethods("GET POST", "/admin/users", RequireRole(s.Repo, "admin")(http.HandlerFunc(s.handleAdminUsers)))
+	mux.HandleMethods("GET POST", "/admin/courses", RequireScope(s.Repo, a.ScopeCoursesWrite)(RequireRole(s.Repo, "teacher", "admin")(http.HandlerFunc(s.handleAdminCourses))))
+	mux.HandleMethods("GET POST", "/admin/quizzes", RequireScope(s.Repo, a.ScopeQuizzesWrite)(RequireRole(s.Repo, "teacher", "admin")(http.HandlerFunc(s.handleAdminQuizzes))))
+	mux.HandleMethods("GET", "/admin/results", RequireScope(s.Repo, a.ScopeResultsRead)(RequireRole(s.Repo, "teacher", "admin")(http.HandlerFunc(s.handleAdminResults))))
+	mux.HandleMethods("GET", "/admin/results/export", RequireScope(s.Repo, a.ScopeResultsRead)(RequireRole(s.Repo, "teacher", "admin")(http.HandlerFunc(s.handleAdminResultsExport))))
+	mux.HandleMethods("GET POST", "/admin/attempt", RequireRole(s.Repo, "teacher", "admin")(http.HandlerFunc(s.handleAdminAttemptDetail)))
+	mux.HandleMethods("GET", "/admin/logs", RequireRole(s.Repo, "teacher", "admin")(http.HandlerFunc(s.handleAdminLogsByUser)))
+	mux.HandleMethods("GET", "/admin/audit", RequireRole(s.Repo, "admin")(http.HandlerFunc(s.handleAdminAudit)))
+	mux.HandleMethods("GET", "/admin/audit/export", RequireRole(s.Repo, "admin")(http.HandlerFunc(s.handleAdminAuditExport)))
+
+	// JSON API
+	s.apiRoutes(mux)
+	mux.HandleFunc("GET /api/openapi.json", s.handleOpenAPI)
+
+	// для healthcheck и балансировщика
+	mux.HandleFunc("GET /healthz", s.handleHealthz)
+	mux.HandleFunc("GET /readyz", s.handleReadyz)
+	mux.HandleFunc("GET /metrics", s.handleMetrics)
+
+	s.patterns = mux.patterns
+	spec, err := buildOpenAPI(routeDocs())
+	if err != nil {
+		panic(err)
+	}
+	s.openapi = spec
 }
 
 /* ---------- универсальный рендер с подбором имени шаблона ---------- */
 
-// render: поднимает только base + конкретную страницу, чтобы не было конфликтов define.
+// render выполняет страницу name из кеша шаблонов, добавив общие данные для base.
 func (s *Server) render(w http.ResponseWriter, r *http.Request, name string, data map[string]any) {
 	if data == nil {
 		data = map[string]any{}
@@ -77,74 +144,13 @@ func (s *Server) render(w http.ResponseWriter, r *http.Request, name string, dat
 	} else {
 		data["Authed"] = false
 	}
+	data["CSRFToken"] = a.CSRFFromContext(r)
+	data["L"] = s.locale(r)
+	data["Path"] = r.URL.RequestURI() // куда вернуться после смены языка
 
-	// где лежат шаблоны
-	root := "web/templates"
-
-	// кандидаты для base
-	baseCandidates := []string{"base.tmpl.html", "base.html"}
-	var basePath string
-	for _, f := range baseCandidates {
-		p := filepath.Join(root, f)
-		if st, err := os.Stat(p); err == nil && !st.IsDir() {
-			basePath = p
-			break
-		}
-	}
-	if basePath == "" {
-		http.Error(w, "template error: base template not found", http.StatusInternalServerError)
-		return
-	}
-
-	// кандидаты для страницы
-	pageCandidates := []string{
-		name,
-		name + ".tmpl.html",
-		name + ".html",
-		"page_" + name,
-		"page_" + name + ".tmpl.html",
-	}
-	var pagePath, pageTplName string
-	for _, f := range pageCandidates {
-		p := filepath.Join(root, f)
-		if st, err := os.Stat(p); err == nil && !st.IsDir() {
-			pagePath = p
-			pageTplName = filepath.Base(p) // имя для ExecuteTemplate
-			break
-		}
-	}
-	if pagePath == "" {
-		// если нет отдельного файла страницы — попробуем выполнить то, что уже распарсили глобально
-		if t := s.T.Lookup(name); t != nil {
-			w.Header().Set("Content-Type", "text/html; charset=utf-8")
-			if err := t.Execute(w, data); err != nil {
-				http.Error(w, "template exec error: "+err.Error(), http.StatusInternalServerError)
-			}
-			return
-		}
-		if t := s.T.Lookup(name + ".tmpl.html"); t != nil {
-			w.Header().Set("Content-Type", "text/html; charset=utf-8")
-			if err := t.Execute(w, data); err != nil {
-				http.Error(w, "template exec error: "+err.Error(), http.StatusInternalServerError)
-			}
-			return
-		}
-		http.Error(w, "template not found for '"+name+"'", http.StatusInternalServerError)
-		return
-	}
-
-	// Парсим ТОЛЬКО base + выбранную страницу (никаких других файлов)
-	t, err := template.New("").ParseFiles(basePath, pagePath)
-	if err != nil {
-		http.Error(w, "template parse error: "+err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	// Выполняем именно шаблон с именем файла страницы (внутри он сам подключит base)
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	if err := t.ExecuteTemplate(w, pageTplName, data); err != nil {
-		http.Error(w, "template exec error: "+err.Error(), http.StatusInternalServerError)
-		return
+	if err := s.Templates.Execute(w, name, data); err != nil {
+		s.serverError(w, r, err)
 	}
 }
 
@@ -160,23 +166,76 @@ func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
 
 /* ===== Регистрация/логин/выход + rate limit ===== */
 
-type loginBucket struct {
-	count int
-	start time.Time
+// limited учитывает событие по правилу и, если лимит превышен,
+// показывает страницу tpl с сообщением. true — запрос уже обработан.
+func (s *Server) limited(w http.ResponseWriter, r *http.Request, rule, key, tpl string) bool {
+	d, err := s.Limiter.Allow(r.Context(), rule, key)
+	if err != nil {
+		s.serverError(w, r, err)
+		return true
+	}
+	if d.Allowed {
+		return false
+	}
+	s.Metrics.RateLimitHits.With(rule).Inc()
+	w.Header().Set("Retry-After", strconv.Itoa(int(d.RetryAfter.Seconds())))
+	l := s.locale(r)
+	msg := l.T("login.rate_limited", d.Minutes())
+	if tpl == "message" {
+		s.render(w, r, tpl, map[string]any{"Title": l.T("login.too_many"), "Message": msg})
+	} else {
+		s.render(w, r, tpl, map[string]any{"Error": msg})
+	}
+	return true
 }
 
-func clientIP(r *http.Request) string {
-	ip := r.Header.Get("X-Forwarded-For")
-	if ip != "" {
-		if p := strings.Split(ip, ","); len(p) > 0 {
-			return strings.TrimSpace(p[0])
-		}
+// loginLimited — с этого IP слишком много неудачных входов (пароль или код 2FA);
+// тогда страница tpl с сообщением уже показана.
+func (s *Server) loginLimited(w http.ResponseWriter, r *http.Request, ip, tpl string) bool {
+	d, err := s.Limiter.Check(r.Context(), ratelimit.Login, ip)
+	if err != nil {
+		s.serverError(w, r, err)
+		return true
 	}
-	host, _, _ := net.SplitHostPort(r.RemoteAddr)
-	if host == "" {
-		host = r.RemoteAddr
+	if d.Allowed {
+		return false
 	}
-	return host
+	s.Metrics.RateLimitHits.With(ratelimit.Login).Inc()
+	w.Header().Set("Retry-After", strconv.Itoa(int(d.RetryAfter.Seconds())))
+	s.render(w, r, tpl, map[string]any{"Error": s.locale(r).T("login.rate_limited", d.Minutes())})
+	return true
+}
+
+// loginFailed учитывает неудачный вход (пароль или код 2FA) с ip и, если
+// аккаунт известен (userID != 0), в аккаунт; после серии неудач аккаунт блокируется.
+func (s *Server) loginFailed(r *http.Request, ip string, userID int64) error {
+	if _, err := s.Limiter.Allow(r.Context(), ratelimit.Login, ip); err != nil {
+		return err
+	}
+	if userID == 0 {
+		return nil
+	}
+	key := strconv.FormatInt(userID, 10)
+	d, err := s.Limiter.Allow(r.Context(), ratelimit.Account, key)
+	if err != nil || d.Remaining > 0 {
+		return err
+	}
+	rule := s.Limiter.Rules[ratelimit.Account]
+	if err := s.Repo.LockUser(r.Context(), userID, time.Now().Add(rule.Window)); err != nil {
+		return err
+	}
+	return s.Limiter.Reset(r.Context(), ratelimit.Account, key)
+}
+
+// loginSucceeded обнуляет счётчик неудач аккаунта — только после входа целиком:
+// верный пароль при включённой 2FA ещё не вход, иначе повторным вводом пароля
+// можно было бы бесконечно перебирать коды.
+func (s *Server) loginSucceeded(r *http.Request, userID int64) {
+	logError(r, "reset account limit", s.Limiter.Reset(r.Context(), ratelimit.Account, strconv.FormatInt(userID, 10)))
+}
+
+func lockedMessage(l *i18n.Locale, u *repo.UserRow) string {
+	return l.T("login.locked", formatTime(l, u.LockedUntil, "02.01.2006 15:04"))
 }
 
 func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
@@ -186,22 +245,36 @@ func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
 	case http.MethodPost:
 		email := strings.TrimSpace(r.FormValue("email"))
 		pw := r.FormValue("password")
-		if len(email) == 0 || len(pw) < 8 {
-			s.render(w, r, "register", map[string]any{"Error": "Укажите валидный email и пароль ≥ 8 символов"})
+		if s.limited(w, r, ratelimit.Register, s.ClientIP(r), "register") {
+			return
+		}
+		if addr, err := netmail.ParseAddress(email); err != nil || addr.Address != email || len(pw) < 8 {
+			s.render(w, r, "register", map[string]any{"Error": s.locale(r).T("register.invalid")})
 			return
 		}
 		hash, err := util.HashPassword(pw)
 		if err != nil {
-			http.Error(w, err.Error(), 500)
+			s.serverError(w, r, err)
 			return
 		}
 		if _, err := s.Repo.CreateUser(r.Context(), email, hash); err != nil {
-			s.render(w, r, "register", map[string]any{"Error": "Пользователь с таким email уже существует"})
+			s.render(w, r, "register", map[string]any{"Error": s.locale(r).T("register.exists")})
 			return
 		}
-		u, _ := s.Repo.FindUserByEmail(r.Context(), email)
-		a.SetSession(w, u.ID)
-		http.Redirect(w, r, "/courses", http.StatusFound)
+		u, err := s.Repo.FindUserByEmail(r.Context(), email)
+		if err != nil {
+			s.serverError(w, r, err)
+			return
+		}
+		if err := s.Sessions.Start(w, r, u.ID, s.ClientIP(r)); err != nil {
+			s.serverError(w, r, err)
+			return
+		}
+		sendErr := s.sendVerification(r, u.ID, u.Email)
+		s.render(w, r, "verify_notice", map[string]any{
+			"Email":     u.Email,
+			"SendError": sendErr != nil,
+		})
 	}
 }
 
@@ -210,41 +283,166 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 	case http.MethodGet:
 		s.render(w, r, "login", nil)
 	case http.MethodPost:
-		ip := clientIP(r)
-		now := time.Now()
-		val, _ := s.loginLimiter.LoadOrStore(ip, &loginBucket{count: 0, start: now})
-		b := val.(*loginBucket)
-		if now.Sub(b.start) > 15*time.Minute {
-			b.start = now
-			b.count = 0
-		}
-		if b.count >= 5 {
-			s.render(w, r, "login", map[string]any{
-				"Error": "Слишком много попыток. Подождите 15 минут и попробуйте снова.",
-			})
+		ip := s.ClientIP(r)
+		// по IP считаем только неудачные попытки
+		if s.loginLimited(w, r, ip, "login") {
 			return
 		}
 
 		email := strings.TrimSpace(r.FormValue("email"))
 		pw := r.FormValue("password")
 		u, err := s.Repo.FindUserByEmail(r.Context(), email)
+		if err == nil && u.Locked() {
+			s.render(w, r, "login", map[string]any{"Error": lockedMessage(s.locale(r), u)})
+			return
+		}
 		if err != nil || !util.CheckPassword(u.PassHash, pw) {
-			b.count++
-			s.render(w, r, "login", map[string]any{"Error": "Неверный логин или пароль"})
+			s.Metrics.LoginFailures.With("password").Inc()
+			var uid int64
+			if u != nil {
+				uid = u.ID
+			}
+			if err := s.loginFailed(r, ip, uid); err != nil {
+				s.serverError(w, r, err)
+				return
+			}
+			s.render(w, r, "login", map[string]any{"Error": s.locale(r).T("login.failed")})
+			return
+		}
+		if u.TwoFactor() {
+			if err := s.Sessions.BeginChallenge(w, r, u.ID); err != nil {
+				s.serverError(w, r, err)
+				return
+			}
+			http.Redirect(w, r, "/login/2fa", http.StatusSeeOther)
+			return
+		}
+		if err := s.Sessions.Start(w, r, u.ID, ip); err != nil {
+			s.serverError(w, r, err)
+			return
+		}
+		s.loginSucceeded(r, u.ID)
+		if s.twoFactorRequired(u.Role) {
+			http.Redirect(w, r, "/settings/2fa", http.StatusSeeOther)
+			return
+		}
+		http.Redirect(w, r, "/courses", http.StatusFound)
+	}
+}
+
+// handleLogin2FA — второй шаг входа: код из приложения или код восстановления.
+func (s *Server) handleLogin2FA(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.render(w, r, "login_2fa", nil)
+	case http.MethodPost:
+		// неверные коды считаются вместе с неверными паролями: и по IP, и по аккаунту
+		ip := s.ClientIP(r)
+		if s.loginLimited(w, r, ip, "login_2fa") {
+			return
+		}
+		uid, err := s.Sessions.ChallengeUser(r)
+		if errors.Is(err, repo.ErrTokenInvalid) {
+			s.Sessions.EndChallenge(w, r)
+			s.render(w, r, "login", map[string]any{"Error": s.locale(r).T("login2fa.expired")})
+			return
+		}
+		if err != nil {
+			s.serverError(w, r, err)
+			return
+		}
+		if u, err := s.Repo.GetUser(r.Context(), uid); err != nil {
+			s.serverError(w, r, err)
+			return
+		} else if u.Locked() {
+			s.Sessions.EndChallenge(w, r)
+			s.render(w, r, "login", map[string]any{"Error": lockedMessage(s.locale(r), u)})
+			return
+		}
+		st, err := s.Repo.GetTOTP(r.Context(), uid)
+		if err != nil {
+			s.serverError(w, r, err)
+			return
+		}
+
+		code := r.FormValue("code")
+		ok, err := s.checkTOTP(r, uid, st, code)
+		if err == nil && !ok {
+			ok, err = s.Repo.UseRecoveryCode(r.Context(), uid, a.HashToken(a.NormalizeRecoveryCode(code)))
+		}
+		if err != nil {
+			s.serverError(w, r, err)
+			return
+		}
+		if !ok {
+			s.Metrics.LoginFailures.With("2fa").Inc()
+			if err := s.loginFailed(r, ip, uid); err != nil {
+				s.serverError(w, r, err)
+				return
+			}
+			s.render(w, r, "login_2fa", map[string]any{"Error": s.locale(r).T("2fa.bad_code")})
+			return
+		}
+
+		s.Sessions.EndChallenge(w, r)
+		if err := s.Sessions.Start(w, r, uid, ip); err != nil {
+			s.serverError(w, r, err)
 			return
 		}
-		b.count = 0
-		b.start = now
-		a.SetSession(w, u.ID)
+		s.loginSucceeded(r, uid)
 		http.Redirect(w, r, "/courses", http.StatusFound)
 	}
 }
 
 func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
-	a.ClearSession(w)
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	logError(r, "logout", s.Sessions.End(w, r))
 	http.Redirect(w, r, "/login", http.StatusFound)
 }
 
+// handleLang — переключатель языка в подвале. Выбор запоминается в cookie,
+// у залогиненного ещё и в профиле; пустой lang — снова по Accept-Language.
+func (s *Server) handleLang(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	lang := r.FormValue("lang")
+	if lang != "" && !i18n.Supported(lang) {
+		http.Error(w, "unsupported lang", http.StatusBadRequest)
+		return
+	}
+	if uid, ok := a.CurrentUserID(r); ok {
+		if err := s.Repo.SetUserLang(r.Context(), uid, lang); err != nil {
+			s.serverError(w, r, err)
+			return
+		}
+	}
+	c := &http.Cookie{
+		Name:     langCookie,
+		Value:    lang,
+		Path:     "/",
+		MaxAge:   365 * 24 * 3600,
+		HttpOnly: true,
+		Secure:   s.Sessions.Secure,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if lang == "" {
+		c.MaxAge = -1
+	}
+	http.SetCookie(w, c)
+
+	// только локальный путь, чтобы форму нельзя было использовать как открытый редирект
+	back := r.FormValue("back")
+	if !strings.HasPrefix(back, "/") || strings.HasPrefix(back, "//") || strings.HasPrefix(back, "/\\") {
+		back = "/"
+	}
+	http.Redirect(w, r, back, http.StatusSeeOther)
+}
+
 /* ===== Смена пароля ===== */
 
 func (s *Server) handlePasswordChange(w http.ResponseWriter, r *http.Request) {
@@ -258,7 +456,7 @@ func (s *Server) handlePasswordChange(w http.ResponseWriter, r *http.Request) {
 		rep := r.FormValue("new2")
 		if len(newp) < 8 || newp != rep {
 			s.render(w, r, "settings_password",
-				map[string]any{"Error": "Пароль должен быть ≥ 8 символов, и поля нового пароля должны совпадать"})
+				map[string]any{"Error": s.locale(r).T("password.invalid")})
 			return
 		}
 		var passHash string
@@ -267,15 +465,474 @@ func (s *Server) handlePasswordChange(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		if !util.CheckPassword(passHash, cur) {
-			s.render(w, r, "settings_password", map[string]any{"Error": "Текущий пароль неверен"})
+			s.render(w, r, "settings_password", map[string]any{"Error": s.locale(r).T("password.wrong_current")})
 			return
 		}
 		hash, _ := util.HashPassword(newp)
 		if err := s.Repo.UpdateUserPass(r.Context(), uid, hash); err != nil {
-			http.Error(w, err.Error(), 500)
+			s.serverError(w, r, err)
+			return
+		}
+		// после смены пароля остаётся только текущая сессия
+		if cur, ok := a.CurrentSession(r); ok {
+			_, err := s.Repo.DeleteUserSessions(r.Context(), uid, cur.ID)
+			logError(r, "revoke other sessions", err)
+		}
+		l := s.locale(r)
+		s.render(w, r, "message", map[string]any{"Title": l.T("common.done"), "Message": l.T("password.changed")})
+	}
+}
+
+/* ===== Восстановление пароля ===== */
+
+const passwordResetTTL = time.Hour
+
+func (s *Server) handleForgot(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.render(w, r, "forgot", nil)
+	case http.MethodPost:
+		if s.limited(w, r, ratelimit.Reset, s.ClientIP(r), "forgot") {
+			return
+		}
+		email := strings.TrimSpace(r.FormValue("email"))
+		l := s.locale(r)
+		// ответ одинаковый, есть такой email или нет — чтобы не раскрывать список пользователей
+		done := map[string]any{
+			"Title":   l.T("forgot.sent_title"),
+			"Message": l.T("forgot.sent"),
+		}
+
+		if u, err := s.Repo.FindUserByEmail(r.Context(), email); err == nil {
+			// сбой отправки — только в лог: 500 выдал бы, что такой аккаунт есть
+			logError(r, "password reset", s.sendPasswordReset(r, u))
+		}
+		s.render(w, r, "message", done)
+	}
+}
+
+// sendPasswordReset выпускает токен сброса пароля и отправляет письмо со ссылкой.
+func (s *Server) sendPasswordReset(r *http.Request, u *repo.UserRow) error {
+	token, err := a.NewToken()
+	if err != nil {
+		return err
+	}
+	if err := s.Repo.CreatePasswordReset(r.Context(), u.ID, a.HashToken(token), time.Now().Add(passwordResetTTL)); err != nil {
+		return err
+	}
+	l := s.locale(r)
+	if err := s.Mailer.Send(r.Context(), mail.Message{
+		To:      u.Email,
+		Subject: l.T("mail.reset_subject"),
+		Body:    l.T("mail.reset_body", s.BaseURL+"/reset?token="+token),
+	}); err != nil {
+		return &mailError{err}
+	}
+	return nil
+}
+
+func (s *Server) handleReset(w http.ResponseWriter, r *http.Request) {
+	token := r.FormValue("token")
+	switch r.Method {
+	case http.MethodGet:
+		if err := s.Repo.CheckPasswordReset(r.Context(), a.HashToken(token)); err != nil {
+			s.renderLinkInvalid(w, r)
+			return
+		}
+		s.render(w, r, "reset", map[string]any{"Token": token})
+	case http.MethodPost:
+		newp := r.FormValue("new")
+		if len(newp) < 8 || newp != r.FormValue("new2") {
+			s.render(w, r, "reset", map[string]any{
+				"Token": token,
+				"Error": s.locale(r).T("password.invalid"),
+			})
+			return
+		}
+		hash, err := util.HashPassword(newp)
+		if err != nil {
+			s.serverError(w, r, err)
+			return
+		}
+		if _, err := s.Repo.ConsumePasswordReset(r.Context(), a.HashToken(token), hash); err != nil {
+			if errors.Is(err, repo.ErrTokenInvalid) {
+				s.renderLinkInvalid(w, r)
+				return
+			}
+			s.serverError(w, r, err)
+			return
+		}
+		l := s.locale(r)
+		s.render(w, r, "message", map[string]any{"Title": l.T("common.done"), "Message": l.T("reset.done")})
+	}
+}
+
+// renderLinkInvalid — ссылка из письма устарела, уже использована или подделана.
+func (s *Server) renderLinkInvalid(w http.ResponseWriter, r *http.Request) {
+	l := s.locale(r)
+	s.render(w, r, "message", map[string]any{"Title": l.T("token.invalid_title"), "Message": l.Err(repo.ErrTokenInvalid)})
+}
+
+/* ===== Подтверждение email ===== */
+
+const emailVerificationTTL = 48 * time.Hour
+
+// sendVerification выпускает новый токен подтверждения и отправляет письмо со ссылкой.
+func (s *Server) sendVerification(r *http.Request, userID int64, email string) error {
+	token, err := a.NewToken()
+	if err != nil {
+		return err
+	}
+	if err := s.Repo.CreateEmailVerification(r.Context(), userID, a.HashToken(token), time.Now().Add(emailVerificationTTL)); err != nil {
+		return err
+	}
+	l := s.locale(r)
+	return s.Mailer.Send(r.Context(), mail.Message{
+		To:      email,
+		Subject: l.T("mail.verify_subject"),
+		Body:    l.T("mail.verify_body", s.BaseURL+"/verify?token="+token),
+	})
+}
+
+// mailError — письмо не ушло (в лог идёт текст из каталога, mail.send_failed).
+type mailError struct{ err error }
+
+func (e *mailError) Error() string            { return i18n.Default("mail.send_failed", e.err) }
+func (e *mailError) Message() (string, []any) { return "mail.send_failed", []any{e.err} }
+func (e *mailError) Unwrap() error            { return e.err }
+
+func (s *Server) handleVerify(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if _, err := s.Repo.ConsumeEmailVerification(r.Context(), a.HashToken(token)); err != nil {
+		if errors.Is(err, repo.ErrTokenInvalid) {
+			s.renderLinkInvalid(w, r)
+			return
+		}
+		s.serverError(w, r, err)
+		return
+	}
+	l := s.locale(r)
+	s.render(w, r, "message", map[string]any{"Title": l.T("verify.done_title"), "Message": l.T("verify.done")})
+}
+
+func (s *Server) handleVerifyResend(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	uid, _ := a.CurrentUserID(r)
+	u, err := s.Repo.GetUser(r.Context(), uid)
+	if err != nil {
+		s.serverError(w, r, err)
+		return
+	}
+	if u.Verified() {
+		l := s.locale(r)
+		s.render(w, r, "message", map[string]any{"Title": l.T("verify.done_title"), "Message": l.T("verify.already")})
+		return
+	}
+	sendErr := s.sendVerification(r, u.ID, u.Email)
+	s.render(w, r, "verify_notice", map[string]any{
+		"Email":     u.Email,
+		"SendError": sendErr != nil,
+	})
+}
+
+/* ===== Активные сессии ===== */
+
+func (s *Server) handleSessions(w http.ResponseWriter, r *http.Request) {
+	uid, _ := a.CurrentUserID(r)
+	cur, _ := a.CurrentSession(r)
+
+	switch r.Method {
+	case http.MethodGet:
+		list, err := s.Repo.ListUserSessions(r.Context(), uid)
+		if err != nil {
+			s.serverError(w, r, err)
+			return
+		}
+
+		var curID int64
+		if cur != nil {
+			curID = cur.ID
+		}
+		s.render(w, r, "settings_sessions", map[string]any{"Rows": list, "CurrentID": curID})
+
+	case http.MethodPost:
+		switch r.FormValue("action") {
+		case "revoke":
+			sid, _ := strconv.ParseInt(r.FormValue("session_id"), 10, 64)
+			if err := s.Repo.DeleteUserSession(r.Context(), uid, sid); err != nil && !errors.Is(err, repo.ErrSessionNotFound) {
+				s.serverError(w, r, err)
+				return
+			}
+			if cur != nil && sid == cur.ID {
+				logError(r, "end session", s.Sessions.End(w, r))
+				http.Redirect(w, r, "/login", http.StatusSeeOther)
+				return
+			}
+		case "revoke_all":
+			if _, err := s.Repo.DeleteUserSessions(r.Context(), uid, 0); err != nil {
+				s.serverError(w, r, err)
+				return
+			}
+			logError(r, "end session", s.Sessions.End(w, r))
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		http.Redirect(w, r, "/settings/sessions", http.StatusSeeOther)
+	}
+}
+
+/* ===== Двухфакторная аутентификация ===== */
+
+const recoveryCodesCount = 10
+
+func (s *Server) twoFactorRequired(role string) bool {
+	for _, r := range s.TwoFactorRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// checkTOTP проверяет код из приложения и не даёт использовать его повторно.
+func (s *Server) checkTOTP(r *http.Request, uid int64, st *repo.TOTPState, code string) (bool, error) {
+	if st.Secret == "" {
+		return false, nil
+	}
+	step, ok := a.VerifyTOTP(st.Secret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+	return s.Repo.UseTOTPStep(r.Context(), uid, step)
+}
+
+// newRecoveryCodes — коды для показа пользователю и их хэши для БД.
+func newRecoveryCodes() ([]string, []string, error) {
+	codes, err := a.NewRecoveryCodes(recoveryCodesCount)
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes := make([]string, len(codes))
+	for i, c := range codes {
+		hashes[i] = a.HashToken(c)
+	}
+	return codes, hashes, nil
+}
+
+func (s *Server) handleTwoFactor(w http.ResponseWriter, r *http.Request) {
+	uid, _ := a.CurrentUserID(r)
+	u, err := s.Repo.GetUser(r.Context(), uid)
+	if err != nil {
+		s.serverError(w, r, err)
+		return
+	}
+	st, err := s.Repo.GetTOTP(r.Context(), uid)
+	if err != nil {
+		s.serverError(w, r, err)
+		return
+	}
+
+	// page собирает данные страницы; codes — свежевыданные коды восстановления (показываются один раз)
+	page := func(errMsg string, codes []string) {
+		data := map[string]any{
+			"Enabled":  st.Enabled(),
+			"Required": s.twoFactorRequired(u.Role),
+			"Error":    errMsg,
+			"Codes":    codes,
+		}
+		if st.Enabled() {
+			left, err := s.Repo.CountRecoveryCodes(r.Context(), uid)
+			if err != nil {
+				s.serverError(w, r, err)
+				return
+			}
+			data["CodesLeft"] = left
+		} else if st.Secret != "" {
+			data["Secret"] = st.Secret
+			data["URI"] = template.URL(a.TOTPURI("Learny", u.Email, st.Secret))
+		}
+		s.render(w, r, "settings_2fa", data)
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		page("", nil)
+	case http.MethodPost:
+		switch r.FormValue("action") {
+		case "begin":
+			if st.Enabled() {
+				break
+			}
+			secret, err := a.NewTOTPSecret()
+			if err != nil {
+				s.serverError(w, r, err)
+				return
+			}
+			if err := s.Repo.BeginTOTP(r.Context(), uid, secret); err != nil {
+				s.serverError(w, r, err)
+				return
+			}
+
+		case "confirm":
+			if st.Enabled() || st.Secret == "" {
+				break
+			}
+			step, ok := a.VerifyTOTP(st.Secret, r.FormValue("code"), time.Now())
+			if !ok {
+				page(s.locale(r).T("2fa.bad_code_time"), nil)
+				return
+			}
+			codes, hashes, err := newRecoveryCodes()
+			if err != nil {
+				s.serverError(w, r, err)
+				return
+			}
+			if err := s.Repo.EnableTOTP(r.Context(), uid, step, hashes); err != nil {
+				if !errors.Is(err, repo.ErrTOTPNotPending) {
+					s.serverError(w, r, err)
+					return
+				}
+				// параллельный запрос успел включить или сбросить 2FA — показываем актуальное состояние
+				if st, err = s.Repo.GetTOTP(r.Context(), uid); err != nil {
+					s.serverError(w, r, err)
+					return
+				}
+				page(s.locale(r).Err(repo.ErrTOTPNotPending), nil)
+				return
+			}
+			now := time.Now()
+			st.EnabledAt = &now
+			page("", codes)
+			return
+
+		case "recovery":
+			// до проверки кода: checkTOTP расходует шаг, а при незаконченном
+			// подключении кодов восстановления ещё нет
+			if !st.Enabled() {
+				break
+			}
+			ok, err := s.checkTOTP(r, uid, st, r.FormValue("code"))
+			if err != nil {
+				s.serverError(w, r, err)
+				return
+			}
+			if !ok {
+				page(s.locale(r).T("2fa.bad_code"), nil)
+				return
+			}
+			codes, hashes, err := newRecoveryCodes()
+			if err != nil {
+				s.serverError(w, r, err)
+				return
+			}
+			if err := s.Repo.ReplaceRecoveryCodes(r.Context(), uid, hashes); err != nil {
+				s.serverError(w, r, err)
+				return
+			}
+			page("", codes)
+			return
+
+		case "disable":
+			if s.twoFactorRequired(u.Role) {
+				http.Error(w, s.locale(r).T("2fa.required_on"), http.StatusForbidden)
+				return
+			}
+			if !st.Enabled() {
+				break
+			}
+			ok, err := s.checkTOTP(r, uid, st, r.FormValue("code"))
+			if err != nil {
+				s.serverError(w, r, err)
+				return
+			}
+			if !ok {
+				page(s.locale(r).T("2fa.bad_code"), nil)
+				return
+			}
+			if err := s.Repo.DisableTOTP(r.Context(), uid); err != nil {
+				s.serverError(w, r, err)
+				return
+			}
+		}
+		http.Redirect(w, r, "/settings/2fa", http.StatusSeeOther)
+	}
+}
+
+/* ===== Персональные API-токены ===== */
+
+func (s *Server) handleAPITokens(w http.ResponseWriter, r *http.Request) {
+	uid, _ := a.CurrentUserID(r)
+	role, err := s.Repo.GetUserRole(r.Context(), uid)
+	if err != nil {
+		s.serverError(w, r, err)
+		return
+	}
+
+	// page — список токенов; created — только что выпущенный токен (показывается один раз)
+	page := func(errMsg, created string) {
+		list, err := s.Repo.ListAPITokens(r.Context(), uid)
+		if err != nil {
+			s.serverError(w, r, err)
+			return
+		}
+		s.render(w, r, "settings_tokens", map[string]any{
+			"Rows":    list,
+			"Now":     time.Now(),
+			"Scopes":  a.ScopesFor(role),
+			"Error":   errMsg,
+			"Created": created,
+		})
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		page("", "")
+	case http.MethodPost:
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+		switch r.FormValue("action") {
+		case "create":
+			name := strings.TrimSpace(r.FormValue("name"))
+			scopes := r.Form["scope"]
+			if name == "" || len(scopes) == 0 {
+				page(s.locale(r).T("tokens.invalid"), "")
+				return
+			}
+			for _, sc := range scopes {
+				if !a.ScopeAllowed(sc, role) {
+					http.Error(w, "scope not allowed: "+sc, 400)
+					return
+				}
+			}
+			t := &repo.APITokenRow{UserID: uid, Name: name, Scopes: scopes}
+			if days, _ := strconv.Atoi(r.FormValue("expires_days")); days > 0 {
+				exp := time.Now().AddDate(0, 0, days)
+				t.ExpiresAt = &exp
+			}
+			token, err := a.NewAPIToken()
+			if err != nil {
+				s.serverError(w, r, err)
+				return
+			}
+			if err := s.Repo.CreateAPIToken(r.Context(), t, a.HashToken(token)); err != nil {
+				s.serverError(w, r, err)
+				return
+			}
+			page("", token)
 			return
+		case "revoke":
+			id, _ := strconv.ParseInt(r.FormValue("token_id"), 10, 64)
+			if err := s.Repo.DeleteAPIToken(r.Context(), uid, id); err != nil && !errors.Is(err, repo.ErrAPITokenNotFound) {
+				s.serverError(w, r, err)
+				return
+			}
 		}
-		s.render(w, r, "message", map[string]any{"Title": "Готово", "Message": "Пароль изменён."})
+		http.Redirect(w, r, "/settings/tokens", http.StatusSeeOther)
 	}
 }
 
@@ -293,59 +950,90 @@ func (s *Server) handleCourses(w http.ResponseWriter, r *http.Request) {
 	s.render(w, r, "courses", map[string]any{"Courses": cs, "Role": role, "QMap": qmap})
 }
 
+// handleQuizStart начинает попытку (POST с CSRF-токеном: новая попытка бросает
+// незавершённую) и отправляет на её страницу — обновление и «назад» ничего не начинают.
 func (s *Server) handleQuizStart(w http.ResponseWriter, r *http.Request) {
 	uid, _ := a.CurrentUserID(r)
 
-	courseID := int64(1)
-	if v := r.URL.Query().Get("course_id"); v != "" {
-		if x, err := strconv.ParseInt(v, 10, 64); err == nil {
-			courseID = x
-		}
+	quizID, err := strconv.ParseInt(r.FormValue("quiz_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "quiz_id required", http.StatusBadRequest)
+		return
 	}
-	quizID := int64(1)
-	if v := r.URL.Query().Get("quiz_id"); v != "" {
-		if x, err := strconv.ParseInt(v, 10, 64); err == nil {
-			quizID = x
-		}
+	// курс — всегда курс квиза; course_id из формы только сверяем
+	quiz, err := s.Repo.GetQuiz(r.Context(), quizID)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "quiz not found", http.StatusNotFound)
+		return
 	}
-
-	rules, title, err := s.Repo.LoadQuizRules(r.Context(), quizID)
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		s.serverError(w, r, err)
 		return
 	}
+	if v := r.FormValue("course_id"); v != "" {
+		if x, err := strconv.ParseInt(v, 10, 64); err != nil || x != quiz.CourseID {
+			http.Error(w, "quiz does not belong to course_id", http.StatusBadRequest)
+			return
+		}
+	}
 
-	// лимиты
-	if rules.MaxAttempts > 0 {
-		total, _ := s.Repo.TotalAttemptsByUserQuiz(r.Context(), uid, quizID)
-		if total >= rules.MaxAttempts {
-			s.render(w, r, "message", map[string]any{
-				"Title":   "Лимит попыток исчерпан",
-				"Message": "Для этого квиза исчерпано максимальное число попыток.",
-			})
+	if s.RequireVerified {
+		if ok, err := s.Repo.IsUserVerified(r.Context(), uid); err != nil || !ok {
+			u, _ := s.Repo.GetUser(r.Context(), uid)
+			email := ""
+			if u != nil {
+				email = u.Email
+			}
+			s.render(w, r, "verify_notice", map[string]any{"Email": email, "Blocked": true})
 			return
 		}
 	}
-	if rules.RetakeCooldownSec > 0 {
-		since := time.Now().Add(-time.Duration(rules.RetakeCooldownSec) * time.Second)
-		count, _ := s.Repo.AttemptsSinceByUserQuiz(r.Context(), uid, quizID, since)
-		if count > 0 {
-			s.render(w, r, "message", map[string]any{
-				"Title":   "Слишком рано для пересдачи",
-				"Message": "Подождите перед новой попыткой согласно правилам квиза.",
-			})
+
+	if s.limited(w, r, ratelimit.QuizStart, strconv.FormatInt(uid, 10), "message") {
+		return
+	}
+
+	st, err := s.startAttempt(r.Context(), uid, quiz)
+	if err != nil {
+		var denied *startDenied
+		if errors.As(err, &denied) {
+			l := s.locale(r)
+			s.render(w, r, "message", map[string]any{"Title": l.T(denied.Key + "_title"), "Message": l.Err(denied)})
 			return
 		}
+		s.serverError(w, r, err)
+		return
+	}
+	http.Redirect(w, r, "/quiz/attempt?id="+strconv.FormatInt(st.AttemptID, 10), http.StatusSeeOther)
+}
+
+// handleQuizAttempt показывает свою попытку: идущую — с выданными на старте
+// вопросами и оставшимся временем, завершённую — её итог.
+func (s *Server) handleQuizAttempt(w http.ResponseWriter, r *http.Request) {
+	uid, _ := a.CurrentUserID(r)
+	id, _ := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	att, err := s.Repo.GetAttempt(r.Context(), id)
+	if errors.Is(err, repo.ErrAttemptNotFound) || (err == nil && att.UserID != uid) {
+		http.Error(w, "attempt not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.serverError(w, r, err)
+		return
+	}
+	if att.Status != repo.AttemptInProgress {
+		s.renderFinished(w, r, att)
+		return
 	}
 
-	qs, err := s.Repo.PickQuestions(r.Context(), courseID, rules)
+	rules, title, err := s.Repo.LoadQuizRules(r.Context(), att.QuizID)
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		s.serverError(w, r, err)
 		return
 	}
-	attemptID, err := s.Repo.CreateAttempt(r.Context(), quizID, uid)
+	issued, err := s.Repo.ListAttemptQuestions(r.Context(), att.ID)
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		s.serverError(w, r, err)
 		return
 	}
 
@@ -359,170 +1047,268 @@ func (s *Server) handleQuizStart(w http.ResponseWriter, r *http.Request) {
 		Payload    json.RawMessage
 	}
 
-	vqs := make([]quizQuestionView, 0, len(qs))
-	for i, q := range qs {
+	vqs := make([]quizQuestionView, 0, len(issued))
+	for _, q := range issued {
 		vqs = append(vqs, quizQuestionView{
-			Ord:        i + 1,
-			ID:         q.ID,
+			Ord:        q.Ord,
+			ID:         q.QuestionID,
 			Topic:      q.Topic,
 			QType:      q.QType,
 			Difficulty: q.Difficulty,
-			Payload:    q.Payload,
+			Payload:    publicPayload(q.Payload), // правильные ответы в страницу не попадают
 		})
 	}
 
-	var tl int
+	// таймер — от начала попытки по серверу; 0 — без ограничения
+	var left int
 	if rules.TimeLimitSec > 0 {
-		tl = rules.TimeLimitSec
+		left = max(1, rules.TimeLimitSec-int(time.Since(att.StartedAt).Seconds()))
 	}
 
 	s.render(w, r, "quiz", map[string]any{
-		"Title":        title,
-		"AttemptID":    attemptID,
-		"Questions":    vqs,
-		"TimeLimitSec": tl,
-		"QuizID":       quizID,
+		"Title":       title,
+		"AttemptID":   att.ID,
+		"Questions":   vqs,
+		"TimeLeftSec": left,
+		"QuizID":      att.QuizID,
 	})
 }
 
 func (s *Server) handleQuizFinish(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
 	if err := r.ParseForm(); err != nil {
 		http.Error(w, err.Error(), 400)
 		return
 	}
+	uid, _ := a.CurrentUserID(r)
 	attemptID, _ := strconv.ParseInt(r.FormValue("attempt_id"), 10, 64)
-	quizID, _ := strconv.ParseInt(r.FormValue("quiz_id"), 10, 64)
-	clientElapsed, _ := strconv.ParseInt(r.FormValue("elapsed_sec"), 10, 64)
 
-	values := map[int64][]string{}
-	var qIDs []int64
-	for key, vals := range r.PostForm {
-		if !strings.HasPrefix(key, "q_") {
-			continue
+	answers := map[int64][]string{}
+	for k, v := range r.PostForm {
+		if id, err := strconv.ParseInt(strings.TrimPrefix(k, "q_"), 10, 64); err == nil && strings.HasPrefix(k, "q_") {
+			answers[id] = v
 		}
-		idStr := strings.TrimPrefix(key, "q_")
-		qid, err := strconv.ParseInt(idStr, 10, 64)
-		if err != nil {
-			continue
+	}
+
+	att, err := s.submitAttempt(r.Context(), uid, attemptID, answers)
+	var closed *attemptClosed
+	switch {
+	case errors.As(err, &closed):
+		s.renderClosed(w, r, closed)
+		return
+	case errors.Is(err, repo.ErrAttemptNotFound):
+		http.Error(w, "attempt not found", 404)
+		return
+	case errors.Is(err, repo.ErrAttemptNotOwned):
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	case err != nil:
+		s.serverError(w, r, err)
+		return
+	}
+	s.renderAttemptResult(w, r, att.ID)
+}
+
+// startDenied — квиз сейчас начать нельзя (лимит попыток, кулдаун, пустой банк);
+// Title/Message — текст для пользователя.
+type startDenied struct {
+	Key  string // ключ сообщения; заголовок страницы — Key+"_title"
+	Args []any
+}
+
+func (e *startDenied) Error() string            { return i18n.Default(e.Key, e.Args...) }
+func (e *startDenied) Message() (string, []any) { return e.Key, e.Args }
+
+type startedAttempt struct {
+	AttemptID int64
+	Title     string
+	Rules     *repo.QuizRules
+	Questions []repo.QuestionRow
+}
+
+// startAttempt проверяет ограничения квиза, подбирает вопросы из банка
+// его курса и заводит попытку.
+func (s *Server) startAttempt(ctx context.Context, uid int64, quiz *repo.QuizRow) (*startedAttempt, error) {
+	rules, title, err := s.Repo.LoadQuizRules(ctx, quiz.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// лимиты
+	if rules.MaxAttempts > 0 {
+		total, _ := s.Repo.TotalAttemptsByUserQuiz(ctx, uid, quiz.ID)
+		if total >= rules.MaxAttempts {
+			return nil, &startDenied{Key: "start.max_attempts"}
+		}
+	}
+	if rules.RetakeCooldownSec > 0 {
+		since := time.Now().Add(-time.Duration(rules.RetakeCooldownSec) * time.Second)
+		count, _ := s.Repo.AttemptsSinceByUserQuiz(ctx, uid, quiz.ID, since)
+		if count > 0 {
+			return nil, &startDenied{Key: "start.cooldown"}
+		}
+	}
+
+	qs, err := s.Repo.PickQuestions(ctx, quiz.CourseID, rules)
+	if err != nil {
+		var short *repo.NotEnoughQuestionsError
+		if errors.As(err, &short) {
+			return nil, &startDenied{Key: "start.unavailable", Args: []any{short}}
 		}
-		qIDs = append(qIDs, qid)
-		values[qid] = vals
+		return nil, err
 	}
-	qs, err := s.Repo.FetchQuestionsByIDs(r.Context(), qIDs)
+	attemptID, err := s.Repo.StartAttempt(ctx, quiz.ID, uid, qs)
 	if err != nil {
-		http.Error(w, err.Error(), 500)
-		return
+		return nil, err
 	}
+	s.Metrics.AttemptsStarted.Inc()
+	return &startedAttempt{AttemptID: attemptID, Title: title, Rules: rules, Questions: qs}, nil
+}
+
+// attemptClosed — попытку сдать уже нельзя и итога у неё нет: брошена
+// (начата новая) или аннулирована преподавателем.
+type attemptClosed struct {
+	Status string
+}
 
-	var rules *repo.QuizRules
-	if quizID > 0 {
-		rules, _, _ = s.Repo.LoadQuizRules(r.Context(), quizID)
+func (e *attemptClosed) key() string              { return "finish." + e.Status }
+func (e *attemptClosed) Error() string            { return i18n.Default(e.key()) }
+func (e *attemptClosed) Message() (string, []any) { return e.key(), nil }
+
+// submitAttempt проверяет и сдаёт попытку. answers — сырые значения по id вопроса
+// (в том виде, в каком их присылает форма). Повторная сдача сданной или
+// просроченной попытки не ошибка: возвращается уже зафиксированное состояние;
+// брошенная или аннулированная — *attemptClosed. Опоздание сохраняется
+// фактами (repo.Lateness), текст собирается при показе.
+func (s *Server) submitAttempt(ctx context.Context, uid, attemptID int64, answers map[int64][]string) (*repo.AttemptInfo, error) {
+	att, err := s.Repo.GetAttempt(ctx, attemptID)
+	if err != nil {
+		return nil, err
+	}
+	if att.UserID != uid {
+		return nil, repo.ErrAttemptNotOwned
+	}
+	if att.Status != repo.AttemptInProgress {
+		// повторная отправка (двойной клик, «назад» в браузере) — просто показываем итог
+		return finishedAttempt(att)
 	}
 
-	var correctCount int
-	for _, q := range qs {
-		rawVals := values[q.ID]
-		var isCorrect *bool
-		var ansJSON []byte
+	rules, _, err := s.Repo.LoadQuizRules(ctx, att.QuizID)
+	if err != nil {
+		return nil, err
+	}
 
-		switch q.QType {
-		case "single":
-			var p struct {
-				Text    string
-				Choices []string
-				Correct []int
-			}
-			_ = json.Unmarshal(q.Payload, &p)
-			chosenIdx, _ := strconv.Atoi(firstOrEmpty(rawVals))
-			ok := len(p.Correct) > 0 && chosenIdx == p.Correct[0]
-			isCorrect = &ok
-			if ok {
-				correctCount++
-			}
-			ansJSON, _ = json.Marshal(map[string]any{"type": "single", "chosen": chosenIdx})
+	// время считаем только по серверу: started_at -> сейчас
+	now := time.Now()
+	dur := int(now.Sub(att.StartedAt).Seconds())
+	var late repo.Lateness
+	overtime := false
+	if rules.TimeLimitSec > 0 && dur > rules.TimeLimitSec+rules.GraceSec {
+		overtime = true
+		late = repo.Lateness{Policy: rules.LatePolicy, Sec: dur - rules.TimeLimitSec}
+		if late.Policy == repo.LatePenalty {
+			late.PenaltyPct = rules.LatePenaltyPct
+		}
+	}
 
-		case "multiple":
-			var p struct {
-				Text    string
-				Choices []string
-				Correct []int
-			}
-			_ = json.Unmarshal(q.Payload, &p)
-			var chosen []int
-			for _, sv := range rawVals {
-				if i, err := strconv.Atoi(sv); err == nil {
-					chosen = append(chosen, i)
-				}
-			}
-			ok := setEq(intSliceToSet(chosen), intSliceToSet(p.Correct))
-			isCorrect = &ok
-			if ok {
-				correctCount++
-			}
-			ansJSON, _ = json.Marshal(map[string]any{"type": "multiple", "chosen": chosen})
+	// оцениваем ровно тот набор, что был выдан на старте;
+	// лишние ответы игнорируются, невыданные вопросы — не засчитываются
+	issued, err := s.Repo.ListAttemptQuestions(ctx, attemptID)
+	if err != nil {
+		return nil, err
+	}
 
-		case "numeric":
-			var p struct {
-				Text         string
-				CorrectValue float64
-			}
-			_ = json.Unmarshal(q.Payload, &p)
-			val, _ := strconv.ParseFloat(firstOrEmpty(rawVals), 64)
-			ok := abs(val-p.CorrectValue) < 1e-9
-			isCorrect = &ok
+	res := repo.AttemptResult{
+		Status:      repo.AttemptSubmitted,
+		FinishedAt:  now,
+		DurationSec: dur,
+		Overtime:    overtime,
+		Late:        late,
+	}
+	var correctCount int
+	if overtime && rules.LatePolicy == repo.LateReject {
+		res.Status = repo.AttemptExpired
+	} else {
+		for _, q := range issued {
+			ok, ansJSON := repo.GradeAnswer(q.QType, q.Payload, answers[q.QuestionID])
 			if ok {
 				correctCount++
 			}
-			ansJSON, _ = json.Marshal(map[string]any{"type": "numeric", "value": val})
+			res.Answers = append(res.Answers, repo.AnswerInput{QuestionID: q.QuestionID, IsCorrect: &ok, Answer: ansJSON})
+		}
+	}
 
-		case "text":
-			var p struct {
-				Text   string
-				Accept []string
-			}
-			_ = json.Unmarshal(q.Payload, &p)
-			ans := strings.TrimSpace(firstOrEmpty(rawVals))
-			ok := containsCI(p.Accept, ans)
-			isCorrect = &ok
-			if ok {
-				correctCount++
+	res.Score = rules.LateScore(float64(correctCount), overtime)
+
+	// ErrAttemptFinished — параллельная отправка успела раньше, отдаём её итог
+	switch err := s.Repo.FinishAttempt(ctx, attemptID, uid, res); {
+	case err == nil:
+		s.Metrics.AttemptsFinished.With(res.Status).Inc()
+		if overtime {
+			policy := rules.LatePolicy
+			if policy == "" {
+				policy = repo.LateAccept
 			}
-			ansJSON, _ = json.Marshal(map[string]any{"type": "text", "value": ans})
-		}
-		if err := s.Repo.SaveAnswer(r.Context(), attemptID, q.ID, isCorrect, ansJSON); err != nil {
-			http.Error(w, err.Error(), 500)
-			return
+			s.Metrics.AttemptsOvertime.With(policy).Inc()
 		}
+	case !errors.Is(err, repo.ErrAttemptFinished):
+		return nil, err
+	}
+	if att, err = s.Repo.GetAttempt(ctx, attemptID); err != nil {
+		return nil, err
+	}
+	return finishedAttempt(att)
+}
+
+// finishedAttempt — итог закрытой попытки; у брошенной и аннулированной
+// его нет (балл 0 выглядел бы как результат), для них — *attemptClosed.
+func finishedAttempt(att *repo.AttemptInfo) (*repo.AttemptInfo, error) {
+	if att.Status == repo.AttemptAbandoned || att.Status == repo.AttemptVoided {
+		return nil, &attemptClosed{Status: att.Status}
 	}
+	return att, nil
+}
 
-	score := float64(correctCount)
-	now := time.Now()
-	if err := s.Repo.SetAttemptResult(r.Context(), attemptID, &now, &score); err != nil {
-		http.Error(w, err.Error(), 500)
+// renderFinished — итог завершённой попытки или, у брошенной и
+// аннулированной, объяснение, почему итога нет.
+func (s *Server) renderFinished(w http.ResponseWriter, r *http.Request, att *repo.AttemptInfo) {
+	var closed *attemptClosed
+	if _, err := finishedAttempt(att); errors.As(err, &closed) {
+		s.renderClosed(w, r, closed)
 		return
 	}
+	s.renderAttemptResult(w, r, att.ID)
+}
 
-	dur := int(clientElapsed)
-	overtime := false
-	if rules != nil && rules.TimeLimitSec > 0 && dur > rules.TimeLimitSec {
-		overtime = true
+func (s *Server) renderClosed(w http.ResponseWriter, r *http.Request, closed *attemptClosed) {
+	l := s.locale(r)
+	s.render(w, r, "message", map[string]any{"Title": l.T(closed.key() + "_title"), "Message": l.Err(closed)})
+}
+
+// renderAttemptResult показывает итог попытки по данным из БД.
+func (s *Server) renderAttemptResult(w http.ResponseWriter, r *http.Request, attemptID int64) {
+	att, err := s.Repo.GetAttempt(r.Context(), attemptID)
+	if err != nil {
+		s.serverError(w, r, err)
+		return
 	}
-	_ = s.Repo.SetAttemptTiming(r.Context(), attemptID, dur, overtime)
 
 	// === считаем номер попытки для ЭТОГО квиза и ЭТОГО пользователя ===
-	uid, _ := a.CurrentUserID(r)
 	attemptNo := 0
-	if uid > 0 && quizID > 0 {
-		if n, err := s.Repo.TotalAttemptsByUserQuiz(r.Context(), uid, quizID); err == nil {
-			// n — количество попыток по этому квизу (включая текущую)
-			attemptNo = n
-		}
+	if n, err := s.Repo.TotalAttemptsByUserQuiz(r.Context(), att.UserID, att.QuizID); err == nil {
+		// n — количество попыток по этому квизу (включая текущую)
+		attemptNo = n
 	}
 
 	s.render(w, r, "result", map[string]any{
-		"AttemptID": attemptID, // глобальный ID на всякий случай
-		"AttemptNo": attemptNo, // номер попытки для этого квиза
-		"Score":     score,
+		"AttemptID":  attemptID, // глобальный ID на всякий случай
+		"AttemptNo":  attemptNo, // номер попытки для этого квиза
+		"Score":      att.Score,
+		"Late":       att.Late,
+		"Status":     att.Status,
 	})
 }
 
@@ -534,21 +1320,7 @@ func (s *Server) handleTopics(w http.ResponseWriter, r *http.Request) {
 	// статистика по темам ТЕПЕРЬ по всем курсам пользователя
 	stats, _ := s.Repo.TopicStatsByUser(r.Context(), uid)
 
-	type Row struct {
-		Topic   string
-		Total   int
-		Correct int
-		Percent int
-	}
-	var rows []Row
-	for _, st := range stats {
-		p := 0
-		if st.Total > 0 {
-			p = int((float64(st.Correct)/float64(st.Total))*100.0 + 0.5)
-		}
-		rows = append(rows, Row{Topic: st.Topic, Total: st.Total, Correct: st.Correct, Percent: p})
-	}
-	s.render(w, r, "topics", map[string]any{"Rows": rows})
+	s.render(w, r, "topics", map[string]any{"Rows": stats})
 }
 
 func (s *Server) handleTopicProfile(w http.ResponseWriter, r *http.Request) {
@@ -563,44 +1335,13 @@ func (s *Server) handleTopicProfile(w http.ResponseWriter, r *http.Request) {
 	// история по теме тоже по всем курсам
 	detail, err := s.Repo.TopicDetail(r.Context(), uid, topic)
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		s.serverError(w, r, err)
 		return
 	}
 
-	type Row struct {
-		Ord     int
-		QID     int64
-		WhenStr string
-		Status  string
-	}
-
-	rows := make([]Row, 0, len(detail))
-	for i, d := range detail {
-
-		// красивое время
-		when := d.When.In(time.Local).Format("02.01.2006 15:04")
-
-		// строка статуса
-		st := "—"
-		if d.Correct != nil {
-			if *d.Correct {
-				st = "✔ Верно"
-			} else {
-				st = "✘ Неверно"
-			}
-		}
-
-		rows = append(rows, Row{
-			Ord:     i + 1,
-			QID:     d.QID,
-			WhenStr: when,
-			Status:  st,
-		})
-	}
-
 	s.render(w, r, "topic", map[string]any{
 		"Topic": topic,
-		"Rows":  rows,
+		"Rows":  detail,
 	})
 }
 
@@ -636,7 +1377,7 @@ func (s *Server) handleAdminUploadGetPost(w http.ResponseWriter, r *http.Request
 
 		count, err := s.Repo.ImportQuestionsCSV(r.Context(), reader, courseID)
 		if err != nil {
-			http.Error(w, err.Error(), 400)
+			http.Error(w, s.locale(r).Err(err), 400)
 			return
 		}
 
@@ -658,7 +1399,7 @@ func (s *Server) handleAdminUploadJSON(w http.ResponseWriter, r *http.Request) {
 			cs, _ := s.Repo.ListCourses(r.Context())
 			s.render(w, r, "admin_upload_json", map[string]any{
 				"Courses": cs,
-				"Error":   "Нужно выбрать курс.",
+				"Error":   s.locale(r).T("upload_json.no_course"),
 			})
 			return
 		}
@@ -668,7 +1409,10 @@ func (s *Server) handleAdminUploadJSON(w http.ResponseWriter, r *http.Request) {
 		file, _, err := r.FormFile("file")
 		if err == nil {
 			defer file.Close()
-			raw, _ = io.ReadAll(file)
+			if raw, err = io.ReadAll(file); err != nil {
+				http.Error(w, err.Error(), 400)
+				return
+			}
 		} else {
 			raw = []byte(r.FormValue("json"))
 		}
@@ -681,7 +1425,7 @@ func (s *Server) handleAdminUploadJSON(w http.ResponseWriter, r *http.Request) {
 			s.render(w, r, "admin_upload_json", map[string]any{
 				"Courses":  cs,
 				"Selected": courseID,
-				"Error":    "JSON не должен быть пустым.",
+				"Error":    s.locale(r).T("upload_json.empty"),
 				"JsonRaw":  rawStr,
 			})
 			return
@@ -689,10 +1433,10 @@ func (s *Server) handleAdminUploadJSON(w http.ResponseWriter, r *http.Request) {
 
 		n, err := s.Repo.ImportQuestionsJSON(r.Context(), []byte(rawStr), courseID)
 		if err != nil {
-			msg := err.Error()
+			msg := s.locale(r).Err(err)
 			if strings.Contains(msg, "cannot unmarshal object into Go value of type []") {
 				// типовая ошибка: засунули один объект вместо массива
-				msg = "Некорректный формат JSON: ожидается массив вопросов `[ ... ]`, а не один объект `{ ... }`."
+				msg = s.locale(r).T("upload_json.object")
 			}
 			cs, _ := s.Repo.ListCourses(r.Context())
 			s.render(w, r, "admin_upload_json", map[string]any{
@@ -719,6 +1463,16 @@ func (s *Server) handleAdminUploadJSON(w http.ResponseWriter, r *http.Request) {
 
 /* ===== Админ: пользователи/курсы/квизы/результаты ===== */
 
+// adminError отвечает на неудачное изменение из админки: объекта уже нет — 404,
+// иначе — 400 с текстом ошибки на языке пользователя.
+func (s *Server) adminError(w http.ResponseWriter, r *http.Request, err error) {
+	code := http.StatusBadRequest
+	if errors.Is(err, repo.ErrNotFound) {
+		code = http.StatusNotFound
+	}
+	http.Error(w, s.locale(r).Err(err), code)
+}
+
 func (s *Server) handleAdminUsers(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
@@ -730,13 +1484,66 @@ func (s *Server) handleAdminUsers(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		id, _ := strconv.ParseInt(r.FormValue("user_id"), 10, 64)
+		switch r.FormValue("action") {
+		case "kill_sessions":
+			if _, err := s.Repo.DeleteUserSessions(r.Context(), id, 0); err != nil {
+				s.serverError(w, r, err)
+				return
+			}
+			http.Redirect(w, r, "/admin/users", http.StatusSeeOther)
+			return
+		case "unlock":
+			if err := s.Repo.UnlockUser(r.Context(), id); err != nil {
+				s.serverError(w, r, err)
+				return
+			}
+			if err := s.Limiter.Reset(r.Context(), ratelimit.Account, strconv.FormatInt(id, 10)); err != nil {
+				s.serverError(w, r, err)
+				return
+			}
+			http.Redirect(w, r, "/admin/users", http.StatusSeeOther)
+			return
+		case "reset_2fa":
+			// пользователь потерял телефон и коды: выключаем 2FA и выкидываем все его сессии
+			if err := s.Repo.DisableTOTP(r.Context(), id); err != nil {
+				s.serverError(w, r, err)
+				return
+			}
+			if _, err := s.Repo.DeleteUserSessions(r.Context(), id, 0); err != nil {
+				s.serverError(w, r, err)
+				return
+			}
+			http.Redirect(w, r, "/admin/users", http.StatusSeeOther)
+			return
+		case "force_verify":
+			if err := s.Repo.SetUserVerified(r.Context(), id); err != nil {
+				s.serverError(w, r, err)
+				return
+			}
+			http.Redirect(w, r, "/admin/users", http.StatusSeeOther)
+			return
+		case "resend_verification":
+			u, err := s.Repo.GetUser(r.Context(), id)
+			if err != nil {
+				http.Error(w, err.Error(), 404)
+				return
+			}
+			if !u.Verified() {
+				if err := s.sendVerification(r, u.ID, u.Email); err != nil {
+					s.serverError(w, r, &mailError{err})
+					return
+				}
+			}
+			http.Redirect(w, r, "/admin/users", http.StatusSeeOther)
+			return
+		}
 		role := strings.TrimSpace(r.FormValue("role"))
 		if role == "" {
 			http.Error(w, "role required", 400)
 			return
 		}
 		if err := s.Repo.UpdateUserRole(r.Context(), id, role); err != nil {
-			http.Error(w, err.Error(), 400)
+			s.adminError(w, r, err)
 			return
 		}
 		http.Redirect(w, r, "/admin/users", http.StatusSeeOther)
@@ -758,7 +1565,7 @@ func (s *Server) handleAdminCourses(w http.ResponseWriter, r *http.Request) {
 				http.Error(w, "title required", 400)
 				return
 			}
-			if err := s.Repo.CreateCourse(r.Context(), title, desc); err != nil {
+			if _, err := s.Repo.CreateCourse(r.Context(), title, desc); err != nil {
 				http.Error(w, err.Error(), 400)
 				return
 			}
@@ -767,13 +1574,13 @@ func (s *Server) handleAdminCourses(w http.ResponseWriter, r *http.Request) {
 			title := strings.TrimSpace(r.FormValue("title"))
 			desc := strings.TrimSpace(r.FormValue("description"))
 			if err := s.Repo.UpdateCourse(r.Context(), id, title, desc); err != nil {
-				http.Error(w, err.Error(), 400)
+				s.adminError(w, r, err)
 				return
 			}
 		case "delete":
 			id, _ := strconv.ParseInt(r.FormValue("id"), 10, 64)
 			if err := s.Repo.DeleteCourse(r.Context(), id); err != nil {
-				http.Error(w, err.Error(), 400)
+				s.adminError(w, r, err)
 				return
 			}
 		}
@@ -818,14 +1625,14 @@ func (s *Server) handleAdminQuizzes(w http.ResponseWriter, r *http.Request) {
 					"Courses":   cs,
 					"Selected":  cid,
 					"Quizzes":   qs,
-					"Error":     "Нужно заполнить название и JSON с правилами квиза.",
+					"Error":     s.locale(r).T("admin_quizzes.required"),
 					"FormTitle": title,
 					"FormRules": rules,
 				})
 				return
 			}
 
-			if err := s.Repo.CreateQuiz(r.Context(), cid, title, []byte(rules)); err != nil {
+			if _, err := s.Repo.CreateQuiz(r.Context(), cid, title, []byte(rules)); err != nil {
 				// здесь уже либо "ошибка в JSON-правилах: ...", либо текст из Validate()
 				cs, _ := s.Repo.ListCourses(r.Context())
 				qs, _ := s.Repo.ListQuizzesByCourse(r.Context(), cid)
@@ -833,7 +1640,7 @@ func (s *Server) handleAdminQuizzes(w http.ResponseWriter, r *http.Request) {
 					"Courses":   cs,
 					"Selected":  cid,
 					"Quizzes":   qs,
-					"Error":     err.Error(),
+					"Error":     s.locale(r).Err(err),
 					"FormTitle": title,
 					"FormRules": rules,
 				})
@@ -846,7 +1653,7 @@ func (s *Server) handleAdminQuizzes(w http.ResponseWriter, r *http.Request) {
 			qid, _ := strconv.ParseInt(r.FormValue("quiz_id"), 10, 64)
 			cid, _ := strconv.ParseInt(r.FormValue("course_id"), 10, 64)
 			if err := s.Repo.DeleteQuiz(r.Context(), qid); err != nil {
-				http.Error(w, err.Error(), http.StatusBadRequest)
+				s.adminError(w, r, err)
 				return
 			}
 			http.Redirect(w, r, "/admin/quizzes?course_id="+strconv.FormatInt(cid, 10), http.StatusSeeOther)
@@ -854,23 +1661,6 @@ func (s *Server) handleAdminQuizzes(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-type adminResultAttempt struct {
-	Ord       int
-	ID        int64
-	UserEmail string
-	QuizTitle string
-
-	WhenStr  string
-	HasScore bool
-	ScoreVal float64
-}
-
-type adminResultsPage struct {
-	Courses  []repo.CourseRow
-	Selected int64
-	Attempts []adminResultAttempt
-}
-
 func (s *Server) handleAdminResults(w http.ResponseWriter, r *http.Request) {
 	cid := int64(1)
 	if v := r.URL.Query().Get("course_id"); v != "" {
@@ -881,56 +1671,36 @@ func (s *Server) handleAdminResults(w http.ResponseWriter, r *http.Request) {
 
 	cs, err := s.Repo.ListCourses(r.Context())
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		s.serverError(w, r, err)
 		return
 	}
 
 	rows, err := s.Repo.ListAttemptsByCourse(r.Context(), cid)
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		s.serverError(w, r, err)
 		return
 	}
 
-	view := make([]adminResultAttempt, 0, len(rows))
-	for i, a := range rows {
-		var whenStr string
-		if a.FinishedAt != nil {
-			whenStr = a.FinishedAt.In(time.Local).Format("02.01.2006 15:04:05")
-		} else {
-			whenStr = ""
-		}
-
-		hasScore := a.Score != nil
-		val := 0.0
-		if a.Score != nil {
-			val = *a.Score
-		}
-
-		view = append(view, adminResultAttempt{
-			Ord:       i + 1,
-			ID:        a.ID,
-			UserEmail: a.UserEmail,
-			QuizTitle: a.QuizTitle,
-			WhenStr:   whenStr,
-			HasScore:  hasScore,
-			ScoreVal:  val,
-		})
-	}
-
-	page := adminResultsPage{
-		Courses:  cs,
-		Selected: cid,
-		Attempts: view,
-	}
-
 	s.render(w, r, "admin_results", map[string]any{
-		"Courses":  page.Courses,
-		"Selected": page.Selected,
-		"Attempts": page.Attempts,
+		"Courses":  cs,
+		"Selected": cid,
+		"Attempts": rows,
 	})
 }
 
 func (s *Server) handleAdminAttemptDetail(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		aid, _ := strconv.ParseInt(r.FormValue("id"), 10, 64)
+		if r.FormValue("action") == "void" {
+			if err := s.Repo.SetAttemptStatus(r.Context(), aid, repo.AttemptVoided); err != nil {
+				http.Error(w, err.Error(), 400)
+				return
+			}
+		}
+		http.Redirect(w, r, "/admin/attempt?id="+strconv.FormatInt(aid, 10), http.StatusSeeOther)
+		return
+	}
+
 	idStr := r.URL.Query().Get("id")
 	if idStr == "" {
 		http.Error(w, "id required", 400)
@@ -940,54 +1710,10 @@ func (s *Server) handleAdminAttemptDetail(w http.ResponseWriter, r *http.Request
 
 	meta, answers, err := s.Repo.GetAttemptWithAnswers(r.Context(), aid)
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		s.serverError(w, r, err)
 		return
 	}
 
-	// --- аккуратные строки для хедера попытки ---
-	started := meta.StartedAt.In(time.Local).Format("02.01.2006 15:04:05")
-
-	finished := "—"
-	if meta.FinishedAt != nil {
-		finished = meta.FinishedAt.In(time.Local).Format("02.01.2006 15:04:05")
-	}
-
-	scoreStr := "—"
-	if meta.Score != nil {
-		scoreStr = strconv.FormatFloat(*meta.Score, 'f', 0, 64)
-	}
-
-	durationStr := "—"
-	if meta.DurationSec != nil {
-		durationStr = strconv.Itoa(*meta.DurationSec) + " с"
-	}
-
-	overtimeStr := "Нет"
-	if meta.Overtime {
-		overtimeStr = "Да"
-	}
-
-	metaView := struct {
-		ID        int64
-		UserEmail string
-		QuizTitle string
-
-		StartedAt  string
-		FinishedAt string
-		Score      string
-		Duration   string
-		Overtime   string
-	}{
-		ID:         meta.ID,
-		UserEmail:  meta.UserEmail,
-		QuizTitle:  meta.QuizTitle,
-		StartedAt:  started,
-		FinishedAt: finished,
-		Score:      scoreStr,
-		Duration:   durationStr,
-		Overtime:   overtimeStr,
-	}
-
 	// --- детали вопросов ---
 	type Row struct {
 		Idx        int
@@ -997,7 +1723,7 @@ func (s *Server) handleAdminAttemptDetail(w http.ResponseWriter, r *http.Request
 		Text       string
 		UserAnswer string
 		Correct    string
-		Status     string // уже готовая строка для колонки "Статус"
+		IsCorrect  *bool
 	}
 
 	var out []Row
@@ -1069,16 +1795,6 @@ func (s *Server) handleAdminAttemptDetail(w http.ResponseWriter, r *http.Request
 			}
 		}
 
-		// статус
-		status := "—"
-		if a1.IsCorrect != nil {
-			if *a1.IsCorrect {
-				status = "✔"
-			} else {
-				status = "✘"
-			}
-		}
-
 		out = append(out, Row{
 			Idx:        len(out) + 1,
 			QuestionID: a1.QuestionID,
@@ -1087,13 +1803,14 @@ func (s *Server) handleAdminAttemptDetail(w http.ResponseWriter, r *http.Request
 			Text:       q.Text,
 			UserAnswer: ua,
 			Correct:    correctText,
-			Status:     status,
+			IsCorrect:  a1.IsCorrect,
 		})
 	}
 
 	s.render(w, r, "admin_attempt", map[string]any{
-		"Meta": metaView,
-		"Rows": out,
+		"Meta":    meta,
+		"CanVoid": meta.Status != repo.AttemptVoided,
+		"Rows":    out,
 	})
 }
 
@@ -1114,77 +1831,23 @@ func (s *Server) handleAdminResultsExport(w http.ResponseWriter, r *http.Request
 	}
 	rows, err := s.Repo.ExportAttempts(r.Context(), courseID, quizID)
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		s.serverError(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
 	w.Header().Set("Content-Disposition", "attachment; filename=\"results.csv\"")
 	cw := csv.NewWriter(w)
-	_ = cw.Write([]string{"attempt_id", "user_email", "course_id", "quiz_id", "quiz_title", "started_at", "finished_at", "score", "duration_sec", "overtime"})
+	_ = cw.Write(repo.AttemptCSVHeader)
 	for _, r0 := range rows {
-		finished := ""
-		if r0.FinishedAt != nil {
-			finished = r0.FinishedAt.Format(time.RFC3339)
-		}
-		score := ""
-		if r0.Score != nil {
-			score = strconv.FormatFloat(*r0.Score, 'f', -1, 64)
-		}
-		dur := ""
-		if r0.Duration != nil {
-			dur = strconv.Itoa(*r0.Duration)
-		}
-		_ = cw.Write([]string{
-			strconv.FormatInt(r0.AttemptID, 10),
-			r0.UserEmail,
-			strconv.FormatInt(r0.CourseID, 10),
-			strconv.FormatInt(r0.QuizID, 10),
-			r0.QuizTitle,
-			r0.StartedAt.Format(time.RFC3339),
-			finished,
-			score,
-			dur,
-			strconv.FormatBool(r0.Overtime),
-		})
+		_ = cw.Write(repo.AttemptCSVRecord(r0))
 	}
 	cw.Flush()
+	// заголовки уже отправлены: обрыв выгрузки можно только записать в лог
+	logError(r, "results export", cw.Error())
 }
 
 /*** helpers ***/
-func firstOrEmpty(a []string) string {
-	if len(a) > 0 {
-		return a[0]
-	}
-	return ""
-}
-
-func intSliceToSet(a []int) map[int]struct{} {
-	m := map[int]struct{}{}
-	for _, v := range a {
-		m[v] = struct{}{}
-	}
-	return m
-}
-
-func setEq(a, b map[int]struct{}) bool {
-	if len(a) != len(b) {
-		return false
-	}
-	for k := range a {
-		if _, ok := b[k]; !ok {
-			return false
-		}
-	}
-	return true
-}
-
-func abs(x float64) float64 {
-	if x < 0 {
-		return -x
-	}
-	return x
-}
 
 func containsCI(hay []string, needle string) bool {
 	n := strings.ToLower(strings.TrimSpace(needle))
@@ -1262,7 +1925,7 @@ func (s *Server) handleAdminQuestionEdit(w http.ResponseWriter, r *http.Request)
 			raw = []byte(payload)
 		}
 		if err := s.Repo.UpdateQuestion(r.Context(), id, topic, qtype, diff, raw); err != nil {
-			http.Error(w, err.Error(), 400)
+			s.adminError(w, r, err)
 			return
 		}
 		http.Redirect(w, r, "/admin/questions/edit?id="+strconv.FormatInt(id, 10), http.StatusSeeOther)
@@ -1282,68 +1945,13 @@ func (s *Server) handleAdminLogsByUser(w http.ResponseWriter, r *http.Request) {
 
 	summary, rows, err := s.Repo.UserLogs(r.Context(), uid)
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		s.serverError(w, r, err)
 		return
 	}
 
-	// --- аккуратно форматируем сводку ---
-	lastAtStr := "—"
-	if summary.LastAt != nil {
-		lastAtStr = summary.LastAt.In(time.Local).Format("02.01.2006 15:04:05")
-	}
-
-	type summaryView struct {
-		UserEmail string
-		Attempts  int
-		Correct   int
-		Wrong     int
-		LastAt    string
-	}
-
-	sumView := summaryView{
-		UserEmail: summary.UserEmail,
-		Attempts:  summary.Attempts,
-		Correct:   summary.Correct,
-		Wrong:     summary.Wrong,
-		LastAt:    lastAtStr,
-	}
-
-	// --- приводим строки логов к виду для шаблона ---
-	type rowView struct {
-		When   string
-		Action string
-		Detail string
-	}
-
-	viewRows := make([]rowView, 0, len(rows))
-	for _, r0 := range rows {
-		whenStr := r0.When.In(time.Local).Format("02.01.2006 15:04:05")
-
-		status := "—"
-		if r0.IsCorrect != nil {
-			if *r0.IsCorrect {
-				status = "верно"
-			} else {
-				status = "неверно"
-			}
-		}
-
-		action := "Ответ по вопросу"
-		detail := "Тема: " + r0.Topic +
-			", тип: " + r0.QType +
-			", статус: " + status +
-			", попытка #" + strconv.FormatInt(r0.AttemptID, 10)
-
-		viewRows = append(viewRows, rowView{
-			When:   whenStr,
-			Action: action,
-			Detail: detail,
-		})
-	}
-
 	s.render(w, r, "admin_logs", map[string]any{
-		"Summary": sumView,
-		"Rows":    viewRows,
+		"Summary": summary,
+		"Rows":    rows,
 		"UserID":  uid,
 	})
 }
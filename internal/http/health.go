@@ -0,0 +1,45 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// readyTimeout — сколько /readyz ждёт БД, прежде чем ответить 503.
+const readyTimeout = 3 * time.Second
+
+// Drain переводит /readyz в 503: вызывается перед остановкой сервера,
+// чтобы балансировщик перестал слать новые запросы.
+func (s *Server) Drain() { s.draining.Store(true) }
+
+// handleHealthz — процесс жив и обслуживает запросы; БД не трогает.
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte("ok\n"))
+}
+
+// handleReadyz — готов принимать трафик: БД отвечает, все миграции применены,
+// сервер не останавливается.
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	if s.draining.Load() {
+		http.Error(w, "shutting down", http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+	defer cancel()
+	if err := s.DB.PingContext(ctx); err != nil {
+		http.Error(w, "db: "+err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	if s.Migrator != nil {
+		if err := s.Migrator.Check(ctx); err != nil {
+			http.Error(w, "schema: "+err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+	}
+	w.Write([]byte("ok\n"))
+}
@@ -0,0 +1,83 @@
+package httpx
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"learny/internal/logx"
+)
+
+// LogRequests присваивает запросу id (или берёт корректный X-Request-ID от прокси),
+// отдаёт его в заголовке X-Request-ID и после ответа пишет строку журнала:
+// маршрут, статус, время, пользователь. Ставится снаружи WithUser:
+// пользователя WithUser дописывает в logx.Request.
+// Проверки здоровья и /metrics пишутся на уровне debug.
+// ClientIP — адрес клиента для журнала аудита (Server.ClientIP).
+func LogRequests(mux *http.ServeMux, clientIP func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get("X-Request-ID")
+			if !logx.ValidRequestID(id) {
+				id = logx.NewRequestID()
+			}
+			w.Header().Set("X-Request-ID", id)
+			r = r.WithContext(logx.WithRequest(r.Context(), &logx.Request{ID: id, IP: clientIP(r)}))
+
+			start := time.Now()
+			sw := &statusWriter{ResponseWriter: w, code: http.StatusOK}
+			next.ServeHTTP(sw, r)
+
+			route := routeOf(mux, r)
+			level := slog.LevelInfo
+			switch {
+			case sw.code >= 500:
+				level = slog.LevelError
+			case route == "/healthz" || route == "/readyz" || route == "/metrics":
+				level = slog.LevelDebug
+			}
+			logx.Logger(r.Context()).LogAttrs(r.Context(), level, "request",
+				slog.String("method", r.Method),
+				slog.String("route", route),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", sw.code),
+				slog.Int("bytes", sw.bytes),
+				slog.Duration("duration", time.Since(start)),
+			)
+		})
+	}
+}
+
+// routeOf — шаблон маршрута mux без метода ("/api/v1/quizzes/{id}"),
+// чтобы в метках и логе не было id из URL.
+func routeOf(mux *http.ServeMux, r *http.Request) string {
+	_, pattern := mux.Handler(r)
+	if i := strings.IndexByte(pattern, ' '); i >= 0 {
+		pattern = pattern[i+1:] // "GET /x" → "/x": метод пишется отдельно
+	}
+	pattern = strings.TrimSuffix(pattern, "{$}")
+	if pattern == "" {
+		return "unmatched"
+	}
+	return pattern
+}
+
+// logError пишет в лог ошибку, из-за которой запрос не прерываем.
+func logError(r *http.Request, msg string, err error) {
+	if err != nil {
+		logx.Logger(r.Context()).Warn(msg, "err", err)
+	}
+}
+
+// serverError пишет ошибку обработчика в лог с request id и отвечает 500.
+// Подробности пользователю не показываем — только код запроса для поддержки.
+func (s *Server) serverError(w http.ResponseWriter, r *http.Request, err error) {
+	logx.Logger(r.Context()).Error("handler error", "path", r.URL.Path, "err", err)
+	l := s.locale(r)
+	msg := l.T("error.internal")
+	if id := logx.RequestID(r.Context()); id != "" {
+		msg = l.T("error.internal_id", id)
+	}
+	http.Error(w, msg, http.StatusInternalServerError)
+}
@@ -0,0 +1,91 @@
+package httpx
+
+import (
+	"context"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"learny/internal/i18n"
+	"learny/internal/ratelimit"
+	"learny/web"
+)
+
+// memStore — счётчики лимитов в памяти вместо таблицы rate_limits.
+type memStore struct {
+	mu sync.Mutex
+	n  map[string]int
+}
+
+func (m *memStore) RateHit(_ context.Context, key string, window time.Duration) (int, time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.n[key]++
+	return m.n[key], time.Now().Add(window), nil
+}
+
+func (m *memStore) RatePeek(_ context.Context, key string) (int, time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.n[key], time.Now().Add(time.Minute), nil
+}
+
+func (m *memStore) RateReset(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.n, key)
+	return nil
+}
+
+// Верный пароль не обнуляет счётчики: после пяти неверных кодов 2FA повторный
+// вход по паролю не открывает новую серию попыток.
+func TestLoginTwoFactorStaysLimited(t *testing.T) {
+	sub, err := fs.Sub(web.FS, "templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpl, err := LoadTemplates(sub, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &memStore{n: map[string]int{}}
+	s := &Server{Templates: tpl, Metrics: NewMetrics(nil), Limiter: ratelimit.New(store, ratelimit.DefaultRules())}
+
+	const ip, uid = "203.0.113.7", int64(42)
+	step := func(tpl string) bool {
+		r := httptest.NewRequest(http.MethodPost, "/login", nil)
+		return s.loginLimited(httptest.NewRecorder(), r, ip, tpl)
+	}
+
+	// пароль верный — handleLogin выдаёт challenge, счётчики не трогает
+	if step("login") {
+		t.Fatal("первый вход ограничен")
+	}
+	for i := 0; i < 5; i++ {
+		if step("login_2fa") {
+			t.Fatalf("код %d: ограничен раньше лимита", i+1)
+		}
+		if err := s.loginFailed(httptest.NewRequest(http.MethodPost, "/login/2fa", nil), ip, uid); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !step("login_2fa") {
+		t.Error("шестой код принят к проверке")
+	}
+
+	// пароль ещё раз — всё так же заблокировано
+	w := httptest.NewRecorder()
+	if !s.loginLimited(w, httptest.NewRequest(http.MethodPost, "/login", nil), ip, "login") {
+		t.Fatal("повторный вход по паролю не ограничен")
+	}
+	if want := i18n.Default("login.rate_limited", 1); w.Header().Get("Retry-After") == "" || !strings.Contains(w.Body.String(), want) {
+		t.Errorf("нет сообщения о блокировке: %q", w.Body.String())
+	}
+	if n := store.n["account:42"]; n != 5 {
+		t.Errorf("счётчик аккаунта = %d, want 5", n)
+	}
+}
@@ -0,0 +1,132 @@
+package httpx
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	a "learny/internal/auth"
+	"learny/internal/metrics"
+)
+
+// Metrics — всё, что отдаёт /metrics: HTTP по маршрутам, пул БД
+// и события квизов и входа.
+type Metrics struct {
+	reg *metrics.Registry
+
+	requests *metrics.CounterVec   // method, route, code
+	latency  *metrics.HistogramVec // method, route
+	inFlight atomic.Int64
+
+	AttemptsStarted  *metrics.Counter
+	AttemptsFinished *metrics.CounterVec // status: submitted | expired
+	AttemptsOvertime *metrics.CounterVec // late_policy
+	LoginFailures    *metrics.CounterVec // step: password | 2fa
+	RateLimitHits    *metrics.CounterVec // rule
+}
+
+// NewMetrics регистрирует метрики; db — для статистики пула (nil — без неё).
+func NewMetrics(db *sql.DB) *Metrics {
+	reg := metrics.NewRegistry()
+	m := &Metrics{
+		reg:      reg,
+		requests: reg.NewCounterVec("learny_http_requests_total", "HTTP-запросы по маршрутам.", "method", "route", "code"),
+		latency: reg.NewHistogramVec("learny_http_request_duration_seconds", "Время обработки HTTP-запроса.",
+			metrics.DefBuckets, "method", "route"),
+	}
+	reg.NewGaugeFunc("learny_http_requests_in_flight", "Запросы в обработке.", func() float64 {
+		return float64(m.inFlight.Load())
+	})
+
+	m.AttemptsStarted = reg.NewCounterVec("learny_attempts_started_total", "Начатые попытки.").With()
+	m.AttemptsFinished = reg.NewCounterVec("learny_attempts_finished_total", "Сданные попытки по итоговому статусу.", "status")
+	m.AttemptsOvertime = reg.NewCounterVec("learny_attempts_overtime_total", "Попытки, сданные после лимита времени.", "late_policy")
+	m.LoginFailures = reg.NewCounterVec("learny_login_failures_total", "Неудачные входы: пароль или код 2FA.", "step")
+	m.RateLimitHits = reg.NewCounterVec("learny_ratelimit_hits_total", "Запросы, отклонённые rate limit.", "rule")
+
+	if db != nil {
+		stats := func(f func(sql.DBStats) float64) func() float64 {
+			return func() float64 { return f(db.Stats()) }
+		}
+		reg.NewGaugeFunc("learny_db_max_open_connections", "Предел открытых соединений (0 — без предела).",
+			stats(func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }))
+		reg.NewGaugeFunc("learny_db_open_connections", "Открытые соединения.",
+			stats(func(s sql.DBStats) float64 { return float64(s.OpenConnections) }))
+		reg.NewGaugeFunc("learny_db_in_use_connections", "Соединения, занятые запросами.",
+			stats(func(s sql.DBStats) float64 { return float64(s.InUse) }))
+		reg.NewGaugeFunc("learny_db_idle_connections", "Свободные соединения.",
+			stats(func(s sql.DBStats) float64 { return float64(s.Idle) }))
+		reg.NewCounterFunc("learny_db_wait_count_total", "Сколько раз ждали свободное соединение.",
+			stats(func(s sql.DBStats) float64 { return float64(s.WaitCount) }))
+		reg.NewCounterFunc("learny_db_wait_duration_seconds_total", "Суммарное ожидание свободного соединения.",
+			stats(func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }))
+		reg.NewCounterFunc("learny_db_max_idle_closed_total", "Соединения, закрытые из-за max_idle_conns.",
+			stats(func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) }))
+		reg.NewCounterFunc("learny_db_max_lifetime_closed_total", "Соединения, закрытые из-за conn_max_lifetime.",
+			stats(func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) }))
+	}
+	return m
+}
+
+// Instrument считает запросы и время ответа по шаблону маршрута mux
+// ("/api/v1/quizzes/{id}"), а не по URL — иначе меток было бы без счёта.
+// Ставится снаружи всех остальных middleware.
+func Instrument(m *Metrics, mux *http.ServeMux) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := routeOf(mux, r)
+			m.inFlight.Add(1)
+			defer m.inFlight.Add(-1)
+			start := time.Now()
+			sw := &statusWriter{ResponseWriter: w, code: http.StatusOK}
+			next.ServeHTTP(sw, r)
+
+			m.requests.With(r.Method, route, strconv.Itoa(sw.code)).Inc()
+			m.latency.Observe(time.Since(start).Seconds(), r.Method, route)
+		})
+	}
+}
+
+// statusWriter запоминает код ответа и размер тела.
+type statusWriter struct {
+	http.ResponseWriter
+	code        int
+	bytes       int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.code, w.wroteHeader = code, true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
+}
+
+// Unwrap — для http.ResponseController (Flush, дедлайны).
+func (w *statusWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// handleMetrics — текстовый формат Prometheus. Если задан MetricsToken,
+// нужен заголовок Authorization: Bearer <token>.
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if s.MetricsToken != "" {
+		token, _ := a.BearerToken(r)
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.MetricsToken)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	s.Metrics.reg.Write(w)
+}
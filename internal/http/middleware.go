@@ -1,28 +1,60 @@
 package httpx
 
 import (
-	"context"
 	"net/http"
+	"strings"
 
 	a "learny/internal/auth"
+	"learny/internal/logx"
 	"learny/internal/repo"
 )
 
-type ctxKey int
-
-const (
-	ctxUserID ctxKey = iota + 1
-)
+// WithUser находит серверную сессию по cookie и кладёт её в контекст,
+// дальше её видят CurrentUserID/RequireAuth/RequireRole.
+// Запросы с Authorization: Bearer cookie не используют — их авторизует RequireScope.
+func WithUser(sm *a.Manager) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, bearer := a.BearerToken(r); bearer {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if sess, ok := sm.Resolve(w, r); ok {
+				logx.SetUserID(r.Context(), sess.UserID)
+				r = r.WithContext(a.WithSession(r.Context(), sess))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
 
-// WithUser — задел на будущее; сейчас просто прокидывает дальше.
-func WithUser(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if id, ok := a.CurrentUserID(r); ok {
-			ctx := context.WithValue(r.Context(), ctxUserID, id)
-			r = r.WithContext(ctx)
-		}
-		next.ServeHTTP(w, r)
-	})
+// CSRF выдаёт токен для форм и отклоняет изменяющие запросы без валидного токена.
+// Должен стоять внутри WithUser: токен берётся из сессии.
+// Запросы с Bearer-токеном не проверяются: браузер сам такой заголовок не подставит.
+func CSRF(sm *a.Manager) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, bearer := a.BearerToken(r); bearer {
+				next.ServeHTTP(w, r)
+				return
+			}
+			token, err := sm.CSRFToken(w, r)
+			if err != nil {
+				logError(r, "csrf token", err)
+				http.Error(w, "csrf error", http.StatusInternalServerError)
+				return
+			}
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+			default:
+				if !a.ValidCSRF(r, token) {
+					http.Error(w, "invalid CSRF token", http.StatusForbidden)
+					return
+				}
+			}
+			next.ServeHTTP(w, r.WithContext(a.WithCSRF(r.Context(), token)))
+		})
+	}
 }
 
 func RequireAuth(next http.Handler) http.Handler {
@@ -35,6 +67,41 @@ func RequireAuth(next http.Handler) http.Handler {
 	})
 }
 
+// authorizeBearer проверяет персональный API-токен и наличие права scope.
+// При отказе возвращает HTTP-статус и код ошибки для WWW-Authenticate.
+func authorizeBearer(rp *repo.Repo, r *http.Request, token, scope string) (*repo.APITokenRow, int, string) {
+	t, err := rp.UseAPIToken(r.Context(), a.HashToken(token))
+	if err != nil {
+		return nil, http.StatusUnauthorized, "invalid_token"
+	}
+	if !t.HasScope(scope) {
+		return nil, http.StatusForbidden, "insufficient_scope"
+	}
+	return t, 0, ""
+}
+
+// RequireScope авторизует запросы с Authorization: Bearer по персональному
+// API-токену, у которого есть нужное право. Запросы без заголовка проходят
+// дальше как обычные — их проверяют RequireAuth/RequireRole.
+func RequireScope(repo *repo.Repo, scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := a.BearerToken(r)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			t, status, code := authorizeBearer(repo, r, token, scope)
+			if status != 0 {
+				w.Header().Set("WWW-Authenticate", `Bearer error="`+code+`", scope="`+scope+`"`)
+				http.Error(w, strings.ReplaceAll(code, "_", " "), status)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(a.WithAPIToken(r.Context(), t)))
+		})
+	}
+}
+
 func RequireRole(repo *repo.Repo, roles ...string) func(http.Handler) http.Handler {
 	allowed := map[string]struct{}{}
 	for _, r := range roles {
@@ -60,3 +127,34 @@ func RequireRole(repo *repo.Repo, roles ...string) func(http.Handler) http.Handl
 		})
 	}
 }
+
+// RequireTwoFactor не пускает пользователей с перечисленными ролями дальше
+// настройки 2FA, пока они её не включат. Без ролей ничего не делает.
+func RequireTwoFactor(repo *repo.Repo, roles ...string) func(http.Handler) http.Handler {
+	required := map[string]struct{}{}
+	for _, r := range roles {
+		required[r] = struct{}{}
+	}
+	return func(next http.Handler) http.Handler {
+		if len(required) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			uid, ok := a.CurrentUserID(r)
+			if !ok || r.URL.Path == "/settings/2fa" || r.URL.Path == "/logout" || strings.HasPrefix(r.URL.Path, "/static/") {
+				next.ServeHTTP(w, r)
+				return
+			}
+			u, err := repo.GetUser(r.Context(), uid)
+			if err != nil {
+				http.Error(w, "user error", http.StatusForbidden)
+				return
+			}
+			if _, need := required[u.Role]; need && !u.TwoFactor() {
+				http.Redirect(w, r, "/settings/2fa", http.StatusSeeOther)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
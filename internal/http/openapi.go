@@ -0,0 +1,578 @@
+package httpx
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	a "learny/internal/auth"
+	"learny/internal/repo"
+)
+
+/*** OpenAPI-описание всех маршрутов: /api/openapi.json ***/
+
+// routeMux — ServeMux, который запоминает зарегистрированные шаблоны,
+// чтобы тест мог сверить их с описанием в routeDocs.
+type routeMux struct {
+	*http.ServeMux
+	patterns []string
+}
+
+func (m *routeMux) Handle(pattern string, h http.Handler) {
+	m.patterns = append(m.patterns, pattern)
+	m.ServeMux.Handle(pattern, h)
+}
+
+func (m *routeMux) HandleFunc(pattern string, h func(http.ResponseWriter, *http.Request)) {
+	m.Handle(pattern, http.HandlerFunc(h))
+}
+
+// HandleMethods регистрирует h на path для каждого метода из methods ("GET POST"):
+// остальные методы получают 405 от самого mux.
+func (m *routeMux) HandleMethods(methods, path string, h http.Handler) {
+	for _, method := range strings.Fields(methods) {
+		m.Handle(method+" "+path, h)
+	}
+}
+
+// param — параметр запроса, пути или поле формы.
+type param struct {
+	Name     string
+	Type     string // string | integer | boolean | file
+	Desc     string
+	Required bool
+}
+
+func opt(name, typ, desc string) param { return param{name, typ, desc, false} }
+func req(name, typ, desc string) param { return param{name, typ, desc, true} }
+
+// operation — один метод маршрута. Body и Result — нулевые значения
+// типов JSON-тела и ответа, схемы для них строятся по структурам.
+type operation struct {
+	Method  string
+	Path    string // как в OpenAPI: /api/v1/courses/{id}
+	Tag     string
+	Summary string
+
+	Access string // "" — всем, "auth" — после входа, иначе роли через запятую
+	Scope  string // право Bearer-токена, с которым маршрут доступен без cookie
+
+	Query     []param
+	Form      []param // тело формы; для не-GET сюда добавляется csrf_token
+	Multipart bool
+
+	Body     any
+	Result   any
+	Status   int    // успешный статус JSON-ответа, 0 — 200
+	Produces string // тип не-JSON ответа, по умолчанию text/html
+	Redirect bool   // успешный POST отвечает 303 See Other
+}
+
+// notDocumented — маршруты, которые сознательно не описываются.
+var notDocumented = map[string]bool{
+	"/api/": true, // JSON 404 для несуществующих методов API
+}
+
+const staffRoles = "teacher,admin"
+
+var idParam = req("id", "integer", "идентификатор")
+
+// routeDocs — описание всех маршрутов Server.Routes. Новый маршрут без
+// описания здесь уронит TestRoutesDocumented (см. checkDocumented).
+func routeDocs() []operation {
+	csvParams := []param{opt("course_id", "integer", "курс"), opt("quiz_id", "integer", "квиз")}
+	page := []param{opt("limit", "integer", "размер страницы, 1–200 (по умолчанию 50)"), opt("offset", "integer", "смещение")}
+	auditParams := []param{
+		opt("actor", "string", "подстрока email автора"),
+		opt("entity", "string", "user | course | quiz | question | attempt"),
+		opt("from", "string", "с даты, ГГГГ-ММ-ДД"),
+		opt("to", "string", "по дату включительно, ГГГГ-ММ-ДД"),
+		opt("page", "integer", "страница, по 100 событий"),
+	}
+
+	return []operation{
+		/* ---------- вход и регистрация ---------- */
+		{Method: "GET", Path: "/", Tag: "auth", Summary: "Главная; после входа — редирект на /courses"},
+		{Method: "GET", Path: "/register", Tag: "auth", Summary: "Форма регистрации"},
+		{Method: "POST", Path: "/register", Tag: "auth", Summary: "Регистрация", Redirect: true,
+			Form: []param{req("email", "string", ""), req("password", "string", "не короче 8 символов")}},
+		{Method: "GET", Path: "/login", Tag: "auth", Summary: "Форма входа"},
+		{Method: "POST", Path: "/login", Tag: "auth", Summary: "Вход по паролю; при включённой 2FA — переход на /login/2fa", Redirect: true,
+			Form: []param{req("email", "string", ""), req("password", "string", "")}},
+		{Method: "GET", Path: "/login/2fa", Tag: "auth", Summary: "Форма второго шага входа"},
+		{Method: "POST", Path: "/login/2fa", Tag: "auth", Summary: "Второй шаг входа: код TOTP или код восстановления", Redirect: true,
+			Form: []param{req("code", "string", "6 цифр из приложения или код восстановления")}},
+		{Method: "POST", Path: "/logout", Tag: "auth", Summary: "Выход", Redirect: true},
+		{Method: "GET", Path: "/forgot", Tag: "auth", Summary: "Форма восстановления пароля"},
+		{Method: "POST", Path: "/forgot", Tag: "auth", Summary: "Отправить ссылку для сброса пароля",
+			Form: []param{req("email", "string", "")}},
+		{Method: "GET", Path: "/reset", Tag: "auth", Summary: "Форма нового пароля по ссылке из письма",
+			Query: []param{req("token", "string", "токен из письма")}},
+		{Method: "POST", Path: "/reset", Tag: "auth", Summary: "Установить новый пароль", Redirect: true,
+			Form: []param{req("token", "string", "токен из письма"), req("new", "string", "новый пароль"), req("new2", "string", "повтор")}},
+		{Method: "GET", Path: "/verify", Tag: "auth", Summary: "Подтверждение email по ссылке из письма",
+			Query: []param{req("token", "string", "токен из письма")}},
+		{Method: "POST", Path: "/verify/resend", Tag: "auth", Access: "auth", Summary: "Выслать письмо подтверждения ещё раз", Redirect: true},
+
+		/* ---------- настройки ---------- */
+		{Method: "GET", Path: "/settings/password", Tag: "settings", Access: "auth", Summary: "Форма смены пароля"},
+		{Method: "POST", Path: "/settings/password", Tag: "settings", Access: "auth", Summary: "Сменить пароль",
+			Form: []param{req("current", "string", "текущий пароль"), req("new", "string", "новый пароль"), req("new2", "string", "повтор")}},
+		{Method: "GET", Path: "/settings/sessions", Tag: "settings", Access: "auth", Summary: "Активные сессии"},
+		{Method: "POST", Path: "/settings/sessions", Tag: "settings", Access: "auth", Summary: "Завершить сессию или все остальные", Redirect: true,
+			Form: []param{req("action", "string", "revoke | revoke_all"), opt("session_id", "integer", "для revoke")}},
+		{Method: "GET", Path: "/settings/2fa", Tag: "settings", Access: "auth", Summary: "Состояние двухфакторной аутентификации"},
+		{Method: "POST", Path: "/settings/2fa", Tag: "settings", Access: "auth", Summary: "Подключение, подтверждение, новые коды восстановления, отключение 2FA",
+			Form: []param{req("action", "string", "begin | confirm | recovery | disable"), opt("code", "string", "код TOTP (кроме begin)")}},
+		{Method: "GET", Path: "/settings/tokens", Tag: "settings", Access: "auth", Summary: "Персональные API-токены"},
+		{Method: "POST", Path: "/settings/tokens", Tag: "settings", Access: "auth", Summary: "Выпустить или отозвать API-токен",
+			Form: []param{
+				req("action", "string", "create | revoke"),
+				opt("name", "string", "название (create)"),
+				opt("scope", "string", "право токена (create), поле повторяется: "+scopeNames()),
+				opt("expires_days", "integer", "срок жизни в днях (create), пусто — бессрочно"),
+				opt("token_id", "integer", "для revoke"),
+			}},
+		{Method: "POST", Path: "/lang", Tag: "settings", Summary: "Язык интерфейса; у залогиненного сохраняется в профиле", Redirect: true,
+			Form: []param{req("lang", "string", "ru | en; пусто — по Accept-Language"), opt("back", "string", "куда вернуться")}},
+
+		/* ---------- обучение ---------- */
+		{Method: "GET", Path: "/courses", Tag: "learning", Access: "auth", Summary: "Курсы и квизы"},
+		{Method: "POST", Path: "/quiz/start", Tag: "learning", Access: "auth", Summary: "Начать попытку (незавершённая по этому квизу становится брошенной)", Redirect: true,
+			Form: []param{req("quiz_id", "integer", "квиз"), opt("course_id", "integer", "курс; если указан, должен совпадать с курсом квиза")}},
+		{Method: "GET", Path: "/quiz/attempt", Tag: "learning", Access: "auth", Summary: "Своя попытка: вопросы идущей или итог завершённой",
+			Query: []param{req("id", "integer", "попытка")}},
+		{Method: "POST", Path: "/quiz/finish", Tag: "learning", Access: "auth", Summary: "Сдать попытку",
+			Form: []param{
+				req("attempt_id", "integer", "попытка"),
+				opt("q_{id}", "string", "ответ на вопрос id; для multiple поле повторяется"),
+			}},
+		{Method: "GET", Path: "/topics", Tag: "learning", Access: "auth", Summary: "Статистика по темам"},
+		{Method: "GET", Path: "/topic", Tag: "learning", Access: "auth", Summary: "Профиль темы",
+			Query: []param{req("name", "string", "тема")}},
+
+		/* ---------- админка ---------- */
+		{Method: "GET", Path: "/admin/questions", Tag: "admin", Access: staffRoles, Summary: "Банк вопросов",
+			Query: []param{opt("course_id", "integer", "курс"), opt("topic", "string", "тема"), opt("qtype", "string", "тип"), opt("limit", "integer", "сколько показать (по умолчанию 100)")}},
+		{Method: "GET", Path: "/admin/questions/edit", Tag: "admin", Access: staffRoles, Scope: a.ScopeQuestionsWrite, Summary: "Форма редактирования вопроса",
+			Query: []param{idParam}},
+		{Method: "POST", Path: "/admin/questions/edit", Tag: "admin", Access: staffRoles, Scope: a.ScopeQuestionsWrite, Summary: "Сохранить вопрос", Redirect: true,
+			Form: []param{idParam, req("topic", "string", ""), req("qtype", "string", "single | multiple | numeric | text"), req("difficulty", "integer", "1–5"), req("payload", "string", "JSON вопроса")}},
+		{Method: "GET", Path: "/admin/questions/upload", Tag: "admin", Access: staffRoles, Scope: a.ScopeQuestionsWrite, Summary: "Форма импорта CSV"},
+		{Method: "POST", Path: "/admin/questions/upload", Tag: "admin", Access: staffRoles, Scope: a.ScopeQuestionsWrite, Summary: "Импорт вопросов из CSV (разделитель ;)", Multipart: true,
+			Form: []param{req("course_id", "integer", "курс"), req("file", "file", "CSV-файл")}},
+		{Method: "GET", Path: "/admin/questions/import-json", Tag: "admin", Access: staffRoles, Scope: a.ScopeQuestionsWrite, Summary: "Форма импорта JSON"},
+		{Method: "POST", Path: "/admin/questions/import-json", Tag: "admin", Access: staffRoles, Scope: a.ScopeQuestionsWrite, Summary: "Импорт вопросов из JSON: файлом или текстом", Multipart: true,
+			Form: []param{req("course_id", "integer", "курс"), opt("file", "file", "JSON-файл"), opt("json", "string", "JSON текстом, если файла нет")}},
+		{Method: "GET", Path: "/admin/users", Tag: "admin", Access: "admin", Summary: "Пользователи"},
+		{Method: "POST", Path: "/admin/users", Tag: "admin", Access: "admin", Summary: "Сменить роль или выполнить действие над пользователем", Redirect: true,
+			Form: []param{
+				req("user_id", "integer", "пользователь"),
+				opt("action", "string", "kill_sessions | unlock | reset_2fa | force_verify | resend_verification; пусто — смена роли"),
+				opt("role", "string", "student | teacher | admin (без action)"),
+			}},
+		{Method: "GET", Path: "/admin/courses", Tag: "admin", Access: staffRoles, Scope: a.ScopeCoursesWrite, Summary: "Курсы"},
+		{Method: "POST", Path: "/admin/courses", Tag: "admin", Access: staffRoles, Scope: a.ScopeCoursesWrite, Summary: "Создать, изменить или удалить курс", Redirect: true,
+			Form: []param{req("action", "string", "create | update | delete"), opt("id", "integer", "для update и delete"), opt("title", "string", ""), opt("description", "string", "")}},
+		{Method: "GET", Path: "/admin/quizzes", Tag: "admin", Access: staffRoles, Scope: a.ScopeQuizzesWrite, Summary: "Квизы курса",
+			Query: []param{opt("course_id", "integer", "курс")}},
+		{Method: "POST", Path: "/admin/quizzes", Tag: "admin", Access: staffRoles, Scope: a.ScopeQuizzesWrite, Summary: "Создать или удалить квиз", Redirect: true,
+			Form: []param{
+				req("action", "string", "create | delete"),
+				req("course_id", "integer", "курс"),
+				opt("title", "string", "для create"),
+				opt("rules_json", "string", "правила квиза (схема QuizRules), для create"),
+				opt("quiz_id", "integer", "для delete"),
+			}},
+		{Method: "GET", Path: "/admin/results", Tag: "admin", Access: staffRoles, Scope: a.ScopeResultsRead, Summary: "Результаты попыток",
+			Query: []param{opt("course_id", "integer", "курс")}},
+		{Method: "GET", Path: "/admin/results/export", Tag: "admin", Access: staffRoles, Scope: a.ScopeResultsRead, Summary: "Выгрузка результатов в CSV",
+			Query: csvParams, Produces: "text/csv"},
+		{Method: "GET", Path: "/admin/attempt", Tag: "admin", Access: staffRoles, Summary: "Разбор попытки",
+			Query: []param{idParam}},
+		{Method: "POST", Path: "/admin/attempt", Tag: "admin", Access: staffRoles, Summary: "Аннулировать попытку", Redirect: true,
+			Form: []param{idParam, req("action", "string", "void")}},
+		{Method: "GET", Path: "/admin/logs", Tag: "admin", Access: staffRoles, Summary: "Журнал попыток пользователя",
+			Query: []param{opt("user_id", "integer", "пусто — выбор пользователя")}},
+		{Method: "GET", Path: "/admin/audit", Tag: "admin", Access: "admin", Summary: "Журнал административных действий",
+			Query: auditParams},
+		{Method: "GET", Path: "/admin/audit/export", Tag: "admin", Access: "admin", Summary: "Выгрузка журнала действий в CSV",
+			Query: auditParams[:4], Produces: "text/csv"},
+
+		/* ---------- JSON API ---------- */
+		{Method: "GET", Path: "/api/openapi.json", Tag: "api", Summary: "Это описание", Produces: "application/json"},
+
+		{Method: "GET", Path: "/api/v1/courses", Tag: "api", Access: "auth", Scope: a.ScopeCoursesRead, Summary: "Список курсов",
+			Query: page, Result: listPage[courseJSON]{}},
+		{Method: "POST", Path: "/api/v1/courses", Tag: "api", Access: staffRoles, Scope: a.ScopeCoursesWrite, Summary: "Создать курс (title обязателен)",
+			Body: courseInput{}, Result: courseJSON{}, Status: http.StatusCreated},
+		{Method: "GET", Path: "/api/v1/courses/{id}", Tag: "api", Access: "auth", Scope: a.ScopeCoursesRead, Summary: "Курс",
+			Result: courseJSON{}},
+		{Method: "PATCH", Path: "/api/v1/courses/{id}", Tag: "api", Access: staffRoles, Scope: a.ScopeCoursesWrite, Summary: "Изменить курс; непереданные поля не меняются",
+			Body: courseInput{}, Result: courseJSON{}},
+		{Method: "DELETE", Path: "/api/v1/courses/{id}", Tag: "api", Access: staffRoles, Scope: a.ScopeCoursesWrite, Summary: "Удалить курс",
+			Status: http.StatusNoContent},
+
+		{Method: "GET", Path: "/api/v1/courses/{id}/quizzes", Tag: "api", Access: "auth", Scope: a.ScopeQuizzesRead, Summary: "Квизы курса",
+			Query: page, Result: listPage[quizJSON]{}},
+		{Method: "POST", Path: "/api/v1/courses/{id}/quizzes", Tag: "api", Access: staffRoles, Scope: a.ScopeQuizzesWrite, Summary: "Создать квиз (title и rules обязательны)",
+			Body: quizInput{}, Result: quizJSON{}, Status: http.StatusCreated},
+		{Method: "GET", Path: "/api/v1/quizzes/{id}", Tag: "api", Access: "auth", Scope: a.ScopeQuizzesRead, Summary: "Квиз",
+			Result: quizJSON{}},
+		{Method: "PATCH", Path: "/api/v1/quizzes/{id}", Tag: "api", Access: staffRoles, Scope: a.ScopeQuizzesWrite, Summary: "Изменить квиз; непереданные поля не меняются",
+			Body: quizInput{}, Result: quizJSON{}},
+		{Method: "DELETE", Path: "/api/v1/quizzes/{id}", Tag: "api", Access: staffRoles, Scope: a.ScopeQuizzesWrite, Summary: "Удалить квиз",
+			Status: http.StatusNoContent},
+
+		{Method: "GET", Path: "/api/v1/courses/{id}/questions", Tag: "api", Access: staffRoles, Scope: a.ScopeQuestionsRead, Summary: "Вопросы курса вместе с ответами",
+			Query: append([]param{opt("topic", "string", "тема"), opt("qtype", "string", "тип")}, page...), Result: listPage[questionJSON]{}},
+		{Method: "POST", Path: "/api/v1/courses/{id}/questions", Tag: "api", Access: staffRoles, Scope: a.ScopeQuestionsWrite, Summary: "Создать вопрос",
+			Body: questionInput{}, Result: questionJSON{}, Status: http.StatusCreated},
+		{Method: "GET", Path: "/api/v1/questions/{id}", Tag: "api", Access: staffRoles, Scope: a.ScopeQuestionsRead, Summary: "Вопрос",
+			Result: questionJSON{}},
+		{Method: "PATCH", Path: "/api/v1/questions/{id}", Tag: "api", Access: staffRoles, Scope: a.ScopeQuestionsWrite, Summary: "Изменить вопрос; непереданные поля не меняются",
+			Body: questionInput{}, Result: questionJSON{}},
+		{Method: "DELETE", Path: "/api/v1/questions/{id}", Tag: "api", Access: staffRoles, Scope: a.ScopeQuestionsWrite, Summary: "Удалить вопрос",
+			Status: http.StatusNoContent},
+
+		{Method: "POST", Path: "/api/v1/quizzes/{id}/attempts", Tag: "api", Access: "auth", Scope: a.ScopeAttemptsWrite, Summary: "Начать попытку: вопросы без правильных ответов",
+			Result: startedAttemptJSON{}, Status: http.StatusCreated},
+		{Method: "GET", Path: "/api/v1/attempts/{id}", Tag: "api", Access: "auth", Scope: a.ScopeAttemptsRead, Summary: "Своя попытка",
+			Result: attemptJSON{}},
+		{Method: "POST", Path: "/api/v1/attempts/{id}/submit", Tag: "api", Access: "auth", Scope: a.ScopeAttemptsWrite, Summary: "Сдать попытку",
+			Body: submitInput{}, Result: attemptJSON{}},
+
+		{Method: "GET", Path: "/api/v1/results", Tag: "api", Access: staffRoles, Scope: a.ScopeResultsRead, Summary: "Результаты попыток",
+			Query: append(csvParams, page...), Result: listPage[resultJSON]{}},
+
+		/* ---------- служебные ---------- */
+		{Method: "GET", Path: "/healthz", Tag: "ops", Summary: "Процесс жив (БД не проверяется)", Produces: "text/plain"},
+		{Method: "GET", Path: "/readyz", Tag: "ops", Summary: "Готов к трафику: БД отвечает, миграции применены; иначе 503 с причиной", Produces: "text/plain"},
+		{Method: "GET", Path: "/metrics", Tag: "ops", Summary: "Метрики в формате Prometheus; при заданном METRICS_TOKEN — Authorization: Bearer", Produces: "text/plain"},
+	}
+}
+
+func scopeNames() string {
+	names := make([]string, 0, len(a.Scopes))
+	for _, sc := range a.Scopes {
+		names = append(names, sc.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// checkDocumented сверяет зарегистрированные шаблоны с описанием:
+// и маршрут без описания, и описание несуществующего маршрута — ошибка.
+// Шаблон без метода ("/login") не принимается: по нему не видно, какие
+// методы обрабатывает handler, и недокументированный POST прошёл бы незамеченным.
+func checkDocumented(patterns []string, ops []operation) error {
+	var problems []string
+	covered := make([]bool, len(ops))
+	for _, p := range patterns {
+		if notDocumented[p] {
+			continue
+		}
+		method, path, ok := strings.Cut(p, " ")
+		if !ok {
+			problems = append(problems, "маршрут "+p+" зарегистрирован без метода")
+			continue
+		}
+		path = strings.TrimSuffix(path, "{$}") // "/{$}" — ровно "/", в описании просто "/"
+		found := false
+		for i, op := range ops {
+			if op.Path == path && op.Method == method {
+				covered[i], found = true, true
+			}
+		}
+		if !found {
+			problems = append(problems, "маршрут "+p+" не описан в routeDocs")
+		}
+	}
+	for i, op := range ops {
+		if !covered[i] {
+			problems = append(problems, "в routeDocs описан несуществующий маршрут "+op.Method+" "+op.Path)
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("openapi: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_, _ = w.Write(s.openapi)
+}
+
+/* ---------- сборка документа ---------- */
+
+// schemaNames — типы, которые выносятся в components/schemas.
+var schemaNames = map[reflect.Type]string{
+	reflect.TypeOf(apiError{}):           "Error",
+	reflect.TypeOf(courseJSON{}):         "Course",
+	reflect.TypeOf(courseInput{}):        "CourseInput",
+	reflect.TypeOf(quizJSON{}):           "Quiz",
+	reflect.TypeOf(quizInput{}):          "QuizInput",
+	reflect.TypeOf(repo.QuizRules{}):     "QuizRules",
+	reflect.TypeOf(questionJSON{}):       "Question",
+	reflect.TypeOf(questionInput{}):      "QuestionInput",
+	reflect.TypeOf(issuedQuestionJSON{}): "IssuedQuestion",
+	reflect.TypeOf(startedAttemptJSON{}): "StartedAttempt",
+	reflect.TypeOf(attemptJSON{}):        "Attempt",
+	reflect.TypeOf(submitInput{}):        "SubmitInput",
+	reflect.TypeOf(resultJSON{}):         "Result",
+}
+
+var (
+	timeType        = reflect.TypeOf(time.Time{})
+	rawType         = reflect.TypeOf(json.RawMessage{})
+	topicQuotasType = reflect.TypeOf(repo.TopicQuotas{})
+)
+
+type schemaSet map[string]any
+
+func (set schemaSet) ref(name string) map[string]any {
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+// of строит JSON-схему типа по полям и json-тегам.
+func (set schemaSet) of(t reflect.Type) map[string]any {
+	if name, ok := schemaNames[t]; ok {
+		if _, done := set[name]; !done {
+			set[name] = nil // от рекурсии
+			set[name] = set.build(t)
+		}
+		return set.ref(name)
+	}
+	return set.build(t)
+}
+
+func (set schemaSet) build(t reflect.Type) map[string]any {
+	switch t {
+	case timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case rawType:
+		return map[string]any{"description": "произвольный JSON"}
+	case topicQuotasType:
+		return map[string]any{"oneOf": []any{
+			map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+			map[string]any{"type": "object", "additionalProperties": map[string]any{"type": "integer"}},
+		}}
+	}
+	if t == reflect.TypeOf(apiError{}) {
+		// в ответе ошибка завёрнута: {"error": {"code", "message"}}
+		return map[string]any{
+			"type":     "object",
+			"required": []string{"error"},
+			"properties": map[string]any{"error": map[string]any{
+				"type":     "object",
+				"required": []string{"code", "message"},
+				"properties": map[string]any{
+					"code":    map[string]any{"type": "string"},
+					"message": map[string]any{"type": "string"},
+				},
+			}},
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return set.of(t.Elem())
+	case reflect.Struct:
+		props := map[string]any{}
+		var required []string
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			if ref := f.Tag.Get("openapi"); ref != "" {
+				props[name] = set.ref(ref)
+			} else {
+				props[name] = set.of(f.Type)
+			}
+			if f.Type.Kind() != reflect.Pointer && f.Type != rawType && !strings.Contains(opts, "omitempty") {
+				required = append(required, name)
+			}
+		}
+		s := map[string]any{"type": "object", "properties": props}
+		if len(required) > 0 {
+			s["required"] = required
+		}
+		return s
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": set.of(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": set.of(t.Elem())}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	}
+	return map[string]any{}
+}
+
+func paramSchema(p param) map[string]any {
+	if p.Type == "file" {
+		return map[string]any{"type": "string", "format": "binary"}
+	}
+	return map[string]any{"type": p.Type}
+}
+
+// buildOpenAPI собирает документ OpenAPI 3.1 по описанию маршрутов.
+func buildOpenAPI(ops []operation) ([]byte, error) {
+	set := schemaSet{}
+	set.of(reflect.TypeOf(repo.QuizRules{})) // на неё ссылаются теги openapi:"QuizRules"
+	errResp := map[string]any{
+		"description": "ошибка",
+		"content":     map[string]any{"application/json": map[string]any{"schema": set.of(reflect.TypeOf(apiError{}))}},
+	}
+
+	paths := map[string]map[string]any{}
+	for _, op := range ops {
+		o := map[string]any{
+			"summary":     op.Summary,
+			"tags":        []string{op.Tag},
+			"operationId": strings.ToLower(op.Method) + operationName(op.Path),
+		}
+
+		var params []any
+		if strings.Contains(op.Path, "{id}") {
+			params = append(params, map[string]any{"name": "id", "in": "path", "required": true, "schema": map[string]any{"type": "integer"}})
+		}
+		for _, p := range op.Query {
+			params = append(params, map[string]any{"name": p.Name, "in": "query", "required": p.Required, "description": p.Desc, "schema": paramSchema(p)})
+		}
+		if len(params) > 0 {
+			o["parameters"] = params
+		}
+
+		html := !strings.HasPrefix(op.Path, "/api/")
+		form := op.Form
+		if html && op.Method != "GET" {
+			form = append([]param{req(a.CSRFField, "string", "CSRF-токен из формы (или заголовок "+a.CSRFHeader+")")}, form...)
+		}
+		switch {
+		case op.Body != nil:
+			o["requestBody"] = map[string]any{
+				"required": true,
+				"content":  map[string]any{"application/json": map[string]any{"schema": set.of(reflect.TypeOf(op.Body))}},
+			}
+		case len(form) > 0:
+			props := map[string]any{}
+			var required []string
+			for _, p := range form {
+				ps := paramSchema(p)
+				if p.Desc != "" {
+					ps["description"] = p.Desc
+				}
+				props[p.Name] = ps
+				if p.Required {
+					required = append(required, p.Name)
+				}
+			}
+			ctype := "application/x-www-form-urlencoded"
+			if op.Multipart {
+				ctype = "multipart/form-data"
+			}
+			schema := map[string]any{"type": "object", "properties": props}
+			if len(required) > 0 {
+				schema["required"] = required
+			}
+			o["requestBody"] = map[string]any{
+				"required": true,
+				"content":  map[string]any{ctype: map[string]any{"schema": schema}},
+			}
+		}
+
+		responses := map[string]any{}
+		switch {
+		case op.Result != nil:
+			status := op.Status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			responses[strconv.Itoa(status)] = map[string]any{
+				"description": http.StatusText(status),
+				"content":     map[string]any{"application/json": map[string]any{"schema": set.of(reflect.TypeOf(op.Result))}},
+			}
+		case op.Status != 0:
+			responses[strconv.Itoa(op.Status)] = map[string]any{"description": http.StatusText(op.Status)}
+		case op.Redirect:
+			responses["303"] = map[string]any{"description": "успех, редирект на страницу"}
+			responses["200"] = map[string]any{"description": "страница с ошибкой в форме", "content": map[string]any{"text/html": map[string]any{}}}
+		default:
+			ctype := op.Produces
+			if ctype == "" {
+				ctype = "text/html"
+			}
+			responses["200"] = map[string]any{"description": "OK", "content": map[string]any{ctype: map[string]any{}}}
+		}
+		if !html {
+			responses["default"] = errResp
+		}
+		o["responses"] = responses
+
+		if op.Access != "" {
+			sec := []any{map[string]any{"cookieAuth": []string{}}}
+			if op.Scope != "" {
+				sec = append(sec, map[string]any{"bearerAuth": []string{op.Scope}})
+			}
+			o["security"] = sec
+			if op.Access != "auth" {
+				o["x-roles"] = strings.Split(op.Access, ",")
+			}
+		}
+
+		if paths[op.Path] == nil {
+			paths[op.Path] = map[string]any{}
+		}
+		paths[op.Path][strings.ToLower(op.Method)] = o
+	}
+
+	doc := map[string]any{
+		"openapi": "3.1.0",
+		"info": map[string]any{
+			"title":   "Learny",
+			"version": "1",
+			"description": "HTML-формы и JSON API /api/v1. Формы требуют cookie-сессии и CSRF-токена; " +
+				"JSON API принимает также персональный токен (Authorization: Bearer) с нужным правом.",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": map[string]any(set),
+			"securitySchemes": map[string]any{
+				"cookieAuth": map[string]any{"type": "apiKey", "in": "cookie", "name": "sid"},
+				"bearerAuth": map[string]any{"type": "http", "scheme": "bearer", "description": "персональный токен " + a.APITokenPrefix + "…, см. /settings/tokens"},
+			},
+		},
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// operationName: /api/v1/courses/{id}/quizzes → ApiV1CoursesIdQuizzes.
+func operationName(path string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '{' || r == '}' || r == '-' || r == '.' }) {
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	if b.Len() == 0 {
+		return "Index"
+	}
+	return b.String()
+}
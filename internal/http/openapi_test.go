@@ -0,0 +1,42 @@
+package httpx
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRoutesDocumented(t *testing.T) {
+	s := &Server{}
+	s.Routes(http.NewServeMux())
+	if err := checkDocumented(s.patterns, routeDocs()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckDocumented(t *testing.T) {
+	ops := []operation{
+		{Method: "GET", Path: "/"},
+		{Method: "GET", Path: "/login"},
+		{Method: "POST", Path: "/login"},
+	}
+	tests := []struct {
+		name     string
+		patterns []string
+		want     string // подстрока ошибки; пусто — ошибки нет
+	}{
+		{"всё описано", []string{"GET /{$}", "GET /login", "POST /login", "/api/"}, ""},
+		{"недокументированный метод", []string{"GET /{$}", "GET /login", "POST /login", "DELETE /login"}, "DELETE /login не описан"},
+		{"шаблон без метода", []string{"GET /{$}", "/login"}, "/login зарегистрирован без метода"},
+		{"описан несуществующий", []string{"GET /{$}", "GET /login"}, "несуществующий маршрут POST /login"},
+	}
+	for _, tt := range tests {
+		err := checkDocumented(tt.patterns, ops)
+		switch {
+		case tt.want == "" && err != nil:
+			t.Errorf("%s: %v", tt.name, err)
+		case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+			t.Errorf("%s: err = %v, want …%s…", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package httpx
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	a "learny/internal/auth"
+	"learny/internal/i18n"
+)
+
+// baseTemplate — общий каркас; каждая страница подключает его через
+// {{ template "base.tmpl.html" . }} и задаёт блоки title и content.
+const baseTemplate = "base.tmpl.html"
+
+// Templates — страницы, разобранные при старте: у каждой свой набор
+// base + страница, иначе define "content" разных страниц конфликтовали бы.
+type Templates struct {
+	fsys   fs.FS
+	reload bool
+	pages  map[string]*template.Template // "login" → base + login.tmpl.html
+}
+
+// LoadTemplates разбирает все *.tmpl.html из fsys; ошибка в любом шаблоне
+// или ключ сообщения, которого нет в каталоге, не даст серверу стартовать.
+// reload — разбирать страницу заново при каждом показе (разработка: правки
+// видны без перезапуска).
+func LoadTemplates(fsys fs.FS, reload bool) (*Templates, error) {
+	files, err := fs.Glob(fsys, "*.tmpl.html")
+	if err != nil {
+		return nil, err
+	}
+	t := &Templates{fsys: fsys, reload: reload, pages: map[string]*template.Template{}}
+	for _, f := range files {
+		if f == baseTemplate {
+			continue
+		}
+		name := strings.TrimSuffix(f, ".tmpl.html")
+		if t.pages[name], err = parsePage(fsys, name); err != nil {
+			return nil, err
+		}
+	}
+	if len(t.pages) == 0 {
+		return nil, fmt.Errorf("шаблоны страниц не найдены")
+	}
+	if err := checkMessageKeys(fsys, files); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// messageKeyRe — ключ в {{ t $.L "key" }}.
+var messageKeyRe = regexp.MustCompile(`\bt \$?\.L "([^"]+)"`)
+
+// checkMessageKeys — все ключи, которые шаблоны передают в t, есть в каталоге:
+// опечатка в ключе видна при старте, а не на странице.
+func checkMessageKeys(fsys fs.FS, files []string) error {
+	var missing []string
+	for _, f := range files {
+		src, err := fs.ReadFile(fsys, f)
+		if err != nil {
+			return err
+		}
+		for _, m := range messageKeyRe.FindAllSubmatch(src, -1) {
+			if key := string(m[1]); !i18n.Has(key) {
+				missing = append(missing, f+": "+key)
+			}
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("ключей нет в каталоге сообщений:\n  %s", strings.Join(missing, "\n  "))
+	}
+	return nil
+}
+
+func parsePage(fsys fs.FS, name string) (*template.Template, error) {
+	t, err := template.New(name).Funcs(templateFuncs).ParseFS(fsys, baseTemplate, name+".tmpl.html")
+	if err != nil {
+		return nil, fmt.Errorf("template parse error: %w", err)
+	}
+	return t, nil
+}
+
+// Execute выполняет страницу name целиком в буфер и только потом пишет в w:
+// ошибка посреди шаблона не оставит клиенту полстраницы.
+func (t *Templates) Execute(w io.Writer, name string, data any) error {
+	page, ok := t.pages[name]
+	if t.reload {
+		// заново с диска: подхватываются и правки, и новые страницы
+		var err error
+		if page, err = parsePage(t.fsys, name); err != nil {
+			return err
+		}
+	} else if !ok {
+		return fmt.Errorf("template not found for %q", name)
+	}
+	var buf bytes.Buffer
+	if err := page.ExecuteTemplate(&buf, name+".tmpl.html", data); err != nil {
+		return fmt.Errorf("template exec error: %w", err)
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// tzCookie — часовой пояс браузера (IANA, "Europe/Moscow"), его ставит скрипт в base.
+const tzCookie = "tz"
+
+// langCookie — язык из переключателя внизу страницы; нужен гостю,
+// вошедшему язык запоминается ещё и в профиле.
+const langCookie = "lang"
+
+// zones — разобранные пояса из cookie; в кеш попадают только существующие,
+// поэтому он ограничен базой tzdata.
+var zones sync.Map // string → *time.Location
+
+// locale — язык (см. lang) и часовой пояс страницы: пояс из cookie tz, иначе пояс сервера.
+func (s *Server) locale(r *http.Request) *i18n.Locale {
+	var zone *time.Location
+	if c, err := r.Cookie(tzCookie); err == nil && c.Value != "" && len(c.Value) <= 64 {
+		if z, ok := zones.Load(c.Value); ok {
+			zone = z.(*time.Location)
+		} else if z, err := time.LoadLocation(c.Value); err == nil {
+			zones.Store(c.Value, z)
+			zone = z
+		}
+	}
+	return i18n.New(s.lang(r), zone)
+}
+
+// lang — язык страницы: выбранный в профиле, иначе из cookie lang,
+// иначе по Accept-Language; "" — язык по умолчанию.
+func (s *Server) lang(r *http.Request) string {
+	if uid, ok := a.CurrentUserID(r); ok {
+		if lang, err := s.Repo.GetUserLang(r.Context(), uid); err == nil && lang != "" {
+			return lang
+		}
+	}
+	if c, err := r.Cookie(langCookie); err == nil && i18n.Supported(c.Value) {
+		return c.Value
+	}
+	return i18n.Negotiate(r.Header.Get("Accept-Language"))
+}
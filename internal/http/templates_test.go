@@ -0,0 +1,47 @@
+package httpx
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	a "learny/internal/auth"
+	"learny/internal/i18n"
+	"learny/web"
+)
+
+// Встроенные шаблоны разбираются, и все их ключи есть в каталоге сообщений.
+func TestEmbeddedTemplates(t *testing.T) {
+	sub, err := fs.Sub(web.FS, "templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadTemplates(sub, false); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTemplateUnknownKey(t *testing.T) {
+	fsys := fstest.MapFS{
+		baseTemplate:     {Data: []byte(`{{ block "content" . }}{{ end }}`)},
+		"page.tmpl.html": {Data: []byte(`{{ template "base.tmpl.html" . }}{{ define "content" }}{{ t .L "no.such.key" }}{{ t $.L "lang.label" }}{{ end }}`)},
+	}
+	_, err := LoadTemplates(fsys, false)
+	if err == nil || !strings.Contains(err.Error(), "page.tmpl.html: no.such.key") {
+		t.Fatalf("err = %v", err)
+	}
+	if strings.Contains(err.Error(), "lang.label") {
+		t.Errorf("известный ключ в ошибке: %v", err)
+	}
+}
+
+// Описание права берётся по ключу, собранному в шаблоне (print "scope." .Name),
+// — его checkMessageKeys не видит.
+func TestScopeTitles(t *testing.T) {
+	for _, sc := range a.Scopes {
+		if !i18n.Has("scope." + sc.Name) {
+			t.Errorf("нет ключа scope.%s", sc.Name)
+		}
+	}
+}
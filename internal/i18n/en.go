@@ -0,0 +1,451 @@
+package i18n
+
+var en = map[string]string{
+	"lang.name":  "English",
+	"lang.label": "Language",
+
+	"common.yes":         "Yes",
+	"common.no":          "No",
+	"common.never":       "never",
+	"common.save":        "Save",
+	"common.create":      "Create",
+	"common.delete":      "Delete",
+	"common.back":        "Back",
+	"common.prev":        "← Back",
+	"common.next":        "Next →",
+	"common.show":        "Show",
+	"common.filter":      "Filter",
+	"common.list":        "List",
+	"common.export_csv":  "Export CSV",
+	"common.done":        "Done",
+	"common.num":         "#",
+	"common.when":        "When",
+	"common.status":      "Status",
+	"common.state":       "State",
+	"common.course":      "Course",
+	"common.quiz":        "Quiz",
+	"common.topic":       "Topic",
+	"common.type":        "Type",
+	"common.user":        "User",
+	"common.score":       "Score",
+	"common.name":        "Name",
+	"common.description": "Description",
+	"common.password":    "Password",
+	"common.to_courses":  "To courses",
+
+	"error.internal":    "Internal error",
+	"error.internal_id": "Internal error. Request ID: %s",
+	"error.bad_date":    "date must be YYYY-MM-DD",
+	"error.not_found":   "not found",
+
+	"answer.correct": "Correct",
+	"answer.wrong":   "Wrong",
+
+	"duration.h":   "%d h",
+	"duration.min": "%d min",
+	"duration.sec": "%d s",
+
+	"attempt.status.in_progress": "in progress",
+	"attempt.status.submitted":   "submitted",
+	"attempt.status.expired":     "expired",
+	"attempt.status.abandoned":   "abandoned",
+	"attempt.status.voided":      "voided",
+
+	"attempts.one":   "attempt",
+	"attempts.few":   "attempts",
+	"attempts.many":  "attempts",
+	"answers.one":    "answer",
+	"answers.few":    "answers",
+	"answers.many":   "answers",
+	"questions.one":  "question",
+	"questions.few":  "questions",
+	"questions.many": "questions",
+	"days.one":       "day",
+	"days.few":       "days",
+	"days.many":      "days",
+
+	// меню
+	"nav.courses":     "Courses",
+	"nav.topics":      "Topics",
+	"nav.password":    "Password",
+	"nav.sessions":    "Sessions",
+	"nav.admin":       "Admin",
+	"nav.quizzes":     "Quizzes",
+	"nav.results":     "Results",
+	"nav.users":       "Users",
+	"nav.questions":   "Questions",
+	"nav.import_csv":  "Import CSV",
+	"nav.import_json": "Import JSON",
+	"nav.logs":        "Logs",
+	"nav.audit":       "Audit",
+	"nav.logout":      "Log out",
+	"nav.login":       "Log in",
+	"nav.register":    "Sign up",
+
+	// главная для гостя
+	"landing.title":          "Learny — learning and testing platform",
+	"landing.hero":           "Learn, practise and test your knowledge with flexible courses and quizzes",
+	"landing.lead":           "Build courses with modules, run timed tests with attempt limits, import questions from CSV/JSON, analyse results and weak topics.",
+	"landing.start":          "Start for free",
+	"landing.have_account":   "I already have an account",
+	"landing.browse":         "Browse courses",
+	"landing.structure":      "Flexible structure",
+	"landing.structure_text": "Courses → quizzes → questions (single/multiple/number/text). Difficulty, topics, tags.",
+	"landing.limits":         "Limits and timer",
+	"landing.limits_text":    "Time per attempt, cooldown between retakes, maximum attempts, randomised questions.",
+	"landing.import":         "CSV/JSON import",
+	"landing.import_text":    "Bulk upload of the question bank. Multiple correct answers supported.",
+	"landing.analytics":      "Analytics",
+	"landing.analytics_text": "Attempt results, duration, overtime, weak topics and recommendations.",
+	"landing.roles":          "Roles",
+	"landing.roles_text":     "Administrator, teacher, student. Manage users, courses and quizzes.",
+	"landing.export":         "Export",
+	"landing.export_text":    "Export results to CSV for reporting and further analysis.",
+	"landing.how":            "Getting started",
+	"landing.step1":          "Sign up or log in.",
+	"landing.step2":          "Open “Courses” and start an available quiz — or create your own (teachers and admins).",
+	"landing.step3":          "Import questions (CSV/JSON) or add them by hand.",
+	"landing.step4":          "Set up the quiz rules: time limit, attempts, cooldown between retakes.",
+	"landing.step5":          "Review the results and use “Topics”/“Recommendations” for targeted practice.",
+	"landing.go_courses":     "Go to courses",
+
+	// вход и регистрация
+	"login.title":        "Log in",
+	"login.no_account":   "No account?",
+	"login.forgot":       "Forgot your password?",
+	"login.failed":       "Wrong login or password",
+	"login.locked":       "The account is locked after failed login attempts until %s. Contact an administrator or wait.",
+	"login.rate_limited": "Too many attempts. Wait %d min and try again.",
+	"login.too_many":     "Too many requests",
+
+	"login2fa.title":   "Confirm login",
+	"login2fa.hint":    "No access to your phone? Enter one of your recovery codes instead of the app code.",
+	"login2fa.expired": "The code has expired or you are out of attempts. Please log in again.",
+
+	"register.password":     "Password (min. 8)",
+	"register.submit":       "Create account",
+	"register.have_account": "Already registered?",
+	"register.invalid":      "Enter a valid email and a password of at least 8 characters",
+	"register.exists":       "A user with this email already exists",
+
+	// пароль
+	"forgot.title":      "Password recovery",
+	"forgot.submit":     "Send link",
+	"forgot.back":       "Back to login",
+	"forgot.sent_title": "Check your email",
+	"forgot.sent":       "If an account with this email exists, we have sent it a password reset link.",
+
+	"password.title":         "Change password",
+	"password.current":       "Current password",
+	"password.new":           "New password (min. 8)",
+	"password.repeat":        "Repeat new password",
+	"password.invalid":       "The password must be at least 8 characters and both new password fields must match",
+	"password.wrong_current": "The current password is wrong",
+	"password.changed":       "Password changed. Other devices have been logged out.",
+	"reset.title":            "New password",
+	"reset.done":             "Password changed. Log in with your new password.",
+	"token.invalid_title":    "Invalid link",
+	"token.invalid":          "the link is invalid or has expired",
+
+	// письма
+	"mail.reset_subject":  "Learny: password reset",
+	"mail.reset_body":     "To set a new password, open the link (valid for 1 hour):\n\n%s\n\nIf you did not request a reset, just ignore this email.\n",
+	"mail.verify_subject": "Learny: confirm your email",
+	"mail.verify_body":    "To confirm your address, open the link (valid for 48 hours):\n\n%s\n",
+	"mail.send_failed":    "failed to send email: %s",
+
+	// подтверждение email
+	"verify.title":       "Email confirmation",
+	"verify.heading":     "Confirm your email",
+	"verify.blocked":     "To take quizzes, confirm the address",
+	"verify.send_failed": "Could not send the email to",
+	"verify.retry_later": "Try sending it again later.",
+	"verify.sent":        "We have sent a confirmation link to",
+	"verify.resend":      "Send the email again",
+	"verify.done_title":  "Email confirmed",
+	"verify.done":        "Thank you! Your address is confirmed.",
+	"verify.already":     "The address is already confirmed.",
+
+	// 2FA
+	"2fa.title":         "Two-factor authentication",
+	"2fa.code":          "App code",
+	"2fa.codes":         "Recovery codes.",
+	"2fa.codes_hint":    "Keep them somewhere safe — they will not be shown again. Each code can be used once to log in when your phone is not at hand.",
+	"2fa.enabled":       "2FA is enabled. Recovery codes left: %d.",
+	"2fa.new_codes":     "Issue new recovery codes",
+	"2fa.required_on":   "2FA is mandatory for your role and cannot be disabled.",
+	"2fa.disable":       "Disable 2FA",
+	"2fa.step_open":     "Open the link",
+	"2fa.step_manual":   "on your phone or add the account to your authenticator app manually with the key:",
+	"2fa.step_code":     "Enter the six-digit code from the app.",
+	"2fa.enable":        "Enable",
+	"2fa.required":      "Your role requires 2FA to be enabled before you can continue.",
+	"2fa.intro":         "Logging in will require a code from an authenticator app (Google Authenticator, Aegis, 1Password, etc.).",
+	"2fa.begin":         "Set up",
+	"2fa.bad_code":      "Wrong code",
+	"2fa.bad_code_time": "Wrong code. Check the time on your phone and try again.",
+	"2fa.not_pending":   "2FA is already enabled or setup has not been started.",
+
+	// сессии
+	"sessions.title":       "Active sessions",
+	"sessions.login":       "Logged in",
+	"sessions.last_seen":   "Last activity",
+	"sessions.device":      "Device",
+	"sessions.this_device": "this device",
+	"sessions.revoke":      "Log out on this device",
+	"sessions.confirm_all": "Log out on all devices?",
+	"sessions.revoke_all":  "Log out everywhere",
+
+	// API-токены
+	"tokens.title":            "API tokens",
+	"tokens.created_note":     "New token.",
+	"tokens.copy_now":         "Copy it now — it will not be shown again.",
+	"tokens.header_hint":      "Send it in the header:",
+	"tokens.token":            "token",
+	"tokens.scopes":           "Scopes",
+	"tokens.created":          "Created",
+	"tokens.used":             "Last used",
+	"tokens.expires":          "Expires",
+	"tokens.expired":          "(expired)",
+	"tokens.confirm_revoke":   "Revoke token “%s”?",
+	"tokens.revoke":           "Revoke",
+	"tokens.none":             "No tokens yet",
+	"tokens.new":              "New token",
+	"tokens.name_placeholder": "e.g. CI question import",
+	"tokens.lifetime":         "Valid for",
+	"tokens.year":             "1 year",
+	"tokens.invalid":          "Enter a name and at least one scope",
+
+	"scope.courses:read":    "view courses",
+	"scope.quizzes:read":    "view quizzes",
+	"scope.attempts:read":   "view your own attempts",
+	"scope.attempts:write":  "take quizzes (start and submit attempts)",
+	"scope.courses:write":   "create and edit courses",
+	"scope.quizzes:write":   "create and edit quizzes",
+	"scope.questions:read":  "view questions with answers",
+	"scope.questions:write": "import and edit questions",
+	"scope.results:read":    "view and export results",
+
+	// обучение
+	"dashboard.title":   "Home",
+	"dashboard.welcome": "Welcome!",
+	"dashboard.hint":    "Go to “Courses” to get started.",
+
+	"courses.empty":      "No courses yet.",
+	"courses.no_quizzes": "No quizzes",
+	"courses.start":      "Start",
+
+	"quiz.time_left":     "Time left:",
+	"quiz.question":      "Question #%d · Topic: %s · Difficulty: %d",
+	"quiz.multiple_hint": "You can select several options",
+	"quiz.submit":        "Finish and submit",
+	"quiz.timer_min":     "m",
+	"quiz.timer_sec":     "s",
+	"quiz.unanswered":    "Answer all questions before submitting. Unanswered: ",
+
+	"start.max_attempts_title": "No attempts left",
+	"start.max_attempts":       "You have used the maximum number of attempts for this quiz.",
+	"start.cooldown_title":     "Too early to retake",
+	"start.cooldown":           "Please wait before a new attempt, as the quiz rules require.",
+	"start.unavailable_title":  "Quiz unavailable",
+	"start.unavailable":        "The question bank cannot satisfy the quiz rules: %s. Please contact your teacher.",
+
+	"late.reject":  "rejected: %d s late",
+	"late.zero":    "zeroed: %d s late",
+	"late.penalty": "penalty %s%%: %d s late",
+
+	"finish.abandoned_title": "Attempt closed",
+	"finish.abandoned":       "This attempt can no longer be submitted: a newer one was started. Your answers were not saved.",
+	"finish.voided_title":    "Attempt voided",
+	"finish.voided":          "This attempt was voided by the teacher; answers are no longer accepted.",
+
+	"result.title":      "Result",
+	"result.attempt_no": "Attempt number:",
+	"result.score":      "Score:",
+	"result.voided":     "The attempt was voided by the teacher.",
+	"result.late":       "Answers were submitted after the time limit — %s.",
+
+	"topics.empty":   "No topic data yet. Take at least one test.",
+	"topics.correct": "Correct: %d of %d",
+	"topics.open":    "Open topic profile",
+	"topic.heading":  "Topic: %s",
+	"topic.empty":    "No data for this topic yet.",
+	"recs.title":     "Recommendations",
+	"recs.empty":     "No data yet. Take at least one test to get recommendations.",
+
+	// админка
+	"admin.title": "Admin: %s",
+
+	"admin_courses.title":          "courses",
+	"admin_courses.new_title":      "New title",
+	"admin_courses.new_desc":       "New description",
+	"admin_courses.confirm_delete": "Delete course #%d?",
+	"admin_courses.create":         "Create course",
+
+	"admin_quizzes.title":            "quizzes",
+	"admin_quizzes.heading":          "Quizzes by course",
+	"admin_quizzes.confirm_delete":   "Delete quiz #%d?",
+	"admin_quizzes.create":           "Create quiz",
+	"admin_quizzes.rules":            "Rules (JSON)",
+	"admin_quizzes.example":          "Example:",
+	"admin_quizzes.fallback":         "— when the bank does not have enough questions:",
+	"admin_quizzes.fallback_strict":  "(error, the default)",
+	"admin_quizzes.fallback_fill":    "(top up with any questions of the course)",
+	"admin_quizzes.fallback_partial": "(give as many as there are).",
+	"admin_quizzes.by_topics":        "— a list of topics",
+	"admin_quizzes.by_topics_quotas": "or quotas",
+	"admin_quizzes.weights_with":     "with",
+	"admin_quizzes.weights":          "the values are weights of",
+	"admin_quizzes.grace":            "— allowance over the time limit;",
+	"admin_quizzes.late_after":       "after it:",
+	"admin_quizzes.late_accept":      "(only mark),",
+	"admin_quizzes.or":               "or",
+	"admin_quizzes.with":             "with",
+	"admin_quizzes.required":         "Fill in the title and the quiz rules JSON.",
+
+	"admin_results.title":   "results",
+	"admin_results.heading": "Results by course",
+	"admin_results.details": "details",
+
+	"admin_attempt.heading":      "Attempt #%d",
+	"admin_attempt.started":      "Started",
+	"admin_attempt.finished":     "Finished",
+	"admin_attempt.duration":     "Duration",
+	"admin_attempt.overtime":     "Overtime",
+	"admin_attempt.late":         "Late",
+	"admin_attempt.confirm_void": "Void attempt #%d?",
+	"admin_attempt.void":         "Void",
+	"admin_attempt.question":     "Question",
+	"admin_attempt.user_answer":  "User's answer",
+	"admin_attempt.correct":      "Correct answer",
+
+	"admin_users.title":             "users",
+	"admin_users.role":              "Role",
+	"admin_users.verified":          "Email confirmed",
+	"admin_users.login":             "Login",
+	"admin_users.actions":           "Actions",
+	"admin_users.send_mail":         "Send email",
+	"admin_users.verify":            "Confirm",
+	"admin_users.confirm_reset_2fa": "Reset 2FA for %s? All of the user's sessions will be ended.",
+	"admin_users.reset":             "Reset",
+	"admin_users.locked":            "locked until %s",
+	"admin_users.unlock":            "Unlock",
+	"admin_users.ok":                "ok",
+	"admin_users.confirm_kill":      "End all sessions of %s?",
+	"admin_users.kill":              "End sessions",
+	"admin_users.logs":              "logs",
+
+	"admin_questions.title":      "questions",
+	"admin_questions.limit":      "Limit",
+	"admin_questions.difficulty": "Diff.",
+	"admin_questions.edit":       "Edit",
+
+	"question_edit.title":      "Edit question",
+	"question_edit.heading":    "Edit question #%d",
+	"question_edit.qtype":      "Type (single/multiple/numeric/text)",
+	"question_edit.difficulty": "Difficulty (0..10)",
+
+	"upload.title":   "Question import (CSV)",
+	"upload.ok":      "Records imported: %d into course #%d",
+	"upload.file":    "CSV file",
+	"upload.submit":  "Upload",
+	"upload.format":  "Format, separator",
+	"upload.to_list": "Go to the question list",
+
+	"upload_json.title":     "question import (JSON)",
+	"upload_json.heading":   "Import questions from JSON",
+	"upload_json.ok":        "Imported:",
+	"upload_json.file":      "JSON file (optional)",
+	"upload_json.file_hint": "If no file is chosen, the contents of the text field below are used.",
+	"upload_json.json":      "JSON (array of questions)",
+	"upload_json.submit":    "Import",
+	"upload_json.no_course": "Choose a course.",
+	"upload_json.empty":     "JSON must not be empty.",
+	"upload_json.object":    "Invalid JSON format: expected an array of questions `[ ... ]`, not a single object `{ ... }`.",
+
+	"logs.title":   "user logs",
+	"logs.heading": "User activity",
+	"logs.total":   "Total attempts",
+	"logs.correct": "Correct answers",
+	"logs.wrong":   "wrong",
+	"logs.last":    "Last activity",
+	"logs.time":    "Time",
+	"logs.details": "Details",
+	"logs.answer":  "Answered a question",
+	"logs.detail":  "Topic: %s, type: %s, status: %s, attempt #%d",
+	"logs.empty":   "No records for this user yet.",
+	"logs.pick":    "Choose a user to see their activity:",
+
+	"audit.title":   "audit log",
+	"audit.heading": "Audit log",
+	"audit.actor":   "Actor",
+	"audit.entity":  "Entity",
+	"audit.all":     "all",
+	"audit.from":    "From",
+	"audit.to":      "To",
+	"audit.found":   "Found: %d",
+	"audit.action":  "Action",
+	"audit.object":  "Object",
+	"audit.changes": "Changes",
+	"audit.request": "request %s",
+	"audit.none":    "No events",
+
+	// JSON API
+	"api.no_method":          "no such API method",
+	"api.object_not_found":   "object not found",
+	"api.question_not_found": "question not found",
+	"api.attempt_not_found":  "attempt not found",
+	"api.token_invalid":      "the token is invalid or lacks the %s scope",
+	"api.unauthorized":       "authentication required",
+	"api.forbidden":          "insufficient permissions",
+	"api.empty_body":         "empty request body",
+	"api.bad_json":           "invalid JSON: %s",
+	"api.bad_id":             "invalid id",
+	"api.bad_param":          "invalid %s",
+	"api.bad_limit":          "limit must be between 1 and %d",
+	"api.bad_offset":         "offset cannot be negative",
+	"api.title_required":     "title is required",
+	"api.title_empty":        "title cannot be empty",
+	"api.invalid":            "%s",
+	"api.email_not_verified": "confirm your email to take quizzes",
+	"api.rate_limited":       "too many attempts, please wait",
+	"api.answers_key":        "answers: key must be a question id, got %q",
+	"api.answer_invalid":     "answers[%s]: %s",
+
+	// ошибки проверки из repo
+	"rules.empty":                 "quiz rules must not be empty",
+	"rules.invalid_json":          "invalid rules JSON: %s",
+	"rules.by_topics_format":      "by_topics: expected a list of topics or an object {topic: count}",
+	"rules.time_limit_negative":   "time_limit_sec must not be negative",
+	"rules.max_attempts_negative": "max_attempts must not be negative",
+	"rules.cooldown_negative":     "retake_cooldown_sec must not be negative",
+	"rules.count_negative":        "count must not be negative",
+	"rules.type_count_negative":   "question counts by type must not be negative",
+	"rules.topic_empty":           "by_topics: empty topic name",
+	"rules.topic_count_negative":  "by_topics: topic count must not be negative",
+	"rules.topic_count_zero":      "by_topics: every topic needs a count > 0 (or give just a list of topics)",
+	"rules.weights_need_quotas":   "topic_weights requires by_topics as {topic: weight}",
+	"rules.total_required":        "set the total number of questions (count), topic quotas or per-type counts > 0",
+	"rules.count_below_types":     "count must not be less than the sum of per-type counts",
+	"rules.quotas_sum":            "the by_topics quotas must add up to count",
+	"rules.difficulty_negative":   "min_difficulty and max_difficulty must not be negative",
+	"rules.difficulty_range":      "min_difficulty must not be greater than max_difficulty",
+	"rules.fallback":              "fallback must be strict, fill or partial",
+	"rules.grace_negative":        "grace_sec must not be negative",
+	"rules.penalty_pct":           "late_policy=penalty needs late_penalty_pct between 0 and 100",
+	"rules.late_policy":           "late_policy must be accept, reject, zero or penalty",
+	"rules.late_needs_limit":      "late_policy only makes sense together with time_limit_sec",
+
+	"question.topic_empty":    "topic must not be empty",
+	"question.qtype":          "unknown question type %q: single, multiple, numeric or text",
+	"question.difficulty":     "difficulty must be between 1 and 5",
+	"question.payload_object": "payload must be a JSON object",
+	"question.payload_text":   "payload.text is required",
+
+	"bank.short_topic": "the bank has too few questions on topic “%s”: %d needed, %d available",
+	"bank.short_type":  "the bank has too few questions of type %s: %d needed, %d available",
+	"bank.short":       "the bank has too few questions: %d needed, %d available",
+
+	"attempt.not_owned":    "the attempt belongs to another user",
+	"answer.invalid_value": "expected a string, a number or an array",
+}
@@ -0,0 +1,201 @@
+// Package i18n — язык и часовой пояс, в которых показывается страница,
+// и каталоги сообщений интерфейса.
+package i18n
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultLang — язык интерфейса, если другой не выбран.
+const DefaultLang = "ru"
+
+// catalogs — сообщения по языкам: ключ → текст (формат fmt для T с аргументами).
+// Набор ключей во всех каталогах одинаков, это проверяет Check.
+var catalogs = map[string]map[string]string{
+	"ru": ru,
+	"en": en,
+}
+
+// Langs — поддерживаемые языки по алфавиту (для переключателя).
+func Langs() []string {
+	out := make([]string, 0, len(catalogs))
+	for lang := range catalogs {
+		out = append(out, lang)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Supported — есть ли каталог для lang.
+func Supported(lang string) bool {
+	_, ok := catalogs[lang]
+	return ok
+}
+
+// Locale — язык и часовой пояс текущего запроса.
+type Locale struct {
+	Lang string
+	Zone *time.Location
+}
+
+// New — локаль для lang (неизвестный язык — DefaultLang) и пояса zone (nil — пояс сервера).
+func New(lang string, zone *time.Location) *Locale {
+	if !Supported(lang) {
+		lang = DefaultLang
+	}
+	if zone == nil {
+		zone = time.Local
+	}
+	return &Locale{Lang: lang, Zone: zone}
+}
+
+// Default — сообщение на языке по умолчанию: для логов, learnyctl и Error()
+// ошибок с ключом каталога.
+func Default(key string, args ...any) string {
+	return New(DefaultLang, nil).T(key, args...)
+}
+
+// T — сообщение по ключу; если его нет в каталоге языка — из DefaultLang,
+// иначе сам ключ (так пропуск сразу виден на странице).
+// Аргументы-ошибки с ключом каталога (Message) тоже переводятся.
+func (l *Locale) T(key string, args ...any) string {
+	msg, ok := catalogs[l.Lang][key]
+	if !ok {
+		if msg, ok = catalogs[DefaultLang][key]; !ok {
+			msg = key
+		}
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	vals := make([]any, len(args)) // не портим срез вызывающего (Args ошибки)
+	for i, arg := range args {
+		if m, ok := arg.(Message); ok {
+			arg = l.Err(m)
+		}
+		vals[i] = arg
+	}
+	return fmt.Sprintf(msg, vals...)
+}
+
+// Plural — форма слова для числа n: ключи key.one, key.few, key.many.
+func (l *Locale) Plural(n int, key string) string {
+	return l.T(key + "." + pluralForm(l.Lang, n))
+}
+
+// pluralForm — правило CLDR: в русском one (1, 21), few (2–4, 22–24), many (остальные);
+// в языках без few — one и many.
+func pluralForm(lang string, n int) string {
+	if n < 0 {
+		n = -n
+	}
+	if lang != "ru" {
+		if n == 1 {
+			return "one"
+		}
+		return "many"
+	}
+	switch n10, n100 := n%10, n%100; {
+	case n10 == 1 && n100 != 11:
+		return "one"
+	case n10 >= 2 && n10 <= 4 && (n100 < 12 || n100 > 14):
+		return "few"
+	}
+	return "many"
+}
+
+// Message — ошибка, текст которой берётся из каталога: repo возвращает ключ
+// и аргументы, а на язык пользователя её переводит интерфейс.
+type Message interface {
+	error
+	Message() (key string, args []any)
+}
+
+// Err — текст ошибки на языке локали: для Message — перевод, для остальных — err.Error().
+func (l *Locale) Err(err error) string {
+	var m Message
+	if errors.As(err, &m) {
+		key, args := m.Message()
+		return l.T(key, args...)
+	}
+	return err.Error()
+}
+
+// Negotiate — лучший поддерживаемый язык по заголовку Accept-Language
+// ("en-US,en;q=0.9,ru;q=0.8"); "" — подходящего нет. При равном q
+// выигрывает стоящий раньше.
+func Negotiate(header string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = f
+		}
+		lang, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if Supported(lang) && q > bestQ {
+			best, bestQ = lang, q
+		}
+	}
+	return best
+}
+
+// verbRe — глаголы fmt в тексте сообщения (%d, %s, %.1f; %% не в счёт).
+var verbRe = regexp.MustCompile(`%[-+# 0]*[0-9]*(?:\.[0-9]+)?[a-zA-Z%]`)
+
+func verbs(msg string) string {
+	var out []string
+	for _, v := range verbRe.FindAllString(msg, -1) {
+		if v != "%%" {
+			out = append(out, v)
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+// Check сверяет каталоги: каждый ключ есть во всех языках и принимает
+// те же аргументы (%d, %s), что и в DefaultLang. Сервер с неполным
+// каталогом не стартует — пропущенный перевод не доходит до пользователя.
+func Check() error {
+	keys := map[string]bool{}
+	for _, cat := range catalogs {
+		for key := range cat {
+			keys[key] = true
+		}
+	}
+	var problems []string
+	for _, lang := range Langs() {
+		cat := catalogs[lang]
+		for key := range keys {
+			msg, ok := cat[key]
+			switch {
+			case !ok:
+				problems = append(problems, lang+": нет ключа "+key)
+			case lang != DefaultLang && verbs(msg) != verbs(catalogs[DefaultLang][key]):
+				problems = append(problems, fmt.Sprintf("%s: %s: аргументы %q, в %s — %q",
+					lang, key, verbs(msg), DefaultLang, verbs(catalogs[DefaultLang][key])))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return errors.New("каталоги сообщений:\n  " + strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// Has — есть ли key в каталоге DefaultLang (по Check он тогда есть во всех).
+func Has(key string) bool {
+	_, ok := catalogs[DefaultLang][key]
+	return ok
+}
@@ -0,0 +1,110 @@
+package i18n
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// Каждый ключ есть в каждом каталоге и принимает те же аргументы.
+func TestCatalogsComplete(t *testing.T) {
+	if err := Check(); err != nil {
+		t.Fatal(err)
+	}
+	if len(Langs()) < 2 {
+		t.Fatalf("языки: %v", Langs())
+	}
+}
+
+func TestCheckReportsProblems(t *testing.T) {
+	en["test.only_en"] = "x"
+	en["lang.label"] = "Language %d"
+	defer func() {
+		delete(en, "test.only_en")
+		en["lang.label"] = "Language"
+	}()
+	err := Check()
+	if err == nil {
+		t.Fatal("Check: нет ошибки")
+	}
+	for _, want := range []string{"ru: нет ключа test.only_en", "en: lang.label: аргументы"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("в ошибке нет %q:\n%v", want, err)
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct{ header, want string }{
+		{"", ""},
+		{"en-US,en;q=0.9,ru;q=0.8", "en"},
+		{"de-DE,ru;q=0.5,en;q=0.7", "en"},
+		{"ru-RU, en", "ru"},           // равный q — выигрывает первый
+		{"fr, de;q=0.9", ""},          // ничего не поддерживается
+		{"EN-gb;q=0.3, ru;q=0", "en"}, // q=0 — «не надо»
+		{"en;q=abc, ru;q=0.1", "ru"},  // битый q пропускается
+	}
+	for _, tt := range tests {
+		if got := Negotiate(tt.header); got != tt.want {
+			t.Errorf("Negotiate(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestPlural(t *testing.T) {
+	ru, en := New("ru", nil), New("en", nil)
+	tests := []struct {
+		n      int
+		ru, en string
+	}{
+		{1, "попытка", "attempt"},
+		{2, "попытки", "attempts"},
+		{5, "попыток", "attempts"},
+		{11, "попыток", "attempts"},
+		{21, "попытка", "attempts"},
+		{24, "попытки", "attempts"},
+		{112, "попыток", "attempts"},
+		{0, "попыток", "attempts"},
+	}
+	for _, tt := range tests {
+		if got := ru.Plural(tt.n, "attempts"); got != tt.ru {
+			t.Errorf("ru %d: %q, want %q", tt.n, got, tt.ru)
+		}
+		if got := en.Plural(tt.n, "attempts"); got != tt.en {
+			t.Errorf("en %d: %q, want %q", tt.n, got, tt.en)
+		}
+	}
+}
+
+type keyErr struct {
+	key  string
+	args []any
+}
+
+func (e *keyErr) Error() string            { return Default(e.key, e.args...) }
+func (e *keyErr) Message() (string, []any) { return e.key, e.args }
+
+func TestErr(t *testing.T) {
+	en := New("en", nil)
+	inner := &keyErr{"bank.short", []any{5, 3}}
+	outer := &keyErr{"start.unavailable", []any{inner}}
+
+	if got, want := en.Err(fmt.Errorf("обёртка: %w", inner)), en.T("bank.short", 5, 3); got != want {
+		t.Errorf("Err через %%w: %q, want %q", got, want)
+	}
+	// ошибка-аргумент тоже переводится, а её Args не портятся
+	got := en.Err(outer)
+	if !strings.Contains(got, en.T("bank.short", 5, 3)) || strings.Contains(got, "в банке") {
+		t.Errorf("вложенная ошибка: %q", got)
+	}
+	if outer.args[0] != inner {
+		t.Error("T изменил Args ошибки")
+	}
+	if got := en.Err(errors.New("plain")); got != "plain" {
+		t.Errorf("обычная ошибка: %q", got)
+	}
+	if got := en.T("no.such.key"); got != "no.such.key" {
+		t.Errorf("нет ключа: %q", got)
+	}
+}
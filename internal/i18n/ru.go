@@ -0,0 +1,451 @@
+package i18n
+
+var ru = map[string]string{
+	"lang.name":  "Русский",
+	"lang.label": "Язык",
+
+	"common.yes":         "Да",
+	"common.no":          "Нет",
+	"common.never":       "бессрочно",
+	"common.save":        "Сохранить",
+	"common.create":      "Создать",
+	"common.delete":      "Удалить",
+	"common.back":        "Назад",
+	"common.prev":        "← Назад",
+	"common.next":        "Дальше →",
+	"common.show":        "Показать",
+	"common.filter":      "Фильтр",
+	"common.list":        "Список",
+	"common.export_csv":  "Экспорт CSV",
+	"common.done":        "Готово",
+	"common.num":         "№",
+	"common.when":        "Когда",
+	"common.status":      "Статус",
+	"common.state":       "Состояние",
+	"common.course":      "Курс",
+	"common.quiz":        "Квиз",
+	"common.topic":       "Тема",
+	"common.type":        "Тип",
+	"common.user":        "Пользователь",
+	"common.score":       "Балл",
+	"common.name":        "Название",
+	"common.description": "Описание",
+	"common.password":    "Пароль",
+	"common.to_courses":  "К курсам",
+
+	"error.internal":    "Внутренняя ошибка",
+	"error.internal_id": "Внутренняя ошибка. Код запроса: %s",
+	"error.bad_date":    "дата в формате ГГГГ-ММ-ДД",
+	"error.not_found":   "объект не найден",
+
+	"answer.correct": "Верно",
+	"answer.wrong":   "Неверно",
+
+	"duration.h":   "%d ч",
+	"duration.min": "%d мин",
+	"duration.sec": "%d с",
+
+	"attempt.status.in_progress": "идёт",
+	"attempt.status.submitted":   "сдана",
+	"attempt.status.expired":     "просрочена",
+	"attempt.status.abandoned":   "брошена",
+	"attempt.status.voided":      "аннулирована",
+
+	"attempts.one":   "попытка",
+	"attempts.few":   "попытки",
+	"attempts.many":  "попыток",
+	"answers.one":    "ответ",
+	"answers.few":    "ответа",
+	"answers.many":   "ответов",
+	"questions.one":  "вопрос",
+	"questions.few":  "вопроса",
+	"questions.many": "вопросов",
+	"days.one":       "день",
+	"days.few":       "дня",
+	"days.many":      "дней",
+
+	// меню
+	"nav.courses":     "Курсы",
+	"nav.topics":      "Темы",
+	"nav.password":    "Пароль",
+	"nav.sessions":    "Сессии",
+	"nav.admin":       "Админ",
+	"nav.quizzes":     "Квизы",
+	"nav.results":     "Результаты",
+	"nav.users":       "Пользователи",
+	"nav.questions":   "Вопросы",
+	"nav.import_csv":  "Импорт CSV",
+	"nav.import_json": "Импорт JSON",
+	"nav.logs":        "Логи",
+	"nav.audit":       "Аудит",
+	"nav.logout":      "Выйти",
+	"nav.login":       "Войти",
+	"nav.register":    "Регистрация",
+
+	// главная для гостя
+	"landing.title":          "Learny — обучающая и тестирующая система",
+	"landing.hero":           "Учитесь, тренируйтесь и проверяйте знания с гибкой структурой курсов и квизов",
+	"landing.lead":           "Создавайте курсы с модулями, запускайте тесты с тайм-лимитом и ограничением попыток, импортируйте вопросы из CSV/JSON, анализируйте результаты и слабые темы.",
+	"landing.start":          "Начать бесплатно",
+	"landing.have_account":   "У меня уже есть аккаунт",
+	"landing.browse":         "Посмотреть курсы",
+	"landing.structure":      "Гибкая структура",
+	"landing.structure_text": "Курсы → квизы → вопросы (single/multiple/число/текст). Сложность, темы, теги.",
+	"landing.limits":         "Ограничения и таймер",
+	"landing.limits_text":    "Время на попытку, пауза между пересдачами, максимум попыток, рандомизация вопросов.",
+	"landing.import":         "Импорт CSV/JSON",
+	"landing.import_text":    "Массовая загрузка банка вопросов. Поддержка нескольких правильных ответов.",
+	"landing.analytics":      "Аналитика",
+	"landing.analytics_text": "Результаты попыток, длительность, перерасход времени, «слабые» темы и рекомендации.",
+	"landing.roles":          "Роли",
+	"landing.roles_text":     "Администратор, преподаватель, студент. Управление пользователями, курсами и квизами.",
+	"landing.export":         "Экспорт",
+	"landing.export_text":    "Выгрузка результатов в CSV для отчётности и последующего анализа.",
+	"landing.how":            "Как начать",
+	"landing.step1":          "Зарегистрируйтесь или войдите в аккаунт.",
+	"landing.step2":          "Откройте «Курсы» и запустите доступный квиз — или создайте свой (для преподавателя/админа).",
+	"landing.step3":          "Импортируйте вопросы (CSV/JSON) или добавьте вручную.",
+	"landing.step4":          "Настройте правила квиза: тайм-лимит, попытки, пауза между пересдачами.",
+	"landing.step5":          "Анализируйте результаты и переходите к разделу «Темы»/«Рекомендации» для точечной подготовки.",
+	"landing.go_courses":     "Перейти к курсам",
+
+	// вход и регистрация
+	"login.title":        "Вход",
+	"login.no_account":   "Нет аккаунта?",
+	"login.forgot":       "Забыли пароль?",
+	"login.failed":       "Неверный логин или пароль",
+	"login.locked":       "Аккаунт временно заблокирован из-за неудачных попыток входа до %s. Обратитесь к администратору или подождите.",
+	"login.rate_limited": "Слишком много попыток. Подождите %d мин. и попробуйте снова.",
+	"login.too_many":     "Слишком много запросов",
+
+	"login2fa.title":   "Подтверждение входа",
+	"login2fa.hint":    "Нет доступа к телефону? Введите один из кодов восстановления вместо кода из приложения.",
+	"login2fa.expired": "Время на ввод кода истекло или попытки закончились. Войдите заново.",
+
+	"register.password":     "Пароль (мин. 8)",
+	"register.submit":       "Создать аккаунт",
+	"register.have_account": "Уже зарегистрированы?",
+	"register.invalid":      "Укажите валидный email и пароль ≥ 8 символов",
+	"register.exists":       "Пользователь с таким email уже существует",
+
+	// пароль
+	"forgot.title":      "Восстановление пароля",
+	"forgot.submit":     "Прислать ссылку",
+	"forgot.back":       "Вернуться ко входу",
+	"forgot.sent_title": "Проверьте почту",
+	"forgot.sent":       "Если аккаунт с таким email существует, мы отправили на него ссылку для сброса пароля.",
+
+	"password.title":         "Смена пароля",
+	"password.current":       "Текущий пароль",
+	"password.new":           "Новый пароль (мин. 8)",
+	"password.repeat":        "Повторите новый пароль",
+	"password.invalid":       "Пароль должен быть ≥ 8 символов, и поля нового пароля должны совпадать",
+	"password.wrong_current": "Текущий пароль неверен",
+	"password.changed":       "Пароль изменён. Остальные устройства разлогинены.",
+	"reset.title":            "Новый пароль",
+	"reset.done":             "Пароль изменён. Войдите с новым паролем.",
+	"token.invalid_title":    "Ссылка недействительна",
+	"token.invalid":          "ссылка недействительна или устарела",
+
+	// письма
+	"mail.reset_subject":  "Learny: сброс пароля",
+	"mail.reset_body":     "Чтобы задать новый пароль, откройте ссылку (действует 1 час):\n\n%s\n\nЕсли вы не запрашивали сброс, просто проигнорируйте это письмо.\n",
+	"mail.verify_subject": "Learny: подтвердите email",
+	"mail.verify_body":    "Чтобы подтвердить адрес, откройте ссылку (действует 48 часов):\n\n%s\n",
+	"mail.send_failed":    "не удалось отправить письмо: %s",
+
+	// подтверждение email
+	"verify.title":       "Подтверждение email",
+	"verify.heading":     "Подтвердите email",
+	"verify.blocked":     "Чтобы проходить квизы, подтвердите адрес",
+	"verify.send_failed": "Не удалось отправить письмо на",
+	"verify.retry_later": "Попробуйте отправить ещё раз позже.",
+	"verify.sent":        "Мы отправили письмо со ссылкой для подтверждения на",
+	"verify.resend":      "Отправить письмо ещё раз",
+	"verify.done_title":  "Email подтверждён",
+	"verify.done":        "Спасибо! Адрес подтверждён.",
+	"verify.already":     "Адрес уже подтверждён.",
+
+	// 2FA
+	"2fa.title":         "Двухфакторная аутентификация",
+	"2fa.code":          "Код из приложения",
+	"2fa.codes":         "Коды восстановления.",
+	"2fa.codes_hint":    "Сохраните их в надёжном месте — больше они показаны не будут. Каждый код можно использовать для входа один раз, если под рукой нет телефона.",
+	"2fa.enabled":       "2FA включена. Осталось кодов восстановления: %d.",
+	"2fa.new_codes":     "Выпустить новые коды восстановления",
+	"2fa.required_on":   "Для вашей роли 2FA обязательна, отключить её нельзя.",
+	"2fa.disable":       "Отключить 2FA",
+	"2fa.step_open":     "Откройте ссылку",
+	"2fa.step_manual":   "на телефоне или добавьте аккаунт в приложении-аутентификаторе вручную, указав ключ:",
+	"2fa.step_code":     "Введите шестизначный код из приложения.",
+	"2fa.enable":        "Включить",
+	"2fa.required":      "Для вашей роли нужно включить 2FA, прежде чем продолжить работу.",
+	"2fa.intro":         "Вход будет требовать код из приложения-аутентификатора (Google Authenticator, Aegis, 1Password и т. п.).",
+	"2fa.begin":         "Подключить",
+	"2fa.bad_code":      "Неверный код",
+	"2fa.bad_code_time": "Неверный код. Проверьте время на телефоне и попробуйте снова.",
+	"2fa.not_pending":   "2FA уже включена или подключение не начато.",
+
+	// сессии
+	"sessions.title":       "Активные сессии",
+	"sessions.login":       "Вход",
+	"sessions.last_seen":   "Последняя активность",
+	"sessions.device":      "Устройство",
+	"sessions.this_device": "это устройство",
+	"sessions.revoke":      "Выйти на этом устройстве",
+	"sessions.confirm_all": "Выйти на всех устройствах?",
+	"sessions.revoke_all":  "Выйти везде",
+
+	// API-токены
+	"tokens.title":            "API-токены",
+	"tokens.created_note":     "Новый токен.",
+	"tokens.copy_now":         "Скопируйте его сейчас — больше он показан не будет.",
+	"tokens.header_hint":      "Передавайте в заголовке:",
+	"tokens.token":            "токен",
+	"tokens.scopes":           "Права",
+	"tokens.created":          "Создан",
+	"tokens.used":             "Использован",
+	"tokens.expires":          "Действует до",
+	"tokens.expired":          "(истёк)",
+	"tokens.confirm_revoke":   "Отозвать токен «%s»?",
+	"tokens.revoke":           "Отозвать",
+	"tokens.none":             "Токенов пока нет",
+	"tokens.new":              "Новый токен",
+	"tokens.name_placeholder": "например, CI импорт вопросов",
+	"tokens.lifetime":         "Срок действия",
+	"tokens.year":             "1 год",
+	"tokens.invalid":          "Укажите название и хотя бы одно право",
+
+	"scope.courses:read":    "просмотр курсов",
+	"scope.quizzes:read":    "просмотр квизов",
+	"scope.attempts:read":   "просмотр своих попыток",
+	"scope.attempts:write":  "прохождение квизов (старт и сдача попыток)",
+	"scope.courses:write":   "создание и изменение курсов",
+	"scope.quizzes:write":   "создание и изменение квизов",
+	"scope.questions:read":  "просмотр вопросов вместе с ответами",
+	"scope.questions:write": "импорт и редактирование вопросов",
+	"scope.results:read":    "просмотр и выгрузка результатов",
+
+	// обучение
+	"dashboard.title":   "Главная",
+	"dashboard.welcome": "Добро пожаловать!",
+	"dashboard.hint":    "Перейдите в «Курсы», чтобы начать.",
+
+	"courses.empty":      "Курсы ещё не созданы.",
+	"courses.no_quizzes": "Нет квизов",
+	"courses.start":      "Начать",
+
+	"quiz.time_left":     "Осталось:",
+	"quiz.question":      "Вопрос #%d · Тема: %s · Сложность: %d",
+	"quiz.multiple_hint": "Можно выбрать несколько вариантов",
+	"quiz.submit":        "Завершить и отправить",
+	"quiz.timer_min":     "м",
+	"quiz.timer_sec":     "с",
+	"quiz.unanswered":    "Заполните ответы на все вопросы перед отправкой. Незаполненных: ",
+
+	"start.max_attempts_title": "Лимит попыток исчерпан",
+	"start.max_attempts":       "Для этого квиза исчерпано максимальное число попыток.",
+	"start.cooldown_title":     "Слишком рано для пересдачи",
+	"start.cooldown":           "Подождите перед новой попыткой согласно правилам квиза.",
+	"start.unavailable_title":  "Квиз недоступен",
+	"start.unavailable":        "Банк вопросов не может выполнить правила квиза: %s. Обратитесь к преподавателю.",
+
+	"late.reject":  "отклонено: опоздание %d с",
+	"late.zero":    "обнулено: опоздание %d с",
+	"late.penalty": "штраф %s%%: опоздание %d с",
+
+	"finish.abandoned_title": "Попытка закрыта",
+	"finish.abandoned":       "Эту попытку уже не сдать: после неё была начата новая. Ответы не сохранены.",
+	"finish.voided_title":    "Попытка аннулирована",
+	"finish.voided":          "Эту попытку аннулировал преподаватель, ответы не принимаются.",
+
+	"result.title":      "Результат",
+	"result.attempt_no": "Номер попытки:",
+	"result.score":      "Баллы:",
+	"result.voided":     "Попытка аннулирована преподавателем.",
+	"result.late":       "Ответы отправлены после лимита времени — %s.",
+
+	"topics.empty":   "Пока нет данных по темам. Пройдите хотя бы один тест.",
+	"topics.correct": "Верных: %d из %d",
+	"topics.open":    "Открыть профиль темы",
+	"topic.heading":  "Тема: %s",
+	"topic.empty":    "Пока нет данных по этой теме.",
+	"recs.title":     "Рекомендации",
+	"recs.empty":     "Пока нет данных. Пройдите хотя бы один тест, чтобы появились рекомендации.",
+
+	// админка
+	"admin.title": "Админ: %s",
+
+	"admin_courses.title":          "курсы",
+	"admin_courses.new_title":      "Новое название",
+	"admin_courses.new_desc":       "Новое описание",
+	"admin_courses.confirm_delete": "Удалить курс #%d?",
+	"admin_courses.create":         "Создать курс",
+
+	"admin_quizzes.title":            "квизы",
+	"admin_quizzes.heading":          "Квизы по курсу",
+	"admin_quizzes.confirm_delete":   "Удалить квиз #%d?",
+	"admin_quizzes.create":           "Создать квиз",
+	"admin_quizzes.rules":            "Правила (JSON)",
+	"admin_quizzes.example":          "Пример:",
+	"admin_quizzes.fallback":         "— если вопросов в банке не хватает:",
+	"admin_quizzes.fallback_strict":  "(ошибка, по умолчанию)",
+	"admin_quizzes.fallback_fill":    "(добрать любыми вопросами курса)",
+	"admin_quizzes.fallback_partial": "(выдать сколько есть).",
+	"admin_quizzes.by_topics":        "— список тем",
+	"admin_quizzes.by_topics_quotas": "или квоты",
+	"admin_quizzes.weights_with":     "с",
+	"admin_quizzes.weights":          "значения считаются весами от",
+	"admin_quizzes.grace":            "— допуск сверх лимита времени;",
+	"admin_quizzes.late_after":       "после него:",
+	"admin_quizzes.late_accept":      "(только пометить),",
+	"admin_quizzes.or":               "или",
+	"admin_quizzes.with":             "вместе с",
+	"admin_quizzes.required":         "Нужно заполнить название и JSON с правилами квиза.",
+
+	"admin_results.title":   "результаты",
+	"admin_results.heading": "Результаты по курсу",
+	"admin_results.details": "детали",
+
+	"admin_attempt.heading":      "Попытка #%d",
+	"admin_attempt.started":      "Начато",
+	"admin_attempt.finished":     "Завершено",
+	"admin_attempt.duration":     "Длительность",
+	"admin_attempt.overtime":     "Овертайм",
+	"admin_attempt.late":         "Опоздание",
+	"admin_attempt.confirm_void": "Аннулировать попытку #%d?",
+	"admin_attempt.void":         "Аннулировать",
+	"admin_attempt.question":     "Вопрос",
+	"admin_attempt.user_answer":  "Ответ пользователя",
+	"admin_attempt.correct":      "Правильный",
+
+	"admin_users.title":             "пользователи",
+	"admin_users.role":              "Роль",
+	"admin_users.verified":          "Email подтверждён",
+	"admin_users.login":             "Вход",
+	"admin_users.actions":           "Действия",
+	"admin_users.send_mail":         "Отправить письмо",
+	"admin_users.verify":            "Подтвердить",
+	"admin_users.confirm_reset_2fa": "Сбросить 2FA у %s? Все сессии пользователя будут завершены.",
+	"admin_users.reset":             "Сбросить",
+	"admin_users.locked":            "заблокирован до %s",
+	"admin_users.unlock":            "Разблокировать",
+	"admin_users.ok":                "ок",
+	"admin_users.confirm_kill":      "Завершить все сессии %s?",
+	"admin_users.kill":              "Завершить сессии",
+	"admin_users.logs":              "логи",
+
+	"admin_questions.title":      "вопросы",
+	"admin_questions.limit":      "Лимит",
+	"admin_questions.difficulty": "Сложн.",
+	"admin_questions.edit":       "Править",
+
+	"question_edit.title":      "Правка вопроса",
+	"question_edit.heading":    "Правка вопроса #%d",
+	"question_edit.qtype":      "Тип (single/multiple/numeric/text)",
+	"question_edit.difficulty": "Сложность (0..10)",
+
+	"upload.title":   "Импорт вопросов (CSV)",
+	"upload.ok":      "Импортировано записей: %d в курс #%d",
+	"upload.file":    "CSV-файл",
+	"upload.submit":  "Загрузить",
+	"upload.format":  "Формат, разделитель",
+	"upload.to_list": "Перейти к списку вопросов",
+
+	"upload_json.title":     "импорт вопросов (JSON)",
+	"upload_json.heading":   "Импорт вопросов из JSON",
+	"upload_json.ok":        "Импортировано:",
+	"upload_json.file":      "Файл JSON (опционально)",
+	"upload_json.file_hint": "Если файл не выбран, будет использовано содержимое текстового поля ниже.",
+	"upload_json.json":      "JSON (массив вопросов)",
+	"upload_json.submit":    "Импортировать",
+	"upload_json.no_course": "Нужно выбрать курс.",
+	"upload_json.empty":     "JSON не должен быть пустым.",
+	"upload_json.object":    "Некорректный формат JSON: ожидается массив вопросов `[ ... ]`, а не один объект `{ ... }`.",
+
+	"logs.title":   "логи по пользователю",
+	"logs.heading": "Аудит по пользователю",
+	"logs.total":   "Итого попыток",
+	"logs.correct": "Верных ответов",
+	"logs.wrong":   "неверных",
+	"logs.last":    "Последняя активность",
+	"logs.time":    "Время",
+	"logs.details": "Детали",
+	"logs.answer":  "Ответ по вопросу",
+	"logs.detail":  "Тема: %s, тип: %s, статус: %s, попытка #%d",
+	"logs.empty":   "По этому пользователю ещё нет записей.",
+	"logs.pick":    "Выберите пользователя, чтобы посмотреть его аудит:",
+
+	"audit.title":   "журнал действий",
+	"audit.heading": "Журнал действий",
+	"audit.actor":   "Автор",
+	"audit.entity":  "Сущность",
+	"audit.all":     "все",
+	"audit.from":    "С даты",
+	"audit.to":      "По дату",
+	"audit.found":   "Найдено: %d",
+	"audit.action":  "Действие",
+	"audit.object":  "Объект",
+	"audit.changes": "Изменения",
+	"audit.request": "запрос %s",
+	"audit.none":    "Событий нет",
+
+	// JSON API
+	"api.no_method":          "нет такого метода API",
+	"api.object_not_found":   "объект не найден",
+	"api.question_not_found": "вопрос не найден",
+	"api.attempt_not_found":  "попытка не найдена",
+	"api.token_invalid":      "токен недействителен или не имеет права %s",
+	"api.unauthorized":       "нужна авторизация",
+	"api.forbidden":          "недостаточно прав",
+	"api.empty_body":         "пустое тело запроса",
+	"api.bad_json":           "некорректный JSON: %s",
+	"api.bad_id":             "некорректный id",
+	"api.bad_param":          "некорректный %s",
+	"api.bad_limit":          "limit должен быть от 1 до %d",
+	"api.bad_offset":         "offset не может быть отрицательным",
+	"api.title_required":     "title обязателен",
+	"api.title_empty":        "title не может быть пустым",
+	"api.invalid":            "%s",
+	"api.email_not_verified": "подтвердите email, чтобы проходить квизы",
+	"api.rate_limited":       "слишком много попыток, подождите",
+	"api.answers_key":        "answers: ключ должен быть id вопроса, получено %q",
+	"api.answer_invalid":     "answers[%s]: %s",
+
+	// ошибки проверки из repo
+	"rules.empty":                 "правила квиза не могут быть пустыми",
+	"rules.invalid_json":          "некорректный JSON правил: %s",
+	"rules.by_topics_format":      "by_topics: ожидается список тем или объект {тема: количество}",
+	"rules.time_limit_negative":   "time_limit_sec не может быть отрицательным",
+	"rules.max_attempts_negative": "max_attempts не может быть отрицательным",
+	"rules.cooldown_negative":     "retake_cooldown_sec не может быть отрицательным",
+	"rules.count_negative":        "count не может быть отрицательным",
+	"rules.type_count_negative":   "количество вопросов по типам не может быть отрицательным",
+	"rules.topic_empty":           "by_topics: пустое название темы",
+	"rules.topic_count_negative":  "by_topics: количество по теме не может быть отрицательным",
+	"rules.topic_count_zero":      "by_topics: у каждой темы должно быть количество > 0 (или задайте просто список тем)",
+	"rules.weights_need_quotas":   "topic_weights требует by_topics в виде {тема: вес}",
+	"rules.total_required":        "нужно указать общее количество вопросов (count), квоты по темам или суммы по типам > 0",
+	"rules.count_below_types":     "count не может быть меньше суммы количеств по типам",
+	"rules.quotas_sum":            "сумма квот by_topics должна совпадать с count",
+	"rules.difficulty_negative":   "min_difficulty и max_difficulty не могут быть отрицательными",
+	"rules.difficulty_range":      "min_difficulty не может быть больше max_difficulty",
+	"rules.fallback":              "fallback должен быть strict, fill или partial",
+	"rules.grace_negative":        "grace_sec не может быть отрицательным",
+	"rules.penalty_pct":           "для late_policy=penalty нужен late_penalty_pct от 0 до 100",
+	"rules.late_policy":           "late_policy должен быть accept, reject, zero или penalty",
+	"rules.late_needs_limit":      "late_policy имеет смысл только вместе с time_limit_sec",
+
+	"question.topic_empty":    "topic не может быть пустым",
+	"question.qtype":          "неизвестный тип вопроса %q: single, multiple, numeric или text",
+	"question.difficulty":     "сложность должна быть от 1 до 5",
+	"question.payload_object": "payload должен быть JSON-объектом",
+	"question.payload_text":   "payload.text обязателен",
+
+	"bank.short_topic": "в банке недостаточно вопросов по теме «%s»: нужно %d, подходит %d",
+	"bank.short_type":  "в банке недостаточно вопросов типа %s: нужно %d, подходит %d",
+	"bank.short":       "в банке недостаточно вопросов: нужно %d, подходит %d",
+
+	"attempt.not_owned":    "попытка принадлежит другому пользователю",
+	"answer.invalid_value": "ожидается строка, число или массив",
+}
@@ -0,0 +1,108 @@
+// Package logx — настройка log/slog и данные запроса в контексте
+// (request id, пользователь), чтобы любая запись в лог и ошибка repo
+// указывали, к какому запросу они относятся.
+package logx
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+)
+
+// New — логгер с уровнем debug|info|warn|error и форматом text|json.
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("уровень лога %q: debug, info, warn или error", level)
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+	switch strings.ToLower(format) {
+	case "text", "":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	}
+	return nil, fmt.Errorf("формат лога %q: text или json", format)
+}
+
+// Request — данные запроса для лога. Лежит в контексте по указателю:
+// middleware ниже по цепочке (WithUser) дописывают пользователя,
+// а журнал запросов снаружи видит его после ответа.
+type Request struct {
+	ID     string
+	IP     string // адрес клиента с учётом доверенных прокси
+	userID atomic.Int64
+}
+
+func (q *Request) SetUserID(id int64) { q.userID.Store(id) }
+func (q *Request) UserID() int64      { return q.userID.Load() }
+
+type ctxKey struct{}
+
+// WithRequest кладёт данные запроса в контекст.
+func WithRequest(ctx context.Context, q *Request) context.Context {
+	return context.WithValue(ctx, ctxKey{}, q)
+}
+
+// FromContext — данные запроса или nil (фоновая задача, CLI).
+func FromContext(ctx context.Context) *Request {
+	q, _ := ctx.Value(ctxKey{}).(*Request)
+	return q
+}
+
+// RequestID — id запроса из контекста или пусто.
+func RequestID(ctx context.Context) string {
+	if q := FromContext(ctx); q != nil {
+		return q.ID
+	}
+	return ""
+}
+
+// SetUserID запоминает пользователя запроса, если контекст из HTTP-запроса.
+func SetUserID(ctx context.Context, id int64) {
+	if q := FromContext(ctx); q != nil {
+		q.SetUserID(id)
+	}
+}
+
+// Logger — slog.Default с request_id и user_id запроса, если они есть.
+func Logger(ctx context.Context) *slog.Logger {
+	l := slog.Default()
+	q := FromContext(ctx)
+	if q == nil {
+		return l
+	}
+	l = l.With("request_id", q.ID)
+	if uid := q.UserID(); uid != 0 {
+		l = l.With("user_id", uid)
+	}
+	return l
+}
+
+// NewRequestID — случайный id (16 hex-символов).
+func NewRequestID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b) // crypto/rand не возвращает ошибок
+	return hex.EncodeToString(b)
+}
+
+// ValidRequestID — можно ли принять id из заголовка X-Request-ID
+// (от прокси): до 64 символов [A-Za-z0-9._-].
+func ValidRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '.', c == '_', c == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
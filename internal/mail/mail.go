@@ -0,0 +1,127 @@
+package mail
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	netmail "net/mail"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"learny/internal/logx"
+)
+
+// Message — простое текстовое письмо.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer — способ доставки писем (SMTP в проде, файл/лог локально и в тестах).
+type Mailer interface {
+	Send(ctx context.Context, m Message) error
+}
+
+// build собирает письмо в формате RFC 5322.
+func build(from string, m Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", m.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mimeHeader(m.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(m.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// mimeHeader кодирует не-ASCII тему письма (RFC 2047).
+func mimeHeader(s string) string {
+	for _, r := range s {
+		if r > 127 {
+			return "=?utf-8?B?" + base64.StdEncoding.EncodeToString([]byte(s)) + "?="
+		}
+	}
+	return s
+}
+
+/* ===== SMTP ===== */
+
+// SMTPMailer отправляет письма через SMTP-сервер (PLAIN auth, если задан логин).
+type SMTPMailer struct {
+	Addr     string // host:port
+	From     string
+	Username string
+	Password string
+}
+
+func (s *SMTPMailer) Send(ctx context.Context, m Message) error {
+	var auth smtp.Auth
+	if s.Username != "" {
+		host, _, err := net.SplitHostPort(s.Addr)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", s.Username, s.Password, host)
+	}
+
+	// в конверте нужен голый адрес, в заголовке From — как задан ("Имя <адрес>")
+	envFrom := s.From
+	if a, err := netmail.ParseAddress(s.From); err == nil {
+		envFrom = a.Address
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(s.Addr, auth, envFrom, []string{m.To}, build(s.From, m))
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+/* ===== файл / лог ===== */
+
+// FileMailer складывает письма в каталог как .eml (для локальной разработки и тестов).
+// Если Dir пустой — во временный каталог learny-mail. В лог попадают только
+// адресат, тема и путь к файлу: в теле живые ссылки сброса пароля и подтверждения.
+type FileMailer struct {
+	Dir  string
+	From string
+}
+
+func (f *FileMailer) Send(ctx context.Context, m Message) error {
+	dir := f.Dir
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "learny-mail")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s_%s.eml", time.Now().Format("20060102T150405.000000000"), safeName(m.To))
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, build(f.From, m), 0o600); err != nil {
+		return err
+	}
+	logx.Logger(ctx).Info("mail", "to", m.To, "subject", m.Subject, "file", path)
+	return nil
+}
+
+func safeName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '@':
+			return r
+		}
+		return '_'
+	}, s)
+}
@@ -0,0 +1,240 @@
+// Package metrics — счётчики, гистограммы и текстовый формат Prometheus
+// (exposition format 0.0.4) без внешних зависимостей.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DefBuckets — границы гистограммы длительности запросов, в секундах.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type collector interface {
+	write(w io.Writer)
+}
+
+// Registry — набор метрик, которые отдаются одним /metrics.
+type Registry struct {
+	mu    sync.Mutex
+	items []collector
+}
+
+func NewRegistry() *Registry { return &Registry{} }
+
+func (r *Registry) add(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.items = append(r.items, c)
+}
+
+// Write пишет все метрики в текстовом формате в порядке регистрации.
+func (r *Registry) Write(w io.Writer) {
+	r.mu.Lock()
+	items := append([]collector(nil), r.items...)
+	r.mu.Unlock()
+	for _, c := range items {
+		c.write(w)
+	}
+}
+
+func header(w io.Writer, name, help, typ string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+/*** счётчики ***/
+
+// Counter — монотонный счётчик.
+type Counter struct{ bits atomic.Uint64 }
+
+func (c *Counter) Inc() { c.Add(1) }
+
+func (c *Counter) Add(v float64) {
+	for {
+		old := c.bits.Load()
+		if c.bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
+
+func (c *Counter) Value() float64 { return math.Float64frombits(c.bits.Load()) }
+
+// CounterVec — счётчики с метками; значения меток передаются в порядке labels.
+type CounterVec struct {
+	name, help string
+	labels     []string
+	mu         sync.Mutex
+	series     map[string]*Counter
+}
+
+// NewCounterVec регистрирует счётчик с метками (без меток — один ряд: With()).
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	v := &CounterVec{name: name, help: help, labels: labels, series: map[string]*Counter{}}
+	r.add(v)
+	return v
+}
+
+// With — ряд с данными значениями меток; создаётся при первом обращении.
+func (v *CounterVec) With(values ...string) *Counter {
+	key := labelString(v.labels, values)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	c, ok := v.series[key]
+	if !ok {
+		c = &Counter{}
+		v.series[key] = c
+	}
+	return c
+}
+
+func (v *CounterVec) write(w io.Writer) {
+	header(w, v.name, v.help, "counter")
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for _, key := range sortedKeys(v.series) {
+		fmt.Fprintf(w, "%s%s %s\n", v.name, key, formatFloat(v.series[key].Value()))
+	}
+}
+
+/*** гистограммы ***/
+
+type histogram struct {
+	mu     sync.Mutex
+	counts []uint64 // по bucket, не накопительно; последний — +Inf
+	sum    float64
+	count  uint64
+}
+
+// HistogramVec — гистограммы с метками и общими границами bucket.
+type HistogramVec struct {
+	name, help string
+	labels     []string
+	buckets    []float64
+	mu         sync.Mutex
+	series     map[string]*histogram
+}
+
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	v := &HistogramVec{name: name, help: help, labels: labels, buckets: buckets, series: map[string]*histogram{}}
+	r.add(v)
+	return v
+}
+
+// Observe добавляет значение в ряд с данными значениями меток.
+func (v *HistogramVec) Observe(value float64, values ...string) {
+	key := labelString(v.labels, values)
+	v.mu.Lock()
+	h, ok := v.series[key]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(v.buckets)+1)}
+		v.series[key] = h
+	}
+	v.mu.Unlock()
+
+	i := sort.SearchFloat64s(v.buckets, value) // первая граница >= value
+	h.mu.Lock()
+	h.counts[i]++
+	h.sum += value
+	h.count++
+	h.mu.Unlock()
+}
+
+func (v *HistogramVec) write(w io.Writer) {
+	header(w, v.name, v.help, "histogram")
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for _, key := range sortedKeys(v.series) {
+		h := v.series[key]
+		h.mu.Lock()
+		var cum uint64
+		for i, le := range v.buckets {
+			cum += h.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", v.name, withLabel(key, "le", formatFloat(le)), cum)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", v.name, withLabel(key, "le", "+Inf"), h.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", v.name, key, formatFloat(h.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", v.name, key, h.count)
+		h.mu.Unlock()
+	}
+}
+
+/*** значения, снимаемые при каждом чтении ***/
+
+type funcMetric struct {
+	name, help, typ string
+	fn              func() float64
+}
+
+// NewGaugeFunc — значение, которое вычисляется при каждом /metrics.
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.add(&funcMetric{name, help, "gauge", fn})
+}
+
+// NewCounterFunc — монотонное значение, которое ведёт кто-то другой (например, sql.DBStats).
+func (r *Registry) NewCounterFunc(name, help string, fn func() float64) {
+	r.add(&funcMetric{name, help, "counter", fn})
+}
+
+func (m *funcMetric) write(w io.Writer) {
+	header(w, m.name, m.help, m.typ)
+	fmt.Fprintf(w, "%s %s\n", m.name, formatFloat(m.fn()))
+}
+
+/*** helpers ***/
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// labelString — `{a="1",b="2"}` или пусто, если меток нет.
+func labelString(names, values []string) string {
+	if len(names) != len(values) {
+		panic(fmt.Sprintf("metrics: ожидается %d значений меток, передано %d", len(names), len(values)))
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, n := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `%s="%s"`, n, labelEscaper.Replace(values[i]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// withLabel дописывает метку к готовой строке меток.
+func withLabel(key, name, value string) string {
+	l := fmt.Sprintf(`%s="%s"`, name, value)
+	if key == "" {
+		return "{" + l + "}"
+	}
+	return key[:len(key)-1] + "," + l + "}"
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
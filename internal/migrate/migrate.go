@@ -0,0 +1,325 @@
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// LegacyVersion — последняя миграция, которую применял отдельный контейнер
+// migrator (psql по списку 001–004). База с таблицей users, но без schema_migrations,
+// считается мигрированной до этой версии включительно; 005 и дальше применяются
+// как обычно — они идемпотентны (IF NOT EXISTS), так что частично обновлённая
+// вручную база тоже доводится до конца.
+const LegacyVersion = 4
+
+// lockID — ключ pg_advisory_lock, чтобы два процесса не мигрировали одновременно.
+const lockID = 7_432_001
+
+// Migration — одна миграция: NNN_name.sql и, если есть, NNN_name.down.sql.
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string // пусто — откат не поддерживается
+	Checksum string // sha256 от Up
+}
+
+// Status — состояние миграции в базе.
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+	Modified  bool // файл изменился после применения (checksum не совпадает)
+}
+
+var fileRe = regexp.MustCompile(`^(\d+)_(.+?)(\.down)?\.sql$`)
+
+// Load читает миграции из fsys (обычно migrations.FS), по возрастанию версий.
+func Load(fsys fs.FS) ([]Migration, error) {
+	files, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int]*Migration{}
+	for _, f := range files {
+		m := fileRe.FindStringSubmatch(f)
+		if m == nil {
+			return nil, fmt.Errorf("migrate: %s: имя должно быть вида 001_name.sql", f)
+		}
+		v, _ := strconv.Atoi(m[1])
+		body, err := fs.ReadFile(fsys, f)
+		if err != nil {
+			return nil, err
+		}
+		mg := byVersion[v]
+		if mg == nil {
+			mg = &Migration{Version: v, Name: m[2]}
+			byVersion[v] = mg
+		}
+		if mg.Name != m[2] {
+			return nil, fmt.Errorf("migrate: версия %d: разные имена %q и %q", v, mg.Name, m[2])
+		}
+		if m[3] != "" {
+			mg.Down = string(body)
+			continue
+		}
+		if mg.Up != "" {
+			return nil, fmt.Errorf("migrate: версия %d встречается дважды", v)
+		}
+		mg.Up = string(body)
+		sum := sha256.Sum256(body)
+		mg.Checksum = hex.EncodeToString(sum[:])
+	}
+
+	out := make([]Migration, 0, len(byVersion))
+	for _, mg := range byVersion {
+		if mg.Up == "" {
+			return nil, fmt.Errorf("migrate: версия %d: есть только .down.sql", mg.Version)
+		}
+		out = append(out, *mg)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+// Migrator применяет и откатывает миграции, отмечая их в schema_migrations.
+type Migrator struct {
+	DB         *sql.DB
+	Migrations []Migration
+}
+
+func New(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	ms, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{DB: db, Migrations: ms}, nil
+}
+
+type applied struct {
+	Checksum  string
+	AppliedAt time.Time
+}
+
+// prepare создаёт schema_migrations. Если её не было, а схема уже есть
+// (база из времён контейнера migrator), записывает миграции до LegacyVersion.
+func (m *Migrator) prepare(ctx context.Context, q querier) error {
+	var exists, legacy bool
+	if err := q.QueryRowContext(ctx,
+		`SELECT to_regclass('schema_migrations') IS NOT NULL, to_regclass('users') IS NOT NULL`,
+	).Scan(&exists, &legacy); err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+	if _, err := q.ExecContext(ctx, `
+		CREATE TABLE schema_migrations (
+		  version    INT PRIMARY KEY,
+		  name       TEXT NOT NULL,
+		  checksum   TEXT NOT NULL,
+		  applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)`); err != nil {
+		return err
+	}
+	if !legacy {
+		return nil
+	}
+	for _, mg := range m.Migrations {
+		if mg.Version > LegacyVersion {
+			break
+		}
+		if _, err := q.ExecContext(ctx,
+			`INSERT INTO schema_migrations(version, name, checksum) VALUES ($1,$2,$3)`,
+			mg.Version, mg.Name, mg.Checksum,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func (m *Migrator) applied(ctx context.Context, q querier) (map[int]applied, error) {
+	rows, err := q.QueryContext(ctx, `SELECT version, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := map[int]applied{}
+	for rows.Next() {
+		var v int
+		var a applied
+		if err := rows.Scan(&v, &a.Checksum, &a.AppliedAt); err != nil {
+			return nil, err
+		}
+		out[v] = a
+	}
+	return out, rows.Err()
+}
+
+// locked выполняет fn на отдельном соединении под advisory lock.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockID); err != nil {
+		return err
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockID)
+
+	if err := m.prepare(ctx, conn); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+// Up применяет все неприменённые миграции, каждую в своей транзакции.
+// Возвращает применённые.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		have, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mg := range m.Migrations {
+			if _, ok := have[mg.Version]; ok {
+				continue
+			}
+			if err := m.run(ctx, conn, mg.Up, func(tx *sql.Tx) error {
+				_, err := tx.ExecContext(ctx,
+					`INSERT INTO schema_migrations(version, name, checksum) VALUES ($1,$2,$3)`,
+					mg.Version, mg.Name, mg.Checksum,
+				)
+				return err
+			}); err != nil {
+				return fmt.Errorf("migrate: %03d_%s: %w", mg.Version, mg.Name, err)
+			}
+			done = append(done, mg)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down откатывает steps последних применённых миграций.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		have, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.Migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			mg := m.Migrations[i]
+			if _, ok := have[mg.Version]; !ok {
+				continue
+			}
+			if mg.Down == "" {
+				return fmt.Errorf("migrate: %03d_%s: нет %03d_%s.down.sql", mg.Version, mg.Name, mg.Version, mg.Name)
+			}
+			if err := m.run(ctx, conn, mg.Down, func(tx *sql.Tx) error {
+				_, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version=$1`, mg.Version)
+				return err
+			}); err != nil {
+				return fmt.Errorf("migrate: откат %03d_%s: %w", mg.Version, mg.Name, err)
+			}
+			done = append(done, mg)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// run выполняет SQL миграции и отметку в schema_migrations одной транзакцией.
+func (m *Migrator) run(ctx context.Context, conn *sql.Conn, script string, mark func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if err := mark(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Status — все известные бинарю миграции и их состояние в базе.
+// Только читает, без advisory lock: его зовут /readyz и проверка при старте,
+// они не должны ждать идущий migrate up. Нет schema_migrations — не применено ничего
+// (таблицу и отметки legacy-базы заводит Up).
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var exists bool
+	if err := m.DB.QueryRowContext(ctx,
+		`SELECT to_regclass('schema_migrations') IS NOT NULL`,
+	).Scan(&exists); err != nil {
+		return nil, err
+	}
+	have := map[int]applied{}
+	if exists {
+		var err error
+		if have, err = m.applied(ctx, m.DB); err != nil {
+			return nil, err
+		}
+	}
+	out := make([]Status, 0, len(m.Migrations))
+	for _, mg := range m.Migrations {
+		st := Status{Migration: mg}
+		if a, ok := have[mg.Version]; ok {
+			at := a.AppliedAt
+			st.AppliedAt = &at
+			st.Modified = a.Checksum != mg.Checksum
+		}
+		out = append(out, st)
+	}
+	return out, nil
+}
+
+// ErrBehind — в базе применены не все миграции бинаря.
+var ErrBehind = errors.New("схема БД отстаёт: выполните learny migrate up")
+
+// Check проверяет, что схема актуальна: все миграции применены и не менялись.
+// Как и Status, ничего не пишет и не блокирует.
+// Миграции новее бинаря (база уже обновлена более свежей версией) не ошибка.
+func (m *Migrator) Check(ctx context.Context) error {
+	sts, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	var pending int
+	for _, st := range sts {
+		if st.AppliedAt == nil {
+			pending++
+			continue
+		}
+		if st.Modified {
+			return fmt.Errorf("migrate: %03d_%s изменена после применения (checksum не совпадает)", st.Version, st.Name)
+		}
+	}
+	if pending > 0 {
+		return fmt.Errorf("%w (не применено миграций: %d)", ErrBehind, pending)
+	}
+	return nil
+}
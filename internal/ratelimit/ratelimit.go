@@ -0,0 +1,154 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Имена правил; они же префиксы ключей в хранилище.
+const (
+	Login     = "login"      // неудачные входы (пароль или код 2FA) с одного IP
+	Account   = "account"    // неудачные входы в один аккаунт; при превышении — блокировка на Window
+	Register  = "register"   // регистрации с одного IP
+	Reset     = "reset"      // запросы сброса пароля с одного IP
+	QuizStart = "quiz_start" // старты квизов одним пользователем
+)
+
+// Rule — не больше Limit событий за окно Window (фиксированное окно).
+type Rule struct {
+	Limit  int
+	Window time.Duration
+}
+
+func (r Rule) String() string { return fmt.Sprintf("%d/%s", r.Limit, r.Window) }
+
+// Rules — правила по именам.
+type Rules map[string]Rule
+
+func DefaultRules() Rules {
+	return Rules{
+		Login:     {Limit: 5, Window: 15 * time.Minute},
+		Account:   {Limit: 10, Window: time.Hour},
+		Register:  {Limit: 10, Window: time.Hour},
+		Reset:     {Limit: 5, Window: time.Hour},
+		QuizStart: {Limit: 30, Window: time.Hour},
+	}
+}
+
+// Set переопределяет правила из строки вида "login=5/15m,register=20/1h".
+func (rs Rules) Set(spec string) error {
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, val, ok := strings.Cut(part, "=")
+		if !ok {
+			return fmt.Errorf("rate limit %q: ожидается имя=число/окно", part)
+		}
+		name = strings.TrimSpace(name)
+		if _, known := rs[name]; !known {
+			return fmt.Errorf("rate limit: неизвестное правило %q", name)
+		}
+		n, w, ok := strings.Cut(val, "/")
+		if !ok {
+			return fmt.Errorf("rate limit %q: ожидается число/окно, например 5/15m", part)
+		}
+		limit, err := strconv.Atoi(strings.TrimSpace(n))
+		if err != nil || limit <= 0 {
+			return fmt.Errorf("rate limit %q: лимит должен быть положительным числом", part)
+		}
+		window, err := time.ParseDuration(strings.TrimSpace(w))
+		if err != nil || window <= 0 {
+			return fmt.Errorf("rate limit %q: неверное окно", part)
+		}
+		rs[name] = Rule{Limit: limit, Window: window}
+	}
+	return nil
+}
+
+// Store — где живут счётчики. Реализация в Postgres — repo.Repo,
+// поэтому счётчики общие для всех реплик и переживают рестарт.
+type Store interface {
+	// RateHit увеличивает счётчик ключа (открывая новое окно, если старое истекло)
+	// и возвращает новое значение и конец окна.
+	RateHit(ctx context.Context, key string, window time.Duration) (int, time.Time, error)
+	// RatePeek — текущее значение без увеличения (0, если окно истекло).
+	RatePeek(ctx context.Context, key string) (int, time.Time, error)
+	RateReset(ctx context.Context, key string) error
+}
+
+// Decision — результат проверки.
+type Decision struct {
+	Allowed    bool
+	Remaining  int           // сколько ещё событий допустимо в окне
+	RetryAfter time.Duration // когда окно закончится (если не Allowed)
+}
+
+type Limiter struct {
+	Store Store
+	Rules Rules
+}
+
+func New(store Store, rules Rules) *Limiter {
+	return &Limiter{Store: store, Rules: rules}
+}
+
+func key(rule, k string) string { return rule + ":" + k }
+
+func (l *Limiter) decide(rule Rule, count int, until time.Time, counted bool) Decision {
+	d := Decision{Remaining: rule.Limit - count}
+	if counted {
+		d.Allowed = count <= rule.Limit
+	} else {
+		d.Allowed = count < rule.Limit
+	}
+	if !d.Allowed {
+		d.RetryAfter = time.Until(until).Round(time.Second)
+	}
+	return d
+}
+
+// Allow учитывает событие и говорит, укладывается ли оно в лимит.
+// Неизвестное правило ничего не ограничивает.
+func (l *Limiter) Allow(ctx context.Context, name, k string) (Decision, error) {
+	rule, ok := l.Rules[name]
+	if !ok {
+		return Decision{Allowed: true}, nil
+	}
+	n, until, err := l.Store.RateHit(ctx, key(name, k), rule.Window)
+	if err != nil {
+		return Decision{}, err
+	}
+	return l.decide(rule, n, until, true), nil
+}
+
+// Check — можно ли ещё одно событие, не учитывая его
+// (для лимитов, где считаются только неудачи).
+func (l *Limiter) Check(ctx context.Context, name, k string) (Decision, error) {
+	rule, ok := l.Rules[name]
+	if !ok {
+		return Decision{Allowed: true}, nil
+	}
+	n, until, err := l.Store.RatePeek(ctx, key(name, k))
+	if err != nil {
+		return Decision{}, err
+	}
+	return l.decide(rule, n, until, false), nil
+}
+
+func (l *Limiter) Reset(ctx context.Context, name, k string) error {
+	return l.Store.RateReset(ctx, key(name, k))
+}
+
+// Minutes — RetryAfter в минутах для сообщений пользователю (не меньше 1).
+func (d Decision) Minutes() int {
+	m := int((d.RetryAfter + time.Minute - 1) / time.Minute)
+	if m < 1 {
+		m = 1
+	}
+	return m
+}
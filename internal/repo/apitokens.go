@@ -0,0 +1,110 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+/*** персональные API-токены ***/
+
+type APITokenRow struct {
+	ID         int64
+	UserID     int64
+	Name       string
+	Scopes     []string
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+	ExpiresAt  *time.Time // nil — бессрочный
+}
+
+// HasScope — разрешено ли токену действие. Право X:write включает X:read:
+// токен, которым стартуют попытки, может и прочитать их.
+func (t *APITokenRow) HasScope(scope string) bool {
+	write := ""
+	if res, ok := strings.CutSuffix(scope, ":read"); ok {
+		write = res + ":write"
+	}
+	for _, s := range t.Scopes {
+		if s == scope || write != "" && s == write {
+			return true
+		}
+	}
+	return false
+}
+
+var ErrAPITokenNotFound = errors.New("токен не найден")
+
+func (r *Repo) CreateAPIToken(ctx context.Context, t *APITokenRow, tokenHash string) error {
+	return r.DB.QueryRowContext(ctx, `
+		INSERT INTO api_tokens(user_id, name, token_hash, scopes, expires_at)
+		VALUES ($1,$2,$3,$4,$5)
+		RETURNING id, created_at
+	`, t.UserID, t.Name, tokenHash, pq.Array(t.Scopes), t.ExpiresAt,
+	).Scan(&t.ID, &t.CreatedAt)
+}
+
+// UseAPIToken находит действующий токен по хэшу и отмечает использование.
+func (r *Repo) UseAPIToken(ctx context.Context, tokenHash string) (*APITokenRow, error) {
+	var t APITokenRow
+	err := r.DB.QueryRowContext(ctx, `
+		UPDATE api_tokens SET last_used_at=now()
+		WHERE token_hash=$1 AND (expires_at IS NULL OR expires_at > now())
+		RETURNING id, user_id, name, scopes, created_at, last_used_at, expires_at
+	`, tokenHash).Scan(&t.ID, &t.UserID, &t.Name, pq.Array(&t.Scopes), &t.CreatedAt, &t.LastUsedAt, &t.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAPITokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *Repo) ListAPITokens(ctx context.Context, userID int64) ([]APITokenRow, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT id, user_id, name, scopes, created_at, last_used_at, expires_at
+		FROM api_tokens
+		WHERE user_id=$1
+		ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []APITokenRow
+	for rows.Next() {
+		var t APITokenRow
+		if err := rows.Scan(&t.ID, &t.UserID, &t.Name, pq.Array(&t.Scopes), &t.CreatedAt, &t.LastUsedAt, &t.ExpiresAt); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+// DeleteAPIToken отзывает токен; чужой токен удалить нельзя.
+func (r *Repo) DeleteAPIToken(ctx context.Context, userID, tokenID int64) error {
+	res, err := r.DB.ExecContext(ctx, `DELETE FROM api_tokens WHERE id=$1 AND user_id=$2`, tokenID, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrAPITokenNotFound
+	}
+	return nil
+}
+
+// DeleteUserAPITokens отзывает все токены пользователя.
+func (r *Repo) DeleteUserAPITokens(ctx context.Context, userID int64) (int64, error) {
+	res, err := r.DB.ExecContext(ctx, `DELETE FROM api_tokens WHERE user_id=$1`, userID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,23 @@
+package repo
+
+import "testing"
+
+func TestHasScope(t *testing.T) {
+	tok := &APITokenRow{Scopes: []string{"attempts:write", "courses:read"}}
+	cases := []struct {
+		scope string
+		want  bool
+	}{
+		{"attempts:write", true},
+		{"attempts:read", true}, // write включает read
+		{"courses:read", true},
+		{"courses:write", false}, // read не включает write
+		{"results:read", false},
+		{":read", false},
+	}
+	for _, c := range cases {
+		if got := tok.HasScope(c.scope); got != c.want {
+			t.Errorf("HasScope(%q) = %v, want %v", c.scope, got, c.want)
+		}
+	}
+}
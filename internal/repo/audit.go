@@ -0,0 +1,227 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"learny/internal/logx"
+)
+
+/*** журнал административных действий ***/
+
+// Типы сущностей в audit_events.
+const (
+	EntityUser     = "user"
+	EntityCourse   = "course"
+	EntityQuiz     = "quiz"
+	EntityQuestion = "question"
+	EntityAttempt  = "attempt"
+)
+
+// AuditEntityTypes — для фильтра на /admin/audit.
+var AuditEntityTypes = []string{EntityUser, EntityCourse, EntityQuiz, EntityQuestion, EntityAttempt}
+
+// Change — значение поля до и после; nil — поля не было (создание, удаление).
+type Change struct {
+	Old any `json:"old"`
+	New any `json:"new"`
+}
+
+// Diff — изменённые поля сущности.
+type Diff map[string]Change
+
+// set добавляет поле, если значение действительно изменилось.
+func (d Diff) set(field string, old, new any) {
+	if !sameJSON(old, new) {
+		d[field] = Change{Old: old, New: new}
+	}
+}
+
+// sameJSON сравнивает значения по их JSON: ключи объектов сортируются,
+// поэтому jsonb из БД и введённый админом JSON с другим порядком равны.
+func sameJSON(a, b any) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(ja, jb)
+}
+
+// jsonValue разбирает сырой JSON (правила квиза, payload вопроса), чтобы в diff
+// он лёг объектом, а не строкой; пустой — nil.
+func jsonValue(raw []byte) any {
+	if len(raw) == 0 {
+		return nil
+	}
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return string(raw)
+	}
+	return v
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+type actorKey struct{}
+
+// WithActor — подпись автора действий вне HTTP-запроса (например, "learnyctl (root)").
+// В запросе автор — вошедший пользователь.
+func WithActor(ctx context.Context, label string) context.Context {
+	return context.WithValue(ctx, actorKey{}, label)
+}
+
+// audit пишет событие журнала. Вызывается в той же транзакции, что и само
+// изменение: без записи в журнал изменение не фиксируется.
+// Автор, IP и request id берутся из контекста запроса.
+func audit(ctx context.Context, ex execer, action, entityType string, entityID int64, diff Diff) error {
+	var (
+		actorID   sql.NullInt64
+		ip, reqID string
+	)
+	label, _ := ctx.Value(actorKey{}).(string)
+	if q := logx.FromContext(ctx); q != nil {
+		if uid := q.UserID(); uid != 0 {
+			actorID = sql.NullInt64{Int64: uid, Valid: true}
+		}
+		ip, reqID = q.IP, q.ID
+	}
+	if diff == nil {
+		diff = Diff{}
+	}
+	raw, err := json.Marshal(diff)
+	if err != nil {
+		return err
+	}
+	_, err = ex.ExecContext(ctx, `
+		INSERT INTO audit_events(actor_id, actor, action, entity_type, entity_id, diff, ip, request_id)
+		VALUES ($1, COALESCE((SELECT email FROM users WHERE id=$1), $2), $3, $4, $5, $6, $7, $8)
+	`, actorID, label, action, entityType, entityID, raw, ip, reqID)
+	return err
+}
+
+// inTx выполняет fn в транзакции: изменение и его запись в журнале
+// фиксируются вместе.
+// ErrNotFound — изменяемого объекта нет (удалён или не существовал).
+var ErrNotFound = userError("error.not_found")
+
+func (r *Repo) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+/*** просмотр ***/
+
+type AuditEvent struct {
+	ID         int64
+	CreatedAt  time.Time
+	ActorID    *int64
+	Actor      string
+	Action     string
+	EntityType string
+	EntityID   *int64
+	Diff       json.RawMessage
+	IP         string
+	RequestID  string
+}
+
+// AuditFilter — фильтр журнала; пустые поля не ограничивают выборку.
+type AuditFilter struct {
+	Actor      string    // подстрока email или подписи автора
+	EntityType string    // EntityUser, EntityCourse, ...
+	From, To   time.Time // [From, To)
+	Limit      int
+	Offset     int
+}
+
+// ListAuditEvents — события журнала, новые сверху, и общее число подходящих
+// под фильтр. Limit <= 0 — без ограничения (выгрузка).
+func (r *Repo) ListAuditEvents(ctx context.Context, f AuditFilter) ([]AuditEvent, int, error) {
+	var args []interface{}
+	where := []string{"TRUE"}
+
+	if f.Actor != "" {
+		args = append(args, "%"+f.Actor+"%")
+		where = append(where, "actor ILIKE $"+strconv.Itoa(len(args)))
+	}
+	if f.EntityType != "" {
+		args = append(args, f.EntityType)
+		where = append(where, "entity_type = $"+strconv.Itoa(len(args)))
+	}
+	if !f.From.IsZero() {
+		args = append(args, f.From)
+		where = append(where, "created_at >= $"+strconv.Itoa(len(args)))
+	}
+	if !f.To.IsZero() {
+		args = append(args, f.To)
+		where = append(where, "created_at < $"+strconv.Itoa(len(args)))
+	}
+	limit := "ALL"
+	if f.Limit > 0 {
+		args = append(args, f.Limit)
+		limit = "$" + strconv.Itoa(len(args))
+	}
+	args = append(args, f.Offset)
+
+	query := `
+		SELECT id, created_at, actor_id, actor, action, entity_type, entity_id, diff, ip, request_id, COUNT(*) OVER()
+		FROM audit_events
+		WHERE ` + strings.Join(where, " AND ") + `
+		ORDER BY created_at DESC, id DESC
+		LIMIT ` + limit + ` OFFSET $` + strconv.Itoa(len(args)) + `
+	`
+
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var out []AuditEvent
+	total := 0
+	for rows.Next() {
+		var e AuditEvent
+		if err := rows.Scan(&e.ID, &e.CreatedAt, &e.ActorID, &e.Actor, &e.Action, &e.EntityType,
+			&e.EntityID, &e.Diff, &e.IP, &e.RequestID, &total); err != nil {
+			return nil, 0, err
+		}
+		out = append(out, e)
+	}
+	return out, total, rows.Err()
+}
+
+// AuditCSVHeader — столбцы выгрузки журнала (/admin/audit/export).
+var AuditCSVHeader = []string{"id", "created_at", "actor_id", "actor", "action", "entity_type", "entity_id", "diff", "ip", "request_id"}
+
+// AuditCSVRecord — строка выгрузки журнала.
+func AuditCSVRecord(e AuditEvent) []string {
+	optID := func(p *int64) string {
+		if p == nil {
+			return ""
+		}
+		return strconv.FormatInt(*p, 10)
+	}
+	return []string{
+		strconv.FormatInt(e.ID, 10),
+		e.CreatedAt.Format(time.RFC3339),
+		optID(e.ActorID),
+		e.Actor,
+		e.Action,
+		e.EntityType,
+		optID(e.EntityID),
+		string(e.Diff),
+		e.IP,
+		e.RequestID,
+	}
+}
@@ -0,0 +1,120 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*** выгрузка: банк вопросов и попытки в форматах импорта/экспорта веб-интерфейса ***/
+
+// QuestionBankItem — элемент JSON-банка вопросов (формат ImportQuestionsJSON).
+type QuestionBankItem struct {
+	Topic      string          `json:"topic"`
+	QType      string          `json:"qtype"`
+	Difficulty int             `json:"difficulty"`
+	Payload    json.RawMessage `json:"payload_json"`
+}
+
+// ExportQuestions — все вопросы курса по порядку id.
+func (r *Repo) ExportQuestions(ctx context.Context, courseID int64) ([]QuestionRow, error) {
+	const pageSize = 500
+	var out []QuestionRow
+	for {
+		page, total, err := r.ListQuestionsPage(ctx, courseID, "", "", pageSize, len(out))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, page...)
+		if len(page) == 0 || len(out) >= total {
+			return out, nil
+		}
+	}
+}
+
+// BankItem — вопрос в формате JSON-банка.
+func BankItem(q QuestionRow) QuestionBankItem {
+	return QuestionBankItem{Topic: q.Topic, QType: q.QType, Difficulty: q.Difficulty, Payload: q.Payload}
+}
+
+// QuestionCSVRecord — вопрос строкой CSV в формате ImportQuestionsCSV:
+// topic;qtype;text;choices;correct;difficulty без заголовка,
+// варианты и верные ответы — через запятую.
+// Варианты с запятой в CSV не представимы — такой вопрос выгружайте в JSON.
+func QuestionCSVRecord(q QuestionRow) ([]string, error) {
+	var p struct {
+		Text         string   `json:"text"`
+		Choices      []string `json:"choices"`
+		Correct      []int    `json:"correct"`
+		CorrectValue float64  `json:"correct_value"`
+		Accept       []string `json:"accept"`
+	}
+	if err := json.Unmarshal(q.Payload, &p); err != nil {
+		return nil, fmt.Errorf("вопрос %d: %w", q.ID, err)
+	}
+	joinList := func(what string, items []string) (string, error) {
+		for _, it := range items {
+			if strings.Contains(it, ",") {
+				return "", fmt.Errorf("вопрос %d: %s %q содержит запятую, в CSV не выгрузить", q.ID, what, it)
+			}
+		}
+		return strings.Join(items, ","), nil
+	}
+
+	var choices, correct string
+	var err error
+	switch q.QType {
+	case "single", "multiple":
+		if choices, err = joinList("вариант", p.Choices); err != nil {
+			return nil, err
+		}
+		idx := make([]string, len(p.Correct))
+		for i, c := range p.Correct {
+			idx[i] = strconv.Itoa(c)
+		}
+		correct = strings.Join(idx, ",")
+	case "numeric":
+		correct = strconv.FormatFloat(p.CorrectValue, 'f', -1, 64)
+	case "text":
+		if correct, err = joinList("ответ", p.Accept); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("вопрос %d: unsupported qtype: %s", q.ID, q.QType)
+	}
+	return []string{q.Topic, q.QType, p.Text, choices, correct, strconv.Itoa(q.Difficulty)}, nil
+}
+
+// AttemptCSVHeader — столбцы выгрузки результатов (/admin/results/export).
+var AttemptCSVHeader = []string{"attempt_id", "user_email", "course_id", "quiz_id", "quiz_title", "started_at", "finished_at", "score", "duration_sec", "overtime"}
+
+// AttemptCSVRecord — строка выгрузки результатов.
+func AttemptCSVRecord(r0 AttemptExportRow) []string {
+	finished := ""
+	if r0.FinishedAt != nil {
+		finished = r0.FinishedAt.Format(time.RFC3339)
+	}
+	score := ""
+	if r0.Score != nil {
+		score = strconv.FormatFloat(*r0.Score, 'f', -1, 64)
+	}
+	dur := ""
+	if r0.Duration != nil {
+		dur = strconv.Itoa(*r0.Duration)
+	}
+	return []string{
+		strconv.FormatInt(r0.AttemptID, 10),
+		r0.UserEmail,
+		strconv.FormatInt(r0.CourseID, 10),
+		strconv.FormatInt(r0.QuizID, 10),
+		r0.QuizTitle,
+		r0.StartedAt.Format(time.RFC3339),
+		finished,
+		score,
+		dur,
+		strconv.FormatBool(r0.Overtime),
+	}
+}
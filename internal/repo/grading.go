@@ -0,0 +1,338 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+/*** оценка ответов ***/
+
+// GradeAnswer проверяет ответ на вопрос по payload и возвращает
+// признак правильности и JSON ответа для сохранения в answers.
+// Пустой ответ (вопрос не заполнен) всегда считается неверным.
+func GradeAnswer(qtype string, payload json.RawMessage, rawVals []string) (bool, []byte) {
+	if strings.TrimSpace(strings.Join(rawVals, "")) == "" {
+		ansJSON, _ := json.Marshal(map[string]any{"type": qtype, "skipped": true})
+		return false, ansJSON
+	}
+
+	var ok bool
+	var ansJSON []byte
+	switch qtype {
+	case "single":
+		var p struct {
+			Text    string
+			Choices []string
+			Correct []int
+		}
+		_ = json.Unmarshal(payload, &p)
+		chosenIdx, _ := strconv.Atoi(firstOrEmpty(rawVals))
+		ok = len(p.Correct) > 0 && chosenIdx == p.Correct[0]
+		ansJSON, _ = json.Marshal(map[string]any{"type": "single", "chosen": chosenIdx})
+
+	case "multiple":
+		var p struct {
+			Text    string
+			Choices []string
+			Correct []int
+		}
+		_ = json.Unmarshal(payload, &p)
+		var chosen []int
+		for _, sv := range rawVals {
+			if i, err := strconv.Atoi(sv); err == nil {
+				chosen = append(chosen, i)
+			}
+		}
+		ok = setEq(intSliceToSet(chosen), intSliceToSet(p.Correct))
+		ansJSON, _ = json.Marshal(map[string]any{"type": "multiple", "chosen": chosen})
+
+	case "numeric":
+		var p struct {
+			Text         string
+			CorrectValue float64 `json:"correct_value"`
+		}
+		_ = json.Unmarshal(payload, &p)
+		val, err := strconv.ParseFloat(strings.ReplaceAll(firstOrEmpty(rawVals), ",", "."), 64)
+		ok = err == nil && abs(val-p.CorrectValue) < 1e-9
+		ansJSON, _ = json.Marshal(map[string]any{"type": "numeric", "value": val})
+
+	case "text":
+		var p struct {
+			Text   string
+			Accept []string
+		}
+		_ = json.Unmarshal(payload, &p)
+		ans := strings.TrimSpace(firstOrEmpty(rawVals))
+		ok = containsFold(p.Accept, ans)
+		ansJSON, _ = json.Marshal(map[string]any{"type": "text", "value": ans})
+	}
+	return ok, ansJSON
+}
+
+// AnswerValues приводит ответ из JSON к виду значений формы:
+// 2 → ["2"], "TCP" → ["TCP"], [0, 2] → ["0", "2"].
+func AnswerValues(raw json.RawMessage) ([]string, error) {
+	var list []json.RawMessage
+	if err := json.Unmarshal(raw, &list); err != nil {
+		list = []json.RawMessage{raw}
+	}
+	out := make([]string, 0, len(list))
+	for _, item := range list {
+		var v any
+		if err := json.Unmarshal(item, &v); err != nil {
+			return nil, err
+		}
+		switch x := v.(type) {
+		case string:
+			out = append(out, x)
+		case float64:
+			out = append(out, strconv.FormatFloat(x, 'f', -1, 64))
+		case nil:
+		default:
+			return nil, userError("answer.invalid_value")
+		}
+	}
+	return out, nil
+}
+
+// storedAnswerValues — значения формы из сохранённого GradeAnswer JSON
+// ({"chosen": 1}, {"value": "TCP"}, {"skipped": true}), чтобы оценить его заново.
+func storedAnswerValues(answer []byte) []string {
+	var a struct {
+		Chosen json.RawMessage `json:"chosen"`
+		Value  json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(answer, &a); err != nil {
+		return nil
+	}
+	raw := a.Chosen
+	if len(raw) == 0 {
+		raw = a.Value
+	}
+	if len(raw) == 0 {
+		return nil
+	}
+	vals, _ := AnswerValues(raw)
+	return vals
+}
+
+// LateScore — балл с учётом политики опоздания.
+func (q *QuizRules) LateScore(score float64, overtime bool) float64 {
+	if !overtime {
+		return score
+	}
+	switch q.LatePolicy {
+	case LateReject, LateZero:
+		return 0
+	case LatePenalty:
+		return score * (100 - q.LatePenaltyPct) / 100
+	}
+	return score
+}
+
+/*** пересчёт баллов ***/
+
+// Rescore — попытка, балл которой изменился при пересчёте.
+type Rescore struct {
+	AttemptID int64
+	UserEmail string
+	Old       *float64
+	New       float64
+}
+
+// RecalcScores заново оценивает сохранённые ответы сданных попыток той же
+// GradeAnswer и теми же правилами опоздания, что и при сдаче. Вопросы берутся
+// из снапшота попытки; refresh — сначала обновить снапшот текущими вопросами
+// (например, после исправления ключа). dryRun — только посчитать.
+func (r *Repo) RecalcScores(ctx context.Context, courseID, quizID *int64, refresh, dryRun bool) ([]Rescore, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	type attempt struct {
+		Rescore
+		overtime bool
+		rules    []byte
+	}
+	rows, err := tx.QueryContext(ctx, `
+		SELECT a.id, u.email, a.total_score, COALESCE(a.overtime, false), qz.rules
+		FROM attempts a
+		JOIN users   u  ON u.id  = a.user_id
+		JOIN quizzes qz ON qz.id = a.quiz_id
+		WHERE a.status = $1
+		  AND ($2::bigint IS NULL OR qz.course_id = $2)
+		  AND ($3::bigint IS NULL OR a.quiz_id = $3)
+		ORDER BY a.id
+		FOR UPDATE OF a
+	`, AttemptSubmitted, courseID, quizID)
+	if err != nil {
+		return nil, err
+	}
+	var list []attempt
+	for rows.Next() {
+		var at attempt
+		if err := rows.Scan(&at.AttemptID, &at.UserEmail, &at.Old, &at.overtime, &at.rules); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		list = append(list, at)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if refresh && len(list) > 0 {
+		ids := make([]int64, len(list))
+		for i, at := range list {
+			ids[i] = at.AttemptID
+		}
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE attempt_questions aq
+			   SET payload_json = q.payload_json, qtype = q.qtype, topic = q.topic, difficulty = q.difficulty
+			  FROM questions q
+			 WHERE q.id = aq.question_id AND aq.attempt_id = ANY($1)
+		`, pq.Array(ids)); err != nil {
+			return nil, err
+		}
+	}
+
+	var out []Rescore
+	for _, at := range list {
+		var rules QuizRules
+		_ = json.Unmarshal(at.rules, &rules)
+
+		correct, err := regradeAttempt(ctx, tx, at.AttemptID)
+		if err != nil {
+			return nil, err
+		}
+		score := rules.LateScore(float64(correct), at.overtime)
+		if at.Old != nil && *at.Old == score {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE attempts SET total_score=$2 WHERE id=$1`, at.AttemptID, score,
+		); err != nil {
+			return nil, err
+		}
+		if err := audit(ctx, tx, "attempt.rescore", EntityAttempt, at.AttemptID,
+			Diff{"total_score": {Old: at.Old, New: score}}); err != nil {
+			return nil, err
+		}
+		at.New = score
+		out = append(out, at.Rescore)
+	}
+
+	if dryRun {
+		return out, nil
+	}
+	return out, tx.Commit()
+}
+
+// regradeAttempt оценивает ответы попытки заново, обновляет is_correct
+// и возвращает число верных.
+func regradeAttempt(ctx context.Context, tx *sql.Tx, attemptID int64) (int, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT an.id, COALESCE(aq.qtype, q.qtype), COALESCE(aq.payload_json, q.payload_json),
+		       COALESCE(an.answer, '{}'::jsonb), an.is_correct
+		FROM answers an
+		JOIN questions q ON q.id = an.question_id
+		LEFT JOIN attempt_questions aq
+		       ON aq.attempt_id = an.attempt_id AND aq.question_id = an.question_id
+		WHERE an.attempt_id = $1
+	`, attemptID)
+	if err != nil {
+		return 0, err
+	}
+	type answer struct {
+		id      int64
+		ok      bool
+		changed bool
+	}
+	var list []answer
+	for rows.Next() {
+		var (
+			id           int64
+			qtype        string
+			payload, ans []byte
+			wasCorrect   sql.NullBool
+		)
+		if err := rows.Scan(&id, &qtype, &payload, &ans, &wasCorrect); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ok, _ := GradeAnswer(qtype, payload, storedAnswerValues(ans))
+		list = append(list, answer{id, ok, !wasCorrect.Valid || wasCorrect.Bool != ok})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	correct := 0
+	for _, a := range list {
+		if a.ok {
+			correct++
+		}
+		if !a.changed {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE answers SET is_correct=$2 WHERE id=$1`, a.id, a.ok); err != nil {
+			return 0, err
+		}
+	}
+	return correct, nil
+}
+
+/*** helpers ***/
+
+func firstOrEmpty(a []string) string {
+	if len(a) > 0 {
+		return a[0]
+	}
+	return ""
+}
+
+func intSliceToSet(a []int) map[int]struct{} {
+	m := map[int]struct{}{}
+	for _, v := range a {
+		m[v] = struct{}{}
+	}
+	return m
+}
+
+func setEq(a, b map[int]struct{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k := range a {
+		if _, ok := b[k]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func abs(x float64) float64 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func containsFold(hay []string, needle string) bool {
+	n := strings.ToLower(strings.TrimSpace(needle))
+	for _, v := range hay {
+		if strings.ToLower(strings.TrimSpace(v)) == n {
+			return true
+		}
+	}
+	return false
+}
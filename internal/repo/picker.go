@@ -0,0 +1,425 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math/rand/v2"
+	"sort"
+
+	"learny/internal/i18n"
+)
+
+/*** подбор вопросов по правилам квиза ***/
+
+// Политики на случай, когда банк не может выполнить правила.
+const (
+	FallbackStrict  = "strict"  // ошибка (по умолчанию)
+	FallbackFill    = "fill"    // добираем недостающее любыми вопросами курса
+	FallbackPartial = "partial" // выдаём сколько есть
+)
+
+var qtypes = []string{"single", "multiple", "numeric", "text"}
+
+// TopicQuotas — правило by_topics. Принимает два формата:
+//
+//	["Подсети", "Маршрутизация"]          — только ограничение по темам;
+//	{"Подсети": 3, "Маршрутизация": 2}    — квоты (или веса, см. topic_weights).
+//
+// В первом случае значения в карте нулевые.
+type TopicQuotas map[string]int
+
+func (t *TopicQuotas) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if bytes.Equal(b, []byte("null")) {
+		*t = nil
+		return nil
+	}
+	if len(b) > 0 && b[0] == '[' {
+		var list []string
+		if err := json.Unmarshal(b, &list); err != nil {
+			return err
+		}
+		m := TopicQuotas{}
+		for _, s := range list {
+			m[s] = 0
+		}
+		*t = m
+		return nil
+	}
+	var m map[string]int
+	if err := json.Unmarshal(b, &m); err != nil {
+		return userError("rules.by_topics_format")
+	}
+	*t = m
+	return nil
+}
+
+// HasQuotas — заданы ли количества (или веса) по темам, а не просто список.
+func (t TopicQuotas) HasQuotas() bool {
+	for _, n := range t {
+		if n > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (t TopicQuotas) sum() int {
+	n := 0
+	for _, v := range t {
+		n += v
+	}
+	return n
+}
+
+// Topics — отсортированный список тем.
+func (t TopicQuotas) Topics() []string {
+	out := make([]string, 0, len(t))
+	for k := range t {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// NotEnoughQuestionsError — банк не может выполнить правила квиза.
+type NotEnoughQuestionsError struct {
+	QType string // нехватка по типу
+	Topic string // нехватка по теме
+	Want  int
+	Have  int
+}
+
+func (e *NotEnoughQuestionsError) Error() string {
+	key, args := e.Message()
+	return i18n.Default(key, args...)
+}
+
+func (e *NotEnoughQuestionsError) Message() (string, []any) {
+	switch {
+	case e.Topic != "":
+		return "bank.short_topic", []any{e.Topic, e.Want, e.Have}
+	case e.QType != "":
+		return "bank.short_type", []any{e.QType, e.Want, e.Have}
+	}
+	return "bank.short", []any{e.Want, e.Have}
+}
+
+// typeCounts возвращает требуемое количество по типам (только ненулевые).
+func (q *QuizRules) typeCounts() map[string]int {
+	m := map[string]int{}
+	for t, n := range map[string]int{
+		"single":   q.CountSingle,
+		"multiple": q.CountMultiple,
+		"numeric":  q.CountNumeric,
+		"text":     q.CountText,
+	} {
+		if n > 0 {
+			m[t] = n
+		}
+	}
+	return m
+}
+
+// Total — итоговый размер квиза: count; если он не задан — сумма квот
+// по темам; если и их нет — сумма по типам.
+func (q *QuizRules) Total() int {
+	if q.Count > 0 {
+		return q.Count
+	}
+	if q.ByTopics.HasQuotas() && !q.TopicWeights {
+		return q.ByTopics.sum()
+	}
+	n := 0
+	for _, v := range q.typeCounts() {
+		n += v
+	}
+	return n
+}
+
+// topicQuotas раскладывает total по темам: квоты как есть,
+// веса — пропорционально (метод наибольшего остатка).
+func (q *QuizRules) topicQuotas(total int) map[string]int {
+	if !q.ByTopics.HasQuotas() {
+		return nil
+	}
+	if !q.TopicWeights {
+		return q.ByTopics
+	}
+
+	sumW := q.ByTopics.sum()
+	out := map[string]int{}
+	type rem struct {
+		topic string
+		frac  int
+	}
+	var rems []rem
+	given := 0
+	for _, t := range q.ByTopics.Topics() {
+		w := q.ByTopics[t]
+		out[t] = total * w / sumW
+		given += out[t]
+		rems = append(rems, rem{t, total * w % sumW})
+	}
+	sort.SliceStable(rems, func(i, j int) bool { return rems[i].frac > rems[j].frac })
+	for i := 0; given < total; i++ {
+		out[rems[i%len(rems)].topic]++
+		given++
+	}
+	return out
+}
+
+func (q *QuizRules) inDifficulty(d int) bool {
+	if q.MinDifficulty > 0 && d < q.MinDifficulty {
+		return false
+	}
+	if q.MaxDifficulty > 0 && d > q.MaxDifficulty {
+		return false
+	}
+	return true
+}
+
+// PickQuestions подбирает вопросы курса по правилам квиза:
+// темы, количество по типам, диапазон сложности и политика нехватки.
+func (r *Repo) PickQuestions(ctx context.Context, courseID int64, rules *QuizRules) (_ []QuestionRow, err error) {
+	defer func() { err = traced(ctx, "PickQuestions", err) }()
+
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT id, course_id, topic, qtype, difficulty, payload_json
+		FROM questions
+		WHERE course_id = $1
+	`, courseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var bank []QuestionRow
+	for rows.Next() {
+		var qr QuestionRow
+		if err := rows.Scan(&qr.ID, &qr.CourseID, &qr.Topic, &qr.QType, &qr.Difficulty, &qr.Payload); err != nil {
+			return nil, err
+		}
+		bank = append(bank, qr)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return SelectQuestions(bank, rules)
+}
+
+// SelectQuestions — сам движок подбора, работает по уже загруженному банку.
+//
+// Ограничения по темам и по типам пересекаются, поэтому раскладка
+// считается как поток: источник → тема (квота) → тип (сколько есть
+// в банке) → сток (count_* по типу, остаток — через «любой тип»).
+func SelectQuestions(bank []QuestionRow, rules *QuizRules) ([]QuestionRow, error) {
+	total := rules.Total()
+	if total <= 0 {
+		total = 10
+	}
+
+	// кандидаты: диапазон сложности + разрешённые темы
+	var pool []QuestionRow
+	for _, q := range bank {
+		if !rules.inDifficulty(q.Difficulty) {
+			continue
+		}
+		if len(rules.ByTopics) > 0 {
+			if _, ok := rules.ByTopics[q.Topic]; !ok {
+				continue
+			}
+		}
+		pool = append(pool, q)
+	}
+	rand.Shuffle(len(pool), func(i, j int) { pool[i], pool[j] = pool[j], pool[i] })
+
+	// группы по темам: либо квоты, либо одна общая группа на весь пул
+	const anyTopic = ""
+	quotas := rules.topicQuotas(total)
+	byTopic := quotas != nil
+	if !byTopic {
+		quotas = map[string]int{anyTopic: total}
+	}
+	groupOf := func(q QuestionRow) string {
+		if !byTopic {
+			return anyTopic
+		}
+		return q.Topic
+	}
+	topics := make([]string, 0, len(quotas))
+	for t := range quotas {
+		topics = append(topics, t)
+	}
+	sort.Strings(topics)
+
+	avail := map[string]map[string][]QuestionRow{} // тема -> тип -> вопросы
+	for _, q := range pool {
+		g := groupOf(q)
+		if _, ok := quotas[g]; !ok {
+			continue
+		}
+		if avail[g] == nil {
+			avail[g] = map[string][]QuestionRow{}
+		}
+		avail[g][q.QType] = append(avail[g][q.QType], q)
+	}
+
+	counts := rules.typeCounts()
+	byTypes := 0
+	for _, n := range counts {
+		byTypes += n
+	}
+
+	// узлы: 0 — источник, 1..T — темы, затем типы, «любой тип», сток
+	nT, nK := len(topics), len(qtypes)
+	src, free, sink := 0, 1+nT+nK, 2+nT+nK
+	typeNode := func(k int) int { return 1 + nT + k }
+	f := newFlow(sink + 1)
+	for i, t := range topics {
+		f.add(src, 1+i, quotas[t])
+		for k, qt := range qtypes {
+			f.add(1+i, typeNode(k), len(avail[t][qt]))
+		}
+	}
+	for k, qt := range qtypes {
+		f.add(typeNode(k), sink, counts[qt])
+		f.add(typeNode(k), free, total)
+	}
+	if rest := total - byTypes; rest > 0 {
+		f.add(free, sink, rest)
+	}
+	f.max(src, sink)
+
+	used := map[int64]bool{}
+	var out []QuestionRow
+	take := func(from []QuestionRow, n int) {
+		for _, q := range from {
+			if n == 0 {
+				return
+			}
+			if used[q.ID] {
+				continue
+			}
+			used[q.ID] = true
+			out = append(out, q)
+			n--
+		}
+	}
+	for i, t := range topics {
+		for k, qt := range qtypes {
+			take(avail[t][qt], f.flow(1+i, typeNode(k)))
+		}
+	}
+
+	if len(out) < total {
+		shortage := diagnoseShortage(topics, quotas, avail, counts, total, len(out))
+		switch rules.Fallback {
+		case FallbackFill:
+			// сначала любые подходящие по темам/сложности, потом вообще любые вопросы курса
+			take(pool, total-len(out))
+			if rest := total - len(out); rest > 0 {
+				all := append([]QuestionRow(nil), bank...)
+				rand.Shuffle(len(all), func(i, j int) { all[i], all[j] = all[j], all[i] })
+				take(all, rest)
+			}
+			if len(out) < total {
+				return nil, &NotEnoughQuestionsError{Want: total, Have: len(out)}
+			}
+		case FallbackPartial:
+			if len(out) == 0 {
+				return nil, shortage
+			}
+		default:
+			return nil, shortage
+		}
+	}
+
+	rand.Shuffle(len(out), func(i, j int) { out[i], out[j] = out[j], out[i] })
+	return out, nil
+}
+
+// diagnoseShortage подбирает понятную причину нехватки: тема, тип или их сочетание.
+func diagnoseShortage(topics []string, quotas map[string]int, avail map[string]map[string][]QuestionRow, counts map[string]int, total, got int) *NotEnoughQuestionsError {
+	byType := map[string]int{}
+	for _, t := range topics {
+		have := 0
+		for qt, qs := range avail[t] {
+			have += len(qs)
+			byType[qt] += len(qs)
+		}
+		if t != "" && have < quotas[t] {
+			return &NotEnoughQuestionsError{Topic: t, Want: quotas[t], Have: have}
+		}
+	}
+	for _, qt := range qtypes {
+		if want := counts[qt]; want > byType[qt] {
+			return &NotEnoughQuestionsError{QType: qt, Want: want, Have: byType[qt]}
+		}
+	}
+	return &NotEnoughQuestionsError{Want: total, Have: got}
+}
+
+// flowNet — минимальная сеть для подбора (узлов единицы, хватает матрицы и BFS).
+type flowNet struct {
+	cap, used [][]int
+}
+
+func newFlow(n int) *flowNet {
+	f := &flowNet{cap: make([][]int, n), used: make([][]int, n)}
+	for i := range f.cap {
+		f.cap[i] = make([]int, n)
+		f.used[i] = make([]int, n)
+	}
+	return f
+}
+
+func (f *flowNet) add(from, to, c int) { f.cap[from][to] += c }
+
+func (f *flowNet) flow(from, to int) int { return f.used[from][to] }
+
+func (f *flowNet) residual(u, v int) int { return f.cap[u][v] - f.used[u][v] + f.used[v][u] }
+
+// max — Эдмондс–Карп.
+func (f *flowNet) max(s, t int) int {
+	n := len(f.cap)
+	total := 0
+	for {
+		prev := make([]int, n)
+		for i := range prev {
+			prev[i] = -1
+		}
+		prev[s] = s
+		queue := []int{s}
+		for len(queue) > 0 && prev[t] == -1 {
+			u := queue[0]
+			queue = queue[1:]
+			for v := 0; v < n; v++ {
+				if prev[v] == -1 && f.residual(u, v) > 0 {
+					prev[v] = u
+					queue = append(queue, v)
+				}
+			}
+		}
+		if prev[t] == -1 {
+			return total
+		}
+
+		push := -1
+		for v := t; v != s; v = prev[v] {
+			if r := f.residual(prev[v], v); push == -1 || r < push {
+				push = r
+			}
+		}
+		for v := t; v != s; v = prev[v] {
+			u := prev[v]
+			// сначала отменяем встречный поток, остаток — по прямому ребру
+			back := min(push, f.used[v][u])
+			f.used[v][u] -= back
+			f.used[u][v] += push - back
+		}
+		total += push
+	}
+}
@@ -0,0 +1,386 @@
+package repo
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// seed — банк вопросов: по n штук каждой комбинации тема/тип/сложность.
+type seed struct {
+	topic, qtype string
+	difficulty   int
+	n            int
+}
+
+func seedBank(seeds ...seed) []QuestionRow {
+	var bank []QuestionRow
+	for _, s := range seeds {
+		for i := 0; i < s.n; i++ {
+			bank = append(bank, QuestionRow{
+				ID:         int64(len(bank) + 1),
+				CourseID:   1,
+				Topic:      s.topic,
+				QType:      s.qtype,
+				Difficulty: s.difficulty,
+			})
+		}
+	}
+	return bank
+}
+
+// tally — сколько выдано вопросов по ключу (тема, тип или тема/тип);
+// заодно проверяет, что ни один вопрос не выдан дважды.
+func tally(t *testing.T, qs []QuestionRow, key func(QuestionRow) string) map[string]int {
+	t.Helper()
+	seen := map[int64]bool{}
+	out := map[string]int{}
+	for _, q := range qs {
+		if seen[q.ID] {
+			t.Fatalf("вопрос %d выдан дважды", q.ID)
+		}
+		seen[q.ID] = true
+		out[key(q)]++
+	}
+	return out
+}
+
+func byType(q QuestionRow) string      { return q.QType }
+func byTopic(q QuestionRow) string     { return q.Topic }
+func byTopicType(q QuestionRow) string { return q.Topic + "/" + q.QType }
+
+func TestSelectQuestionsTypeCounts(t *testing.T) {
+	bank := seedBank(
+		seed{"a", "single", 2, 5},
+		seed{"a", "multiple", 2, 5},
+		seed{"a", "numeric", 2, 5},
+		seed{"a", "text", 2, 5},
+	)
+	tests := []struct {
+		name  string
+		rules QuizRules
+		total int
+		want  map[string]int // минимум по типу
+	}{
+		{"только по типам", QuizRules{CountSingle: 2, CountMultiple: 1, CountNumeric: 1}, 4,
+			map[string]int{"single": 2, "multiple": 1, "numeric": 1}},
+		{"count больше суммы по типам", QuizRules{Count: 7, CountText: 3, CountSingle: 1}, 7,
+			map[string]int{"text": 3, "single": 1}},
+		{"всё из банка одного типа", QuizRules{CountNumeric: 5}, 5,
+			map[string]int{"numeric": 5}},
+		{"без count — 10 по умолчанию", QuizRules{}, 10, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SelectQuestions(bank, &tt.rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != tt.total {
+				t.Fatalf("выдано %d, want %d", len(got), tt.total)
+			}
+			types := tally(t, got, byType)
+			for qt, n := range tt.want {
+				if types[qt] < n {
+					t.Errorf("%s: %d, want ≥ %d (%v)", qt, types[qt], n, types)
+				}
+			}
+		})
+	}
+}
+
+func TestSelectQuestionsDifficulty(t *testing.T) {
+	bank := seedBank(
+		seed{"a", "single", 1, 4},
+		seed{"a", "single", 2, 4},
+		seed{"a", "single", 3, 4},
+		seed{"a", "single", 4, 4},
+		seed{"a", "single", 5, 4},
+	)
+	tests := []struct {
+		name     string
+		min, max int
+		count    int
+	}{
+		{"диапазон", 2, 3, 8},
+		{"только снизу", 4, 0, 8},
+		{"только сверху", 0, 1, 4},
+		{"одна сложность", 5, 5, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := &QuizRules{Count: tt.count, MinDifficulty: tt.min, MaxDifficulty: tt.max}
+			got, err := SelectQuestions(bank, rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != tt.count {
+				t.Fatalf("выдано %d, want %d", len(got), tt.count)
+			}
+			for _, q := range got {
+				if !rules.inDifficulty(q.Difficulty) {
+					t.Errorf("вопрос %d сложности %d вне [%d, %d]", q.ID, q.Difficulty, tt.min, tt.max)
+				}
+			}
+		})
+	}
+}
+
+func TestSelectQuestionsFallback(t *testing.T) {
+	// в диапазоне 1..2 всего 3 вопроса, ещё 4 — сложнее
+	bank := seedBank(
+		seed{"a", "single", 1, 3},
+		seed{"a", "single", 5, 4},
+	)
+	tests := []struct {
+		fallback string
+		count    int
+		want     int // 0 — ошибка
+	}{
+		{FallbackStrict, 5, 0},
+		{"", 5, 0}, // strict по умолчанию
+		{FallbackPartial, 5, 3},
+		{FallbackFill, 5, 5},
+		{FallbackFill, 7, 7},
+		{FallbackFill, 8, 0}, // во всём курсе только 7
+		{FallbackStrict, 3, 3},
+	}
+	for _, tt := range tests {
+		rules := &QuizRules{Count: tt.count, MaxDifficulty: 2, Fallback: tt.fallback}
+		got, err := SelectQuestions(bank, rules)
+		if tt.want == 0 {
+			var short *NotEnoughQuestionsError
+			if !errors.As(err, &short) {
+				t.Errorf("%q count=%d: err = %v, want NotEnoughQuestionsError", tt.fallback, tt.count, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q count=%d: %v", tt.fallback, tt.count, err)
+			continue
+		}
+		if len(got) != tt.want {
+			t.Errorf("%q count=%d: выдано %d, want %d", tt.fallback, tt.count, len(got), tt.want)
+		}
+		tally(t, got, byType)
+		if tt.fallback == FallbackFill && tt.count == 5 {
+			// добор идёт только когда подходящие кончились: все три лёгких на месте
+			easy := 0
+			for _, q := range got {
+				if q.Difficulty == 1 {
+					easy++
+				}
+			}
+			if easy != 3 {
+				t.Errorf("fill: лёгких %d, want 3", easy)
+			}
+		}
+	}
+}
+
+func TestSelectQuestionsPartialEmpty(t *testing.T) {
+	bank := seedBank(seed{"a", "single", 5, 3})
+	_, err := SelectQuestions(bank, &QuizRules{Count: 2, MaxDifficulty: 2, Fallback: FallbackPartial})
+	var short *NotEnoughQuestionsError
+	if !errors.As(err, &short) {
+		t.Fatalf("err = %v, want NotEnoughQuestionsError: partial без единого вопроса — ошибка", err)
+	}
+}
+
+// Темы и типы пересекаются: жадный подбор по темам взял бы в «a» одиночные
+// и не нашёл бы текстовых в «b». Поток обязан отдать «a» её текстовые вопросы.
+func TestSelectQuestionsTopicTypeOverlap(t *testing.T) {
+	bank := seedBank(
+		seed{"a", "single", 1, 2},
+		seed{"a", "text", 1, 2},
+		seed{"b", "single", 1, 2},
+	)
+	rules := &QuizRules{
+		ByTopics:    TopicQuotas{"a": 2, "b": 2},
+		CountSingle: 2,
+		CountText:   2,
+	}
+	for i := 0; i < 20; i++ { // банк перемешивается — проверяем не один прогон
+		got, err := SelectQuestions(bank, rules)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := map[string]int{"a/text": 2, "b/single": 2}
+		if c := tally(t, got, byTopicType); !reflect.DeepEqual(c, want) {
+			t.Fatalf("раскладка %v, want %v", c, want)
+		}
+	}
+}
+
+func TestSelectQuestionsTopicQuotas(t *testing.T) {
+	bank := seedBank(
+		seed{"a", "single", 1, 5},
+		seed{"b", "multiple", 1, 5},
+		seed{"c", "numeric", 1, 5},
+	)
+	tests := []struct {
+		name  string
+		rules QuizRules
+		want  map[string]int
+	}{
+		{"квоты", QuizRules{ByTopics: TopicQuotas{"a": 3, "b": 1}},
+			map[string]int{"a": 3, "b": 1}},
+		{"веса", QuizRules{Count: 6, TopicWeights: true, ByTopics: TopicQuotas{"a": 2, "c": 1}},
+			map[string]int{"a": 4, "c": 2}},
+		{"квоты вместе с типом", QuizRules{ByTopics: TopicQuotas{"a": 2, "b": 2}, CountMultiple: 2},
+			map[string]int{"a": 2, "b": 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SelectQuestions(bank, &tt.rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c := tally(t, got, byTopic); !reflect.DeepEqual(c, tt.want) {
+				t.Errorf("по темам %v, want %v", c, tt.want)
+			}
+		})
+	}
+
+	// список тем без квот — только фильтр
+	got, err := SelectQuestions(bank, &QuizRules{Count: 8, ByTopics: TopicQuotas{"b": 0, "c": 0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := tally(t, got, byTopic); c["a"] != 0 || c["b"]+c["c"] != 8 {
+		t.Errorf("список тем: %v", c)
+	}
+}
+
+func TestSelectQuestionsShortage(t *testing.T) {
+	bank := seedBank(
+		seed{"a", "single", 1, 2},
+		seed{"b", "single", 1, 5},
+		seed{"b", "numeric", 1, 1},
+		seed{"b", "text", 1, 1},
+	)
+	tests := []struct {
+		name  string
+		rules QuizRules
+		want  NotEnoughQuestionsError
+		key   string
+	}{
+		{"не хватает темы", QuizRules{ByTopics: TopicQuotas{"a": 3, "b": 1}},
+			NotEnoughQuestionsError{Topic: "a", Want: 3, Have: 2}, "bank.short_topic"},
+		{"не хватает типа", QuizRules{CountNumeric: 3},
+			NotEnoughQuestionsError{QType: "numeric", Want: 3, Have: 1}, "bank.short_type"},
+		{"не хватает всего", QuizRules{Count: 20},
+			NotEnoughQuestionsError{Want: 20, Have: 9}, "bank.short"},
+		// numeric и text есть только в «b», а у неё место на один вопрос
+		{"темы и типы по отдельности есть, вместе нет", QuizRules{ByTopics: TopicQuotas{"a": 1, "b": 1}, CountNumeric: 1, CountText: 1},
+			NotEnoughQuestionsError{Want: 2, Have: 1}, "bank.short"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := SelectQuestions(bank, &tt.rules)
+			var short *NotEnoughQuestionsError
+			if !errors.As(err, &short) {
+				t.Fatalf("err = %v, want NotEnoughQuestionsError", err)
+			}
+			if *short != tt.want {
+				t.Errorf("got %+v, want %+v", *short, tt.want)
+			}
+			if key, _ := short.Message(); key != tt.key {
+				t.Errorf("ключ %q, want %q", key, tt.key)
+			}
+		})
+	}
+}
+
+func TestTopicQuotasUnmarshal(t *testing.T) {
+	tests := []struct {
+		in         string
+		want       TopicQuotas
+		hasQuotas  bool
+		wantErrKey string
+	}{
+		{`["Подсети", "Маршрутизация"]`, TopicQuotas{"Подсети": 0, "Маршрутизация": 0}, false, ""},
+		{`{"Подсети": 3, "Маршрутизация": 2}`, TopicQuotas{"Подсети": 3, "Маршрутизация": 2}, true, ""},
+		{` [ "a" ] `, TopicQuotas{"a": 0}, false, ""},
+		{`[]`, TopicQuotas{}, false, ""},
+		{`null`, nil, false, ""},
+		{`"Подсети"`, nil, false, "rules.by_topics_format"},
+		{`{"a": "три"}`, nil, false, "rules.by_topics_format"},
+	}
+	for _, tt := range tests {
+		var got TopicQuotas
+		err := json.Unmarshal([]byte(tt.in), &got)
+		if tt.wantErrKey != "" {
+			var ue *Error
+			if !errors.As(err, &ue) || ue.Key != tt.wantErrKey {
+				t.Errorf("%s: err = %v, want ключ %s", tt.in, err, tt.wantErrKey)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: %v, want %v", tt.in, got, tt.want)
+		}
+		if got.HasQuotas() != tt.hasQuotas {
+			t.Errorf("%s: HasQuotas = %v", tt.in, got.HasQuotas())
+		}
+	}
+	// оба формата — через разбор правил целиком, с валидацией
+	for _, raw := range []string{
+		`{"count": 3, "by_topics": ["a", "b"]}`,
+		`{"by_topics": {"a": 2, "b": 1}}`,
+	} {
+		if _, err := ParseQuizRules([]byte(raw)); err != nil {
+			t.Errorf("%s: %v", raw, err)
+		}
+	}
+}
+
+func TestTopicQuotasWeights(t *testing.T) {
+	tests := []struct {
+		name    string
+		total   int
+		weights TopicQuotas
+		want    map[string]int
+	}{
+		{"делится ровно", 6, TopicQuotas{"a": 1, "b": 2}, map[string]int{"a": 2, "b": 4}},
+		{"наибольший остаток", 5, TopicQuotas{"a": 3, "b": 1}, map[string]int{"a": 4, "b": 1}},
+		{"больший остаток у меньшего веса", 3, TopicQuotas{"a": 2, "b": 2, "c": 1}, map[string]int{"a": 1, "b": 1, "c": 1}},
+		{"ничья — по алфавиту", 10, TopicQuotas{"c": 1, "b": 1, "a": 1}, map[string]int{"a": 4, "b": 3, "c": 3}},
+		{"ничья на две темы", 5, TopicQuotas{"a": 1, "b": 1, "c": 1}, map[string]int{"a": 2, "b": 2, "c": 1}},
+		{"меньше, чем тем", 2, TopicQuotas{"a": 1, "b": 1, "c": 1}, map[string]int{"a": 1, "b": 1, "c": 0}},
+		{"большие веса", 7, TopicQuotas{"a": 50, "b": 30, "c": 20}, map[string]int{"a": 4, "b": 2, "c": 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := &QuizRules{Count: tt.total, TopicWeights: true, ByTopics: tt.weights}
+			got := rules.topicQuotas(tt.total)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			sum := 0
+			for _, n := range got {
+				sum += n
+			}
+			if sum != tt.total {
+				t.Errorf("сумма %d, want %d", sum, tt.total)
+			}
+		})
+	}
+
+	// без topic_weights — квоты как есть, а Total — их сумма
+	rules := &QuizRules{ByTopics: TopicQuotas{"a": 3, "b": 2}}
+	if got := rules.topicQuotas(rules.Total()); !reflect.DeepEqual(got, map[string]int{"a": 3, "b": 2}) {
+		t.Errorf("квоты: %v", got)
+	}
+	if rules.Total() != 5 {
+		t.Errorf("Total = %d, want 5", rules.Total())
+	}
+	if got := (&QuizRules{ByTopics: TopicQuotas{"a": 0}}).topicQuotas(5); got != nil {
+		t.Errorf("список тем: %v, want nil", got)
+	}
+}
@@ -17,12 +17,24 @@ import (
 /*** users ***/
 
 type UserRow struct {
-	ID       int64
-	Email    string
-	PassHash string
-	Role     string
+	ID          int64
+	Email       string
+	PassHash    string
+	Role        string
+	VerifiedAt  *time.Time // nil — email не подтверждён
+	TOTPAt      *time.Time // nil — 2FA не включена
+	LockedUntil *time.Time // блокировка после неудачных входов
 }
 
+// Verified — подтверждён ли email.
+func (u UserRow) Verified() bool { return u.VerifiedAt != nil }
+
+// TwoFactor — включена ли TOTP 2FA.
+func (u UserRow) TwoFactor() bool { return u.TOTPAt != nil }
+
+// Locked — действует ли сейчас блокировка входа.
+func (u UserRow) Locked() bool { return u.LockedUntil != nil && u.LockedUntil.After(time.Now()) }
+
 func (r *Repo) CreateUser(ctx context.Context, email, passHash string) (int64, error) {
 	row := r.DB.QueryRowContext(ctx,
 		`INSERT INTO users(email, pass_hash, role_id)
@@ -34,27 +46,58 @@ func (r *Repo) CreateUser(ctx context.Context, email, passHash string) (int64, e
 	return id, row.Scan(&id)
 }
 
+// CreateAdmin заводит подтверждённого администратора одной транзакцией:
+// учётка не может остаться студентом или неподтверждённой на полпути.
+func (r *Repo) CreateAdmin(ctx context.Context, email, passHash string) (id int64, err error) {
+	err = r.inTx(ctx, func(tx *sql.Tx) error {
+		var at time.Time
+		if err := tx.QueryRowContext(ctx,
+			`INSERT INTO users(email, pass_hash, role_id, email_verified_at)
+             VALUES ($1, $2, (SELECT id FROM roles WHERE name = 'admin'), now())
+             RETURNING id, email_verified_at`,
+			email, passHash,
+		).Scan(&id, &at); err != nil {
+			return err
+		}
+		return audit(ctx, tx, "user.create", EntityUser, id, Diff{
+			"email":             {Old: nil, New: email},
+			"role":              {Old: nil, New: "admin"},
+			"email_verified_at": {Old: nil, New: at},
+		})
+	})
+	return id, err
+}
+
 func (r *Repo) FindUserByEmail(ctx context.Context, email string) (*UserRow, error) {
 	row := r.DB.QueryRowContext(ctx,
-		`SELECT u.id, u.email, u.pass_hash, r.name AS role
+		`SELECT u.id, u.email, u.pass_hash, r.name AS role, u.email_verified_at, u.totp_enabled_at, u.locked_until
          FROM users u
          JOIN roles r ON r.id = u.role_id
          WHERE u.email = $1`,
 		email,
 	)
 	var u UserRow
-	if err := row.Scan(&u.ID, &u.Email, &u.PassHash, &u.Role); err != nil {
+	if err := row.Scan(&u.ID, &u.Email, &u.PassHash, &u.Role, &u.VerifiedAt, &u.TOTPAt, &u.LockedUntil); err != nil {
 		return nil, err
 	}
 	return &u, nil
 }
 
+// UpdateUserPass меняет хеш пароля; в журнал попадает только факт смены.
 func (r *Repo) UpdateUserPass(ctx context.Context, userID int64, newHash string) error {
-	_, err := r.DB.ExecContext(ctx,
-		`UPDATE users SET pass_hash=$2 WHERE id=$1`,
-		userID, newHash,
-	)
-	return err
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx,
+			`UPDATE users SET pass_hash=$2 WHERE id=$1`,
+			userID, newHash,
+		)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return nil
+		}
+		return audit(ctx, tx, "user.password", EntityUser, userID, nil)
+	})
 }
 
 func (r *Repo) GetUserRole(ctx context.Context, userID int64) (string, error) {
@@ -69,9 +112,72 @@ func (r *Repo) GetUserRole(ctx context.Context, userID int64) (string, error) {
 	return role, err
 }
 
+// IsUserVerified — подтвердил ли пользователь email.
+func (r *Repo) IsUserVerified(ctx context.Context, userID int64) (bool, error) {
+	var ok bool
+	err := r.DB.QueryRowContext(ctx,
+		`SELECT email_verified_at IS NOT NULL FROM users WHERE id=$1`,
+		userID,
+	).Scan(&ok)
+	return ok, err
+}
+
+// GetUserLang — язык интерфейса, выбранный пользователем; "" — не выбран.
+func (r *Repo) GetUserLang(ctx context.Context, userID int64) (string, error) {
+	var lang sql.NullString
+	err := r.DB.QueryRowContext(ctx, `SELECT lang FROM users WHERE id=$1`, userID).Scan(&lang)
+	return lang.String, err
+}
+
+// SetUserLang сохраняет язык интерфейса; "" — снова по Accept-Language.
+// Личная настройка, в журнал действий не попадает.
+func (r *Repo) SetUserLang(ctx context.Context, userID int64, lang string) error {
+	_, err := r.DB.ExecContext(ctx, `UPDATE users SET lang=NULLIF($2, '') WHERE id=$1`, userID, lang)
+	return err
+}
+
+// SetUserVerified отмечает email подтверждённым (например, вручную из админки).
+func (r *Repo) SetUserVerified(ctx context.Context, userID int64) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		var old *time.Time
+		err := tx.QueryRowContext(ctx,
+			`SELECT email_verified_at FROM users WHERE id=$1 FOR UPDATE`, userID,
+		).Scan(&old)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil || old != nil {
+			return err
+		}
+		var at time.Time
+		if err := tx.QueryRowContext(ctx,
+			`UPDATE users SET email_verified_at = now() WHERE id=$1 RETURNING email_verified_at`,
+			userID,
+		).Scan(&at); err != nil {
+			return err
+		}
+		return audit(ctx, tx, "user.verify", EntityUser, userID, Diff{"email_verified_at": {Old: nil, New: at}})
+	})
+}
+
+func (r *Repo) GetUser(ctx context.Context, userID int64) (*UserRow, error) {
+	var u UserRow
+	err := r.DB.QueryRowContext(ctx,
+		`SELECT u.id, u.email, u.pass_hash, r.name AS role, u.email_verified_at, u.totp_enabled_at, u.locked_until
+         FROM users u
+         JOIN roles r ON r.id = u.role_id
+         WHERE u.id = $1`,
+		userID,
+	).Scan(&u.ID, &u.Email, &u.PassHash, &u.Role, &u.VerifiedAt, &u.TOTPAt, &u.LockedUntil)
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
 func (r *Repo) ListUsers(ctx context.Context) ([]UserRow, error) {
 	rows, err := r.DB.QueryContext(ctx,
-		`SELECT u.id, u.email, u.pass_hash, r.name AS role
+		`SELECT u.id, u.email, u.pass_hash, r.name AS role, u.email_verified_at, u.totp_enabled_at, u.locked_until
          FROM users u
          JOIN roles r ON r.id = u.role_id
          ORDER BY u.id`,
@@ -84,7 +190,7 @@ func (r *Repo) ListUsers(ctx context.Context) ([]UserRow, error) {
 	var out []UserRow
 	for rows.Next() {
 		var u UserRow
-		if err := rows.Scan(&u.ID, &u.Email, &u.PassHash, &u.Role); err != nil {
+		if err := rows.Scan(&u.ID, &u.Email, &u.PassHash, &u.Role, &u.VerifiedAt, &u.TOTPAt, &u.LockedUntil); err != nil {
 			return nil, err
 		}
 		out = append(out, u)
@@ -99,13 +205,29 @@ func (r *Repo) UpdateUserRole(ctx context.Context, userID int64, role string) er
 		return fmt.Errorf("invalid role")
 	}
 
-	_, err := r.DB.ExecContext(ctx,
-		`UPDATE users
-         SET role_id = (SELECT id FROM roles WHERE name = $2)
-         WHERE id = $1`,
-		userID, role,
-	)
-	return err
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		var old string
+		err := tx.QueryRowContext(ctx,
+			`SELECT r.name FROM users u JOIN roles r ON r.id = u.role_id
+             WHERE u.id = $1 FOR UPDATE OF u`,
+			userID,
+		).Scan(&old)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil || old == role {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE users
+             SET role_id = (SELECT id FROM roles WHERE name = $2)
+             WHERE id = $1`,
+			userID, role,
+		); err != nil {
+			return err
+		}
+		return audit(ctx, tx, "user.role", EntityUser, userID, Diff{"role": {Old: old, New: role}})
+	})
 }
 
 /*** courses ***/
@@ -136,30 +258,88 @@ func (r *Repo) ListCourses(ctx context.Context) ([]CourseRow, error) {
 	return out, rows.Err()
 }
 
-func (r *Repo) CreateCourse(ctx context.Context, title, description string) error {
-	_, err := r.DB.ExecContext(ctx,
-		`INSERT INTO courses(title, description) VALUES ($1,$2)`,
-		title, description,
-	)
-	return err
+func (r *Repo) CreateCourse(ctx context.Context, title, description string) (int64, error) {
+	var id int64
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		if err := tx.QueryRowContext(ctx,
+			`INSERT INTO courses(title, description) VALUES ($1,$2) RETURNING id`,
+			title, description,
+		).Scan(&id); err != nil {
+			return err
+		}
+		return audit(ctx, tx, "course.create", EntityCourse, id, Diff{
+			"title":       {New: title},
+			"description": {New: description},
+		})
+	})
+	return id, err
+}
+
+// GetCourse — курс по id; sql.ErrNoRows, если его нет.
+func (r *Repo) GetCourse(ctx context.Context, id int64) (*CourseRow, error) {
+	var c CourseRow
+	var desc sql.NullString
+	err := r.DB.QueryRowContext(ctx,
+		`SELECT id, title, description FROM courses WHERE id=$1`,
+		id,
+	).Scan(&c.ID, &c.Title, &desc)
+	if err != nil {
+		return nil, err
+	}
+	c.Description = desc.String
+	return &c, nil
 }
 
 func (r *Repo) UpdateCourse(ctx context.Context, id int64, title, description string) error {
-	_, err := r.DB.ExecContext(ctx, `
-		UPDATE courses
-		   SET title       = COALESCE(NULLIF($2,''), title),
-		       description = COALESCE(NULLIF($3,''), description)
-		 WHERE id=$1
-	`, id, title, description)
-	return err
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		var old CourseRow
+		err := tx.QueryRowContext(ctx,
+			`SELECT title, COALESCE(description,'') FROM courses WHERE id=$1 FOR UPDATE`, id,
+		).Scan(&old.Title, &old.Description)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		var cur CourseRow
+		if err := tx.QueryRowContext(ctx, `
+			UPDATE courses
+			   SET title       = COALESCE(NULLIF($2,''), title),
+			       description = COALESCE(NULLIF($3,''), description)
+			 WHERE id=$1
+			RETURNING title, COALESCE(description,'')
+		`, id, title, description).Scan(&cur.Title, &cur.Description); err != nil {
+			return err
+		}
+		diff := Diff{}
+		diff.set("title", old.Title, cur.Title)
+		diff.set("description", old.Description, cur.Description)
+		if len(diff) == 0 {
+			return nil
+		}
+		return audit(ctx, tx, "course.update", EntityCourse, id, diff)
+	})
 }
 
 func (r *Repo) DeleteCourse(ctx context.Context, id int64) error {
-	_, err := r.DB.ExecContext(ctx,
-		`DELETE FROM courses WHERE id=$1`,
-		id,
-	)
-	return err
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		var old CourseRow
+		err := tx.QueryRowContext(ctx,
+			`DELETE FROM courses WHERE id=$1 RETURNING title, COALESCE(description,'')`,
+			id,
+		).Scan(&old.Title, &old.Description)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		return audit(ctx, tx, "course.delete", EntityCourse, id, Diff{
+			"title":       {Old: old.Title},
+			"description": {Old: old.Description},
+		})
+	})
 }
 
 /*** quizzes & questions ***/
@@ -171,8 +351,11 @@ func (r *Repo) DeleteCourse(ctx context.Context, id int64) error {
 // так и новый (count_* + min/max_difficulty).
 type QuizRules struct {
 	// старые поля
-	Count    int      `json:"count"`      // если >0 — общее количество вопросов
-	ByTopics []string `json:"by_topics"`  // пока не используется
+	Count    int         `json:"count"`     // если >0 — общее количество вопросов
+	ByTopics TopicQuotas `json:"by_topics"` // список тем или {тема: количество}
+
+	// значения by_topics — веса, а не количества (делим общий count пропорционально)
+	TopicWeights bool `json:"topic_weights"`
 
 	// общие ограничения
 	TimeLimitSec      int `json:"time_limit_sec"`      // 0 = без ограничения
@@ -188,42 +371,99 @@ type QuizRules struct {
 	// новый формат: сложности
 	MinDifficulty int `json:"min_difficulty"`
 	MaxDifficulty int `json:"max_difficulty"`
+
+	// что делать, если банк не может выполнить правила: strict | fill | partial
+	Fallback string `json:"fallback"`
+
+	// опоздание: grace_sec — допуск сверх time_limit_sec,
+	// late_policy — что делать после допуска: accept | reject | zero | penalty
+	GraceSec       int     `json:"grace_sec"`
+	LatePolicy     string  `json:"late_policy"`
+	LatePenaltyPct float64 `json:"late_penalty_pct"` // для penalty: сколько % балла снимать
 }
 
+// Политики для ответов, пришедших позже time_limit_sec + grace_sec.
+const (
+	LateAccept  = "accept"  // принять, только пометить overtime (по умолчанию)
+	LateReject  = "reject"  // ответы не принимаются, балл 0
+	LateZero    = "zero"    // ответы сохраняются, балл 0
+	LatePenalty = "penalty" // балл снижается на late_penalty_pct процентов
+)
+
 // Validate проверяет, что правила не бредовые.
 // ВАЖНО: не запрещаем 0 в полях, это значит "нет ограничения".
 func (q *QuizRules) Validate() error {
 	if q.TimeLimitSec < 0 {
-		return errors.New("time_limit_sec не может быть отрицательным")
+		return userError("rules.time_limit_negative")
 	}
 	if q.MaxAttempts < 0 {
-		return errors.New("max_attempts не может быть отрицательным")
+		return userError("rules.max_attempts_negative")
 	}
 	if q.RetakeCooldownSec < 0 {
-		return errors.New("retake_cooldown_sec не может быть отрицательным")
+		return userError("rules.cooldown_negative")
 	}
 
 	if q.Count < 0 {
-		return errors.New("count не может быть отрицательным")
+		return userError("rules.count_negative")
 	}
 	if q.CountSingle < 0 || q.CountMultiple < 0 || q.CountNumeric < 0 || q.CountText < 0 {
-		return errors.New("количество вопросов по типам не может быть отрицательным")
+		return userError("rules.type_count_negative")
 	}
 
-	// должен быть хотя бы какой-то положительный итоговый размер
-	total := q.Count
-	if total == 0 {
-		total = q.CountSingle + q.CountMultiple + q.CountNumeric + q.CountText
+	for topic, n := range q.ByTopics {
+		if strings.TrimSpace(topic) == "" {
+			return userError("rules.topic_empty")
+		}
+		if n < 0 {
+			return userError("rules.topic_count_negative")
+		}
+		if n == 0 && q.ByTopics.HasQuotas() {
+			return userError("rules.topic_count_zero")
+		}
+	}
+	if q.TopicWeights && !q.ByTopics.HasQuotas() {
+		return userError("rules.weights_need_quotas")
 	}
+
+	// должен быть хотя бы какой-то положительный итоговый размер
+	total := q.Total()
 	if total <= 0 {
-		return errors.New("нужно указать общее количество вопросов (count) или суммы по типам > 0")
+		return userError("rules.total_required")
+	}
+	if byTypes := q.CountSingle + q.CountMultiple + q.CountNumeric + q.CountText; total < byTypes {
+		return userError("rules.count_below_types")
+	}
+	if q.ByTopics.HasQuotas() && !q.TopicWeights && q.ByTopics.sum() != total {
+		return userError("rules.quotas_sum")
 	}
 
 	if q.MinDifficulty < 0 || q.MaxDifficulty < 0 {
-		return errors.New("min_difficulty и max_difficulty не могут быть отрицательными")
+		return userError("rules.difficulty_negative")
 	}
 	if q.MinDifficulty > 0 && q.MaxDifficulty > 0 && q.MinDifficulty > q.MaxDifficulty {
-		return errors.New("min_difficulty не может быть больше max_difficulty")
+		return userError("rules.difficulty_range")
+	}
+
+	switch q.Fallback {
+	case "", FallbackStrict, FallbackFill, FallbackPartial:
+	default:
+		return userError("rules.fallback")
+	}
+
+	if q.GraceSec < 0 {
+		return userError("rules.grace_negative")
+	}
+	switch q.LatePolicy {
+	case "", LateAccept, LateReject, LateZero:
+	case LatePenalty:
+		if q.LatePenaltyPct <= 0 || q.LatePenaltyPct > 100 {
+			return userError("rules.penalty_pct")
+		}
+	default:
+		return userError("rules.late_policy")
+	}
+	if q.LatePolicy != "" && q.LatePolicy != LateAccept && q.TimeLimitSec == 0 {
+		return userError("rules.late_needs_limit")
 	}
 
 	return nil
@@ -231,16 +471,53 @@ func (q *QuizRules) Validate() error {
 
 
 type QuizRow struct {
-	ID    int64
-	Title string
-	Rules []byte
+	ID       int64
+	CourseID int64
+	Title    string
+	Rules    []byte
 }
 
-func (r *Repo) LoadQuizRules(ctx context.Context, quizID int64) (*QuizRules, string, error) {
+// ParseQuizRules разбирает и проверяет JSON правил. Неизвестные ключи —
+// ошибка, чтобы ловить опечатки (unknown field ...).
+func ParseQuizRules(rulesRaw []byte) (*QuizRules, error) {
+	if len(rulesRaw) == 0 {
+		return nil, userError("rules.empty")
+	}
+
+	var rules QuizRules
+	dec := json.NewDecoder(bytes.NewReader(rulesRaw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&rules); err != nil {
+		return nil, userError("rules.invalid_json", err)
+	}
+
+	// Бизнес-валидация значений
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+	return &rules, nil
+}
+
+// GetQuiz — квиз по id; sql.ErrNoRows, если его нет.
+func (r *Repo) GetQuiz(ctx context.Context, quizID int64) (*QuizRow, error) {
+	var q QuizRow
+	err := r.DB.QueryRowContext(ctx,
+		`SELECT id, course_id, title, rules FROM quizzes WHERE id=$1`,
+		quizID,
+	).Scan(&q.ID, &q.CourseID, &q.Title, &q.Rules)
+	if err != nil {
+		return nil, err
+	}
+	return &q, nil
+}
+
+func (r *Repo) LoadQuizRules(ctx context.Context, quizID int64) (_ *QuizRules, _ string, err error) {
+	defer func() { err = traced(ctx, "LoadQuizRules", err) }()
+
 	var rulesRaw []byte
 	var title string
 
-	err := r.DB.QueryRowContext(ctx,
+	err = r.DB.QueryRowContext(ctx,
 		`SELECT rules, title FROM quizzes WHERE id=$1`, quizID,
 	).Scan(&rulesRaw, &title)
 	if err != nil {
@@ -253,7 +530,7 @@ func (r *Repo) LoadQuizRules(ctx context.Context, quizID int64) (*QuizRules, str
 			return nil, "", err
 		}
 	}
-	if q.Count == 0 {
+	if q.Total() == 0 {
 		q.Count = 10
 	}
 	return &q, title, nil
@@ -261,7 +538,7 @@ func (r *Repo) LoadQuizRules(ctx context.Context, quizID int64) (*QuizRules, str
 
 func (r *Repo) ListQuizzesByCourse(ctx context.Context, courseID int64) ([]QuizRow, error) {
 	rows, err := r.DB.QueryContext(ctx,
-		`SELECT id, title, rules FROM quizzes WHERE course_id=$1 ORDER BY id`,
+		`SELECT id, course_id, title, rules FROM quizzes WHERE course_id=$1 ORDER BY id`,
 		courseID,
 	)
 	if err != nil {
@@ -272,7 +549,7 @@ func (r *Repo) ListQuizzesByCourse(ctx context.Context, courseID int64) ([]QuizR
 	var out []QuizRow
 	for rows.Next() {
 		var q QuizRow
-		if err := rows.Scan(&q.ID, &q.Title, &q.Rules); err != nil {
+		if err := rows.Scan(&q.ID, &q.CourseID, &q.Title, &q.Rules); err != nil {
 			return nil, err
 		}
 		out = append(out, q)
@@ -280,42 +557,99 @@ func (r *Repo) ListQuizzesByCourse(ctx context.Context, courseID int64) ([]QuizR
 	return out, rows.Err()
 }
 
-func (r *Repo) CreateQuiz(ctx context.Context, courseID int64, title string, rulesRaw []byte) error {
-	if len(rulesRaw) == 0 {
-		return errors.New("правила квиза не могут быть пустыми")
+func (r *Repo) CreateQuiz(ctx context.Context, courseID int64, title string, rulesRaw []byte) (int64, error) {
+	if _, err := ParseQuizRules(rulesRaw); err != nil {
+		return 0, err
 	}
 
-	var rules QuizRules
-
-	// Используем Decoder с DisallowUnknownFields,
-	// чтобы ловить опечатки в ключах (unknown field ...).
-	dec := json.NewDecoder(bytes.NewReader(rulesRaw))
-	dec.DisallowUnknownFields()
+	// Сохраняем именно тот JSON, который ввёл админ
+	var id int64
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		if err := tx.QueryRowContext(ctx,
+			`INSERT INTO quizzes(course_id, title, rules) VALUES ($1,$2,$3) RETURNING id`,
+			courseID, title, rulesRaw,
+		).Scan(&id); err != nil {
+			return err
+		}
+		return audit(ctx, tx, "quiz.create", EntityQuiz, id, Diff{
+			"course_id": {New: courseID},
+			"title":     {New: title},
+			"rules":     {New: jsonValue(rulesRaw)},
+		})
+	})
+	return id, err
+}
 
-	if err := dec.Decode(&rules); err != nil {
-		return fmt.Errorf("invalid JSON в rules: %w", err)
+// UpdateQuiz меняет название и/или правила (пустые значения не трогают поле).
+func (r *Repo) UpdateQuiz(ctx context.Context, quizID int64, title string, rulesRaw []byte) error {
+	if len(rulesRaw) > 0 {
+		if _, err := ParseQuizRules(rulesRaw); err != nil {
+			return err
+		}
 	}
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		var oldTitle, curTitle string
+		var oldRules, curRules []byte
+		err := tx.QueryRowContext(ctx,
+			`SELECT title, rules FROM quizzes WHERE id=$1 FOR UPDATE`, quizID,
+		).Scan(&oldTitle, &oldRules)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if err := tx.QueryRowContext(ctx, `
+			UPDATE quizzes
+			   SET title = COALESCE(NULLIF($2,''), title),
+			       rules = COALESCE($3, rules)
+			 WHERE id=$1
+			RETURNING title, rules
+		`, quizID, title, nullBytes(rulesRaw)).Scan(&curTitle, &curRules); err != nil {
+			return err
+		}
+		diff := Diff{}
+		diff.set("title", oldTitle, curTitle)
+		diff.set("rules", jsonValue(oldRules), jsonValue(curRules))
+		if len(diff) == 0 {
+			return nil
+		}
+		return audit(ctx, tx, "quiz.update", EntityQuiz, quizID, diff)
+	})
+}
 
-	// Бизнес-валидация значений
-	if err := rules.Validate(); err != nil {
-		return err
+// nullBytes — пустой срез как NULL (для COALESCE в UPDATE).
+func nullBytes(b []byte) any {
+	if len(b) == 0 {
+		return nil
 	}
-
-	// Сохраняем именно тот JSON, который ввёл админ
-	_, err := r.DB.ExecContext(ctx,
-		`INSERT INTO quizzes(course_id, title, rules) VALUES ($1,$2,$3)`,
-		courseID, title, rulesRaw,
-	)
-	return err
+	return b
 }
 
 
 func (r *Repo) DeleteQuiz(ctx context.Context, quizID int64) error {
-	_, err := r.DB.ExecContext(ctx,
-		`DELETE FROM quizzes WHERE id=$1`,
-		quizID,
-	)
-	return err
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		var (
+			courseID int64
+			title    string
+			rules    []byte
+		)
+		err := tx.QueryRowContext(ctx,
+			`DELETE FROM quizzes WHERE id=$1 RETURNING course_id, title, rules`,
+			quizID,
+		).Scan(&courseID, &title, &rules)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		return audit(ctx, tx, "quiz.delete", EntityQuiz, quizID, Diff{
+			"course_id": {Old: courseID},
+			"title":     {Old: title},
+			"rules":     {Old: jsonValue(rules)},
+		})
+	})
 }
 
 /*** questions ***/
@@ -339,49 +673,6 @@ func toPGTextArray(a []string) string {
 	return "{" + strings.Join(parts, ",") + "}"
 }
 
-func (r *Repo) PickQuestions(ctx context.Context, courseID int64, rules *QuizRules) ([]QuestionRow, error) {
-	// считаем, сколько всего вопросов нужно
-	total := rules.Count
-	if total <= 0 {
-		// если Count не задан, пробуем сумму по типам
-		total = rules.CountSingle + rules.CountMultiple + rules.CountNumeric + rules.CountText
-	}
-	if total <= 0 {
-		// если вообще ничего не указано — дефолт
-		total = 10
-	}
-
-	const q = `
-		SELECT id, topic, qtype, difficulty, payload_json
-		FROM questions
-		WHERE course_id = $1
-		ORDER BY random()
-		LIMIT $2
-	`
-
-	rows, err := r.DB.QueryContext(ctx, q, courseID, total)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var out []QuestionRow
-	for rows.Next() {
-		var qr QuestionRow
-		if err := rows.Scan(&qr.ID, &qr.Topic, &qr.QType, &qr.Difficulty, &qr.Payload); err != nil {
-			return nil, err
-		}
-		out = append(out, qr)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-
-	return out, nil
-}
-
-
-
 func (r *Repo) FetchQuestionsByIDs(ctx context.Context, ids []int64) ([]QuestionRow, error) {
 	if len(ids) == 0 {
 		return nil, nil
@@ -421,6 +712,12 @@ func (r *Repo) FetchQuestionsByIDs(ctx context.Context, ids []int64) ([]Question
 }
 
 func (r *Repo) ListQuestions(ctx context.Context, courseID int64, topic, qtype string, limit int) ([]QuestionRow, error) {
+	out, _, err := r.ListQuestionsPage(ctx, courseID, topic, qtype, limit, 0)
+	return out, err
+}
+
+// ListQuestionsPage — страница вопросов курса и общее число подходящих под фильтр.
+func (r *Repo) ListQuestionsPage(ctx context.Context, courseID int64, topic, qtype string, limit, offset int) ([]QuestionRow, int, error) {
 	args := []interface{}{courseID}
 	where := []string{"course_id = $1"}
 
@@ -435,23 +732,24 @@ func (r *Repo) ListQuestions(ctx context.Context, courseID int64, topic, qtype s
 	if limit <= 0 {
 		limit = 100
 	}
-	args = append(args, limit)
+	args = append(args, limit, offset)
 
 	query := `
-		SELECT id, course_id, topic, qtype, difficulty, payload_json
+		SELECT id, course_id, topic, qtype, difficulty, payload_json, COUNT(*) OVER()
 		FROM questions
 		WHERE ` + strings.Join(where, " AND ") + `
 		ORDER BY id
-		LIMIT $` + strconv.Itoa(len(args)) + `
+		LIMIT $` + strconv.Itoa(len(args)-1) + ` OFFSET $` + strconv.Itoa(len(args)) + `
 	`
 
 	rows, err := r.DB.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer rows.Close()
 
 	var out []QuestionRow
+	total := 0
 	for rows.Next() {
 		var qr QuestionRow
 		if err := rows.Scan(
@@ -461,15 +759,16 @@ func (r *Repo) ListQuestions(ctx context.Context, courseID int64, topic, qtype s
 			&qr.QType,
 			&qr.Difficulty,
 			&qr.Payload,
+			&total,
 		); err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 		out = append(out, qr)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return nil, 0, err
 	}
-	return out, nil
+	return out, total, nil
 }
 
 func (r *Repo) GetQuestion(ctx context.Context, id int64) (*QuestionRow, error) {
@@ -500,21 +799,125 @@ func (r *Repo) UpdateQuestion(ctx context.Context, id int64, topic, qtype string
 		switch qtype {
 		case "single", "multiple", "numeric", "text":
 		default:
-			return fmt.Errorf("invalid qtype")
+			return userError("question.qtype", qtype)
 		}
 	}
 	if diff != 0 && (diff < 1 || diff > 5) {
-		return fmt.Errorf("invalid difficulty")
-	}
-	_, err := r.DB.ExecContext(ctx, `
-		UPDATE questions
-		   SET topic       = COALESCE(NULLIF($2,''), topic),
-		       qtype       = COALESCE(NULLIF($3,''), qtype),
-		       difficulty  = COALESCE(NULLIF($4,0), difficulty),
-		       payload_json = COALESCE($5, payload_json)
-		 WHERE id=$1
-	`, id, topic, qtype, diff, payload)
-	return err
+		return userError("question.difficulty")
+	}
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		var old, cur QuestionRow
+		err := tx.QueryRowContext(ctx,
+			`SELECT topic, qtype, difficulty, payload_json FROM questions WHERE id=$1 FOR UPDATE`, id,
+		).Scan(&old.Topic, &old.QType, &old.Difficulty, &old.Payload)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if err := tx.QueryRowContext(ctx, `
+			UPDATE questions
+			   SET topic       = COALESCE(NULLIF($2,''), topic),
+			       qtype       = COALESCE(NULLIF($3,''), qtype),
+			       difficulty  = COALESCE(NULLIF($4,0), difficulty),
+			       payload_json = COALESCE($5, payload_json)
+			 WHERE id=$1
+			RETURNING topic, qtype, difficulty, payload_json
+		`, id, topic, qtype, diff, payload).Scan(&cur.Topic, &cur.QType, &cur.Difficulty, &cur.Payload); err != nil {
+			return err
+		}
+		d := questionDiff(&old, &cur)
+		if len(d) == 0 {
+			return nil
+		}
+		return audit(ctx, tx, "question.update", EntityQuestion, id, d)
+	})
+}
+
+// questionDiff — изменённые поля вопроса; nil на месте old или cur —
+// создание или удаление.
+func questionDiff(old, cur *QuestionRow) Diff {
+	var o, c QuestionRow
+	if old != nil {
+		o = *old
+	}
+	if cur != nil {
+		c = *cur
+	}
+	field := func(isSet bool, v any) any {
+		if !isSet {
+			return nil
+		}
+		return v
+	}
+	d := Diff{}
+	d.set("topic", field(old != nil, o.Topic), field(cur != nil, c.Topic))
+	d.set("qtype", field(old != nil, o.QType), field(cur != nil, c.QType))
+	d.set("difficulty", field(old != nil, o.Difficulty), field(cur != nil, c.Difficulty))
+	d.set("payload", jsonValue(o.Payload), jsonValue(c.Payload))
+	return d
+}
+
+// ValidateQuestion проверяет поля вопроса перед сохранением.
+func ValidateQuestion(topic, qtype string, diff int, payload []byte) error {
+	if strings.TrimSpace(topic) == "" {
+		return userError("question.topic_empty")
+	}
+	switch qtype {
+	case "single", "multiple", "numeric", "text":
+	default:
+		return userError("question.qtype", qtype)
+	}
+	if diff < 1 || diff > 5 {
+		return userError("question.difficulty")
+	}
+	var obj map[string]any
+	if err := json.Unmarshal(payload, &obj); err != nil {
+		return userError("question.payload_object")
+	}
+	if _, ok := obj["text"].(string); !ok {
+		return userError("question.payload_text")
+	}
+	return nil
+}
+
+func (r *Repo) CreateQuestion(ctx context.Context, courseID int64, topic, qtype string, diff int, payload []byte) (int64, error) {
+	if err := ValidateQuestion(topic, qtype, diff, payload); err != nil {
+		return 0, err
+	}
+	var id int64
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		if err := tx.QueryRowContext(ctx,
+			`INSERT INTO questions(course_id, topic, difficulty, qtype, payload_json)
+			 VALUES ($1,$2,$3,$4,$5) RETURNING id`,
+			courseID, topic, diff, qtype, payload,
+		).Scan(&id); err != nil {
+			return err
+		}
+		d := questionDiff(nil, &QuestionRow{Topic: topic, QType: qtype, Difficulty: diff, Payload: payload})
+		d["course_id"] = Change{New: courseID}
+		return audit(ctx, tx, "question.create", EntityQuestion, id, d)
+	})
+	return id, err
+}
+
+func (r *Repo) DeleteQuestion(ctx context.Context, id int64) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		var old QuestionRow
+		err := tx.QueryRowContext(ctx,
+			`DELETE FROM questions WHERE id=$1 RETURNING course_id, topic, qtype, difficulty, payload_json`, id,
+		).Scan(&old.CourseID, &old.Topic, &old.QType, &old.Difficulty, &old.Payload)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		d := questionDiff(&old, nil)
+		d["course_id"] = Change{Old: old.CourseID}
+		return audit(ctx, tx, "question.delete", EntityQuestion, id, d)
+	})
 }
 
 /*** attempts & answers ***/
@@ -528,12 +931,108 @@ func (r *Repo) CreateAttempt(ctx context.Context, quizID, userID int64) (int64,
 	return id, err
 }
 
-func (r *Repo) SaveAnswer(ctx context.Context, attemptID, questionID int64, isCorrect *bool, answer []byte) error {
-	_, err := r.DB.ExecContext(ctx,
-		`INSERT INTO answers(attempt_id, question_id, is_correct, answer) VALUES ($1,$2,$3,$4)`,
-		attemptID, questionID, isCorrect, answer,
-	)
-	return err
+// StartAttempt создаёт попытку и фиксирует выданный набор вопросов
+// (порядок + снапшот payload) в одной транзакции. Предыдущая незавершённая
+// попытка того же квиза переводится в abandoned (с записью в журнал).
+func (r *Repo) StartAttempt(ctx context.Context, quizID, userID int64, qs []QuestionRow) (_ int64, err error) {
+	defer func() { err = traced(ctx, "StartAttempt", err) }()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// незавершённые попытки по этому квизу считаем брошенными
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id FROM attempts
+		 WHERE user_id=$1 AND quiz_id=$2 AND status=$3
+		 FOR UPDATE
+	`, userID, quizID, AttemptInProgress)
+	if err != nil {
+		return 0, err
+	}
+	var open []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		open = append(open, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	for _, id := range open {
+		if err := setAttemptStatus(ctx, tx, id, AttemptInProgress, AttemptAbandoned); err != nil {
+			return 0, err
+		}
+	}
+
+	var id int64
+	if err := tx.QueryRowContext(ctx,
+		`INSERT INTO attempts(quiz_id, user_id) VALUES ($1,$2) RETURNING id`,
+		quizID, userID,
+	).Scan(&id); err != nil {
+		return 0, err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO attempt_questions(attempt_id, ord, question_id, topic, qtype, difficulty, payload_json)
+		VALUES ($1,$2,$3,$4,$5,$6,$7)
+	`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	for i, q := range qs {
+		if _, err := stmt.ExecContext(ctx,
+			id, i+1, q.ID, q.Topic, q.QType, q.Difficulty, []byte(q.Payload),
+		); err != nil {
+			return 0, err
+		}
+	}
+
+	return id, tx.Commit()
+}
+
+// AttemptQuestion — вопрос, выданный в конкретной попытке.
+type AttemptQuestion struct {
+	Ord        int
+	QuestionID int64
+	Topic      string
+	QType      string
+	Difficulty int
+	Payload    json.RawMessage
+}
+
+// ListAttemptQuestions возвращает выданные в попытке вопросы в порядке выдачи.
+func (r *Repo) ListAttemptQuestions(ctx context.Context, attemptID int64) (_ []AttemptQuestion, err error) {
+	defer func() { err = traced(ctx, "ListAttemptQuestions", err) }()
+
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT ord, question_id, topic, qtype, difficulty, payload_json
+		FROM attempt_questions
+		WHERE attempt_id=$1
+		ORDER BY ord
+	`, attemptID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []AttemptQuestion
+	for rows.Next() {
+		var q AttemptQuestion
+		if err := rows.Scan(&q.Ord, &q.QuestionID, &q.Topic, &q.QType, &q.Difficulty, &q.Payload); err != nil {
+			return nil, err
+		}
+		out = append(out, q)
+	}
+	return out, rows.Err()
 }
 
 func (r *Repo) SetAttemptResult(ctx context.Context, attemptID int64, finishedAt *time.Time, score *float64) error {
@@ -544,12 +1043,214 @@ func (r *Repo) SetAttemptResult(ctx context.Context, attemptID int64, finishedAt
 	return err
 }
 
-func (r *Repo) SetAttemptTiming(ctx context.Context, attemptID int64, durationSec int, overtime bool) error {
-	_, err := r.DB.ExecContext(ctx,
-		`UPDATE attempts SET duration_sec=$2, overtime=$3 WHERE id=$1`,
-		attemptID, durationSec, overtime,
+/* состояния попытки */
+
+const (
+	AttemptInProgress = "in_progress"
+	AttemptSubmitted  = "submitted"
+	AttemptExpired    = "expired"   // пришла после лимита и была отклонена
+	AttemptAbandoned  = "abandoned" // брошена: пользователь начал новую попытку
+	AttemptVoided     = "voided"    // аннулирована преподавателем
+)
+
+// допустимые переходы состояний
+var attemptTransitions = map[string][]string{
+	AttemptInProgress: {AttemptSubmitted, AttemptExpired, AttemptAbandoned, AttemptVoided},
+	AttemptSubmitted:  {AttemptVoided},
+	AttemptExpired:    {AttemptVoided},
+	AttemptAbandoned:  {AttemptVoided},
+}
+
+var (
+	ErrAttemptNotFound   = errors.New("попытка не найдена")
+	ErrAttemptNotOwned   = userError("attempt.not_owned")
+	ErrAttemptFinished   = errors.New("попытка уже завершена")
+	ErrAttemptTransition = errors.New("недопустимая смена состояния попытки")
+)
+
+func canTransition(from, to string) bool {
+	for _, s := range attemptTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// lockAttempt блокирует строку попытки до конца транзакции и возвращает владельца и состояние.
+func lockAttempt(ctx context.Context, tx *sql.Tx, attemptID int64) (userID int64, status string, err error) {
+	err = tx.QueryRowContext(ctx,
+		`SELECT user_id, status FROM attempts WHERE id=$1 FOR UPDATE`,
+		attemptID,
+	).Scan(&userID, &status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, "", ErrAttemptNotFound
+	}
+	return userID, status, err
+}
+
+// AnswerInput — проверенный ответ на выданный вопрос.
+type AnswerInput struct {
+	QuestionID int64
+	IsCorrect  *bool
+	Answer     []byte
+}
+
+// Lateness — опоздание при сдаче: политика квиза на тот момент, на сколько
+// секунд превышен лимит и штраф. Фразу из этого собирает интерфейс на языке читателя.
+type Lateness struct {
+	Policy     string // late_policy квиза; пусто — не опоздал
+	Sec        int
+	PenaltyPct float64
+	Legacy     string // готовый текст у попыток, сданных до миграции 019
+}
+
+// Message — ключ каталога и аргументы причины опоздания; "" — для accept и
+// без опоздания (тогда причина — Legacy, если есть).
+func (x Lateness) Message() (string, []any) {
+	switch x.Policy {
+	case LateReject:
+		return "late.reject", []any{x.Sec}
+	case LateZero:
+		return "late.zero", []any{x.Sec}
+	case LatePenalty:
+		return "late.penalty", []any{strconv.FormatFloat(x.PenaltyPct, 'f', -1, 64), x.Sec}
+	}
+	return "", nil
+}
+
+// AttemptResult — всё, что фиксируется при сдаче попытки.
+type AttemptResult struct {
+	Status      string // submitted или expired
+	Answers     []AnswerInput
+	Score       float64
+	FinishedAt  time.Time
+	DurationSec int
+	Overtime    bool
+	Late        Lateness
+}
+
+// FinishAttempt сдаёт попытку: в одной транзакции проверяет владельца и
+// состояние (только in_progress), сохраняет ответы и итог.
+// Повторная сдача возвращает ErrAttemptFinished, ничего не меняя.
+func (r *Repo) FinishAttempt(ctx context.Context, attemptID, userID int64, res AttemptResult) (err error) {
+	defer func() { err = traced(ctx, "FinishAttempt", err) }()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	owner, status, err := lockAttempt(ctx, tx, attemptID)
+	if err != nil {
+		return err
+	}
+	if owner != userID {
+		return ErrAttemptNotOwned
+	}
+	if status != AttemptInProgress {
+		return ErrAttemptFinished
+	}
+	if !canTransition(status, res.Status) {
+		return ErrAttemptTransition
+	}
+
+	stmt, err := tx.PrepareContext(ctx,
+		`INSERT INTO answers(attempt_id, question_id, is_correct, answer) VALUES ($1,$2,$3,$4)`,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, an := range res.Answers {
+		if _, err := stmt.ExecContext(ctx, attemptID, an.QuestionID, an.IsCorrect, an.Answer); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE attempts
+		   SET status=$2, finished_at=$3, total_score=$4,
+		       duration_sec=$5, overtime=$6,
+		       late_policy=NULLIF($7,''), late_sec=NULLIF($8,0), late_penalty_pct=NULLIF($9,0)
+		 WHERE id=$1
+	`, attemptID, res.Status, res.FinishedAt, res.Score,
+		res.DurationSec, res.Overtime, res.Late.Policy, res.Late.Sec, res.Late.PenaltyPct,
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetAttemptStatus переводит попытку в новое состояние, если переход допустим
+// (например, преподаватель аннулирует попытку).
+func (r *Repo) SetAttemptStatus(ctx context.Context, attemptID int64, to string) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, status, err := lockAttempt(ctx, tx, attemptID)
+	if err != nil {
+		return err
+	}
+	if err := setAttemptStatus(ctx, tx, attemptID, status, to); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// setAttemptStatus переводит заблокированную попытку из from в to по
+// attemptTransitions и пишет переход в журнал.
+func setAttemptStatus(ctx context.Context, tx *sql.Tx, attemptID int64, from, to string) error {
+	if !canTransition(from, to) {
+		return ErrAttemptTransition
+	}
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE attempts SET status=$2 WHERE id=$1`,
+		attemptID, to,
+	); err != nil {
+		return err
+	}
+	return audit(ctx, tx, "attempt.status", EntityAttempt, attemptID, Diff{"status": {Old: from, New: to}})
+}
+
+// AttemptInfo — служебные поля попытки для проверки при сдаче.
+type AttemptInfo struct {
+	ID         int64
+	QuizID     int64
+	UserID     int64
+	Status     string
+	StartedAt  time.Time
+	FinishedAt *time.Time
+	Score      *float64
+	Late       Lateness
+}
+
+// lateColumns — поля Lateness в SELECT из attempts a, в порядке Lateness.dest.
+const lateColumns = `COALESCE(a.late_policy, ''), COALESCE(a.late_sec, 0), COALESCE(a.late_penalty_pct, 0), COALESCE(a.late_reason, '')`
+
+func (x *Lateness) dest() []any { return []any{&x.Policy, &x.Sec, &x.PenaltyPct, &x.Legacy} }
+
+func (r *Repo) GetAttempt(ctx context.Context, attemptID int64) (_ *AttemptInfo, err error) {
+	defer func() { err = traced(ctx, "GetAttempt", err) }()
+
+	var a AttemptInfo
+	err = r.DB.QueryRowContext(ctx, `
+		SELECT a.id, a.quiz_id, a.user_id, a.status, a.started_at, a.finished_at,
+		       a.total_score, `+lateColumns+`
+		FROM attempts a WHERE a.id=$1
+	`, attemptID,
+	).Scan(append([]any{&a.ID, &a.QuizID, &a.UserID, &a.Status, &a.StartedAt, &a.FinishedAt, &a.Score}, a.Late.dest()...)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAttemptNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
 }
 
 type AttemptRow struct {
@@ -558,6 +1259,7 @@ type AttemptRow struct {
 	QuizTitle  string
 	FinishedAt *time.Time
 	Score      *float64
+	Status     string
 }
 
 // ScoreVal — удобный геттер для вывода в шаблоне.
@@ -570,7 +1272,7 @@ func (a AttemptRow) ScoreVal() float64 {
 
 func (r *Repo) ListAttemptsByCourse(ctx context.Context, courseID int64) ([]AttemptRow, error) {
 	rows, err := r.DB.QueryContext(ctx, `
-		SELECT a.id, u.email, qz.title, a.finished_at, a.total_score
+		SELECT a.id, u.email, qz.title, a.finished_at, a.total_score, a.status
 		FROM attempts a
 		JOIN users   u  ON u.id  = a.user_id
 		JOIN quizzes qz ON qz.id = a.quiz_id
@@ -585,7 +1287,7 @@ func (r *Repo) ListAttemptsByCourse(ctx context.Context, courseID int64) ([]Atte
 	var out []AttemptRow
 	for rows.Next() {
 		var r0 AttemptRow
-		if err := rows.Scan(&r0.ID, &r0.UserEmail, &r0.QuizTitle, &r0.FinishedAt, &r0.Score); err != nil {
+		if err := rows.Scan(&r0.ID, &r0.UserEmail, &r0.QuizTitle, &r0.FinishedAt, &r0.Score, &r0.Status); err != nil {
 			return nil, err
 		}
 		out = append(out, r0)
@@ -604,6 +1306,8 @@ type AttemptMeta struct {
 	Score       *float64
 	DurationSec *int
 	Overtime    bool
+	Late        Lateness
+	Status      string
 }
 
 type AnswerDetail struct {
@@ -620,26 +1324,32 @@ func (r *Repo) GetAttemptWithAnswers(ctx context.Context, attemptID int64) (*Att
 	err := r.DB.QueryRowContext(ctx, `
 		SELECT a.id, u.email, qz.title,
 		       a.started_at, a.finished_at, a.total_score,
-		       a.duration_sec, a.overtime
+		       a.duration_sec, a.overtime, a.status, `+lateColumns+`
 		FROM attempts a
 		JOIN users   u  ON u.id  = a.user_id
 		JOIN quizzes qz ON qz.id = a.quiz_id
 		WHERE a.id=$1
-	`, attemptID).Scan(
+	`, attemptID).Scan(append([]any{
 		&meta.ID, &meta.UserEmail, &meta.QuizTitle,
 		&meta.StartedAt, &meta.FinishedAt, &meta.Score,
-		&meta.DurationSec, &meta.Overtime,
-	)
+		&meta.DurationSec, &meta.Overtime, &meta.Status,
+	}, meta.Late.dest()...)...)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	// payload берём из снапшота попытки, если он есть:
+	// вопрос могли отредактировать уже после прохождения
 	rows, err := r.DB.QueryContext(ctx, `
-		SELECT q.id, q.topic, q.qtype, q.payload_json, an.is_correct, an.answer
+		SELECT q.id, q.topic, q.qtype,
+		       COALESCE(aq.payload_json, q.payload_json),
+		       an.is_correct, an.answer
 		FROM answers   an
 		JOIN questions q ON q.id = an.question_id
+		LEFT JOIN attempt_questions aq
+		       ON aq.attempt_id = an.attempt_id AND aq.question_id = an.question_id
 		WHERE an.attempt_id=$1
-		ORDER BY an.id
+		ORDER BY aq.ord NULLS LAST, an.id
 	`, attemptID)
 	if err != nil {
 		return nil, nil, err
@@ -669,7 +1379,7 @@ func (r *Repo) GetAttemptWithAnswers(ctx context.Context, attemptID int64) (*Att
 func (r *Repo) TotalAttemptsByUserQuiz(ctx context.Context, userID, quizID int64) (int, error) {
 	var n int
 	err := r.DB.QueryRowContext(ctx,
-		`SELECT COUNT(*) FROM attempts WHERE user_id=$1 AND quiz_id=$2`,
+		`SELECT COUNT(*) FROM attempts WHERE user_id=$1 AND quiz_id=$2 AND status <> 'voided'`,
 		userID, quizID,
 	).Scan(&n)
 	return n, err
@@ -680,7 +1390,7 @@ func (r *Repo) AttemptsSinceByUserQuiz(ctx context.Context, userID, quizID int64
 	err := r.DB.QueryRowContext(ctx, `
 		SELECT COUNT(*)
 		FROM attempts
-		WHERE user_id=$1 AND quiz_id=$2 AND started_at >= $3
+		WHERE user_id=$1 AND quiz_id=$2 AND started_at >= $3 AND status <> 'voided'
 	`, userID, quizID, since).Scan(&n)
 	return n, err
 }
@@ -698,9 +1408,17 @@ type AttemptExportRow struct {
 	Score     *float64
 	Duration  *int
 	Overtime  bool
+	Status    string
 }
 
 func (r *Repo) ExportAttempts(ctx context.Context, courseID *int64, quizID *int64) ([]AttemptExportRow, error) {
+	out, _, err := r.ExportAttemptsPage(ctx, courseID, quizID, 0, 0)
+	return out, err
+}
+
+// ExportAttemptsPage — то же, что ExportAttempts, но постранично (limit 0 — все)
+// и с общим числом строк.
+func (r *Repo) ExportAttemptsPage(ctx context.Context, courseID *int64, quizID *int64, limit, offset int) ([]AttemptExportRow, int, error) {
 	sb := strings.Builder{}
 	args := []any{}
 	i := 1
@@ -708,7 +1426,7 @@ func (r *Repo) ExportAttempts(ctx context.Context, courseID *int64, quizID *int6
 	sb.WriteString(`
 		SELECT a.id, u.email, q.course_id, q.id, q.title,
 		       a.started_at, a.finished_at, a.total_score,
-		       a.duration_sec, a.overtime
+		       a.duration_sec, a.overtime, a.status, COUNT(*) OVER()
 		FROM attempts a
 		JOIN users   u ON u.id = a.user_id
 		JOIN quizzes q ON q.id = a.quiz_id
@@ -728,27 +1446,32 @@ func (r *Repo) ExportAttempts(ctx context.Context, courseID *int64, quizID *int6
 		sb.WriteString(" WHERE " + strings.Join(where, " AND "))
 	}
 	sb.WriteString(" ORDER BY a.id DESC")
+	if limit > 0 {
+		sb.WriteString(fmt.Sprintf(" LIMIT $%d OFFSET $%d", i, i+1))
+		args = append(args, limit, offset)
+	}
 
 	rows, err := r.DB.QueryContext(ctx, sb.String(), args...)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer rows.Close()
 
 	var out []AttemptExportRow
+	total := 0
 	for rows.Next() {
 		var r0 AttemptExportRow
 		if err := rows.Scan(
 			&r0.AttemptID, &r0.UserEmail,
 			&r0.CourseID, &r0.QuizID, &r0.QuizTitle,
 			&r0.StartedAt, &r0.FinishedAt, &r0.Score,
-			&r0.Duration, &r0.Overtime,
+			&r0.Duration, &r0.Overtime, &r0.Status, &total,
 		); err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 		out = append(out, r0)
 	}
-	return out, rows.Err()
+	return out, total, rows.Err()
 }
 
 /*** статистика по темам ***/
@@ -825,8 +1548,9 @@ func (r *Repo) TopicDetail(ctx context.Context, userID int64, topic string) ([]T
 
 /*** importers ***/
 
-func (r *Repo) ImportQuestionsCSV(ctx context.Context, reader *csv.Reader, courseID int64) (int, error) {
-	count := 0
+func (r *Repo) ImportQuestionsCSV(ctx context.Context, reader *csv.Reader, courseID int64) (count int, err error) {
+	defer func() { err = r.auditImport(ctx, "csv", courseID, count, err) }()
+
 	for {
 		rec, err := reader.Read()
 		if errors.Is(err, io.EOF) {
@@ -908,17 +1632,13 @@ func (r *Repo) ImportQuestionsCSV(ctx context.Context, reader *csv.Reader, cours
 }
 
 // JSON массив объектов: { "topic","qtype","difficulty","payload_json":{...} }
-func (r *Repo) ImportQuestionsJSON(ctx context.Context, raw []byte, courseID int64) (int, error) {
-	var items []struct {
-		Topic      string          `json:"topic"`
-		QType      string          `json:"qtype"`
-		Difficulty int             `json:"difficulty"`
-		Payload    json.RawMessage `json:"payload_json"`
-	}
+func (r *Repo) ImportQuestionsJSON(ctx context.Context, raw []byte, courseID int64) (n int, err error) {
+	defer func() { err = r.auditImport(ctx, "json", courseID, n, err) }()
+
+	var items []QuestionBankItem
 	if err := json.Unmarshal(raw, &items); err != nil {
 		return 0, fmt.Errorf("invalid JSON: %w", err)
 	}
-	n := 0
 	for _, it := range items {
 		if it.Topic == "" || it.QType == "" || len(it.Payload) == 0 {
 			return n, fmt.Errorf("missing fields in item #%d", n+1)
@@ -938,6 +1658,20 @@ func (r *Repo) ImportQuestionsJSON(ctx context.Context, raw []byte, courseID int
 	return n, nil
 }
 
+// auditImport пишет в журнал число импортированных вопросов. Импорт идёт
+// построчно без общей транзакции, поэтому запись делается и после ошибки
+// посередине файла: добавленные до неё вопросы уже в базе.
+func (r *Repo) auditImport(ctx context.Context, format string, courseID int64, n int, err error) error {
+	if n == 0 {
+		return err
+	}
+	if aerr := audit(ctx, r.DB, "question.import", EntityCourse, courseID,
+		Diff{"imported_" + format: {New: n}}); aerr != nil && err == nil {
+		return aerr
+	}
+	return err
+}
+
 /*** helpers ***/
 
 func splitComma(s string) []string {
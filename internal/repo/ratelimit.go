@@ -0,0 +1,78 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+/*** rate limit: хранилище счётчиков для ratelimit.Limiter ***/
+
+// RateHit атомарно увеличивает счётчик; истёкшее окно начинается заново.
+func (r *Repo) RateHit(ctx context.Context, key string, window time.Duration) (int, time.Time, error) {
+	var (
+		n     int
+		until time.Time
+	)
+	err := r.DB.QueryRowContext(ctx, `
+		INSERT INTO rate_limits(key, count, expires_at)
+		VALUES ($1, 1, now() + make_interval(secs => $2))
+		ON CONFLICT (key) DO UPDATE SET
+			count      = CASE WHEN rate_limits.expires_at <= now() THEN 1 ELSE rate_limits.count + 1 END,
+			expires_at = CASE WHEN rate_limits.expires_at <= now() THEN EXCLUDED.expires_at ELSE rate_limits.expires_at END
+		RETURNING count, expires_at
+	`, key, window.Seconds()).Scan(&n, &until)
+	return n, until, err
+}
+
+func (r *Repo) RatePeek(ctx context.Context, key string) (int, time.Time, error) {
+	var (
+		n     int
+		until time.Time
+	)
+	err := r.DB.QueryRowContext(ctx,
+		`SELECT count, expires_at FROM rate_limits WHERE key=$1 AND expires_at > now()`,
+		key,
+	).Scan(&n, &until)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, time.Time{}, nil
+	}
+	return n, until, err
+}
+
+func (r *Repo) RateReset(ctx context.Context, key string) error {
+	_, err := r.DB.ExecContext(ctx, `DELETE FROM rate_limits WHERE key=$1`, key)
+	return err
+}
+
+func (r *Repo) DeleteExpiredRateLimits(ctx context.Context) error {
+	_, err := r.DB.ExecContext(ctx, `DELETE FROM rate_limits WHERE expires_at <= now()`)
+	return err
+}
+
+/*** блокировка аккаунта ***/
+
+func (r *Repo) LockUser(ctx context.Context, userID int64, until time.Time) error {
+	_, err := r.DB.ExecContext(ctx, `UPDATE users SET locked_until=$2 WHERE id=$1`, userID, until)
+	return err
+}
+
+func (r *Repo) UnlockUser(ctx context.Context, userID int64) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		var old *time.Time
+		err := tx.QueryRowContext(ctx,
+			`SELECT locked_until FROM users WHERE id=$1 FOR UPDATE`, userID,
+		).Scan(&old)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil || old == nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE users SET locked_until=NULL WHERE id=$1`, userID); err != nil {
+			return err
+		}
+		return audit(ctx, tx, "user.unlock", EntityUser, userID, Diff{"locked_until": {Old: old, New: nil}})
+	})
+}
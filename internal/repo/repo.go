@@ -1,9 +1,52 @@
 package repo
 
-import "database/sql"
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"learny/internal/i18n"
+	"learny/internal/logx"
+)
 
 type Repo struct {
 	DB *sql.DB
 }
 
 func New(db *sql.DB) *Repo { return &Repo{DB: db} }
+
+// Error — ошибка для пользователя (правила квиза, поля вопроса и т.п.):
+// Key — ключ каталога i18n, Args — аргументы сообщения. Интерфейс показывает
+// её на языке пользователя (Locale.Err), Error() — текст на языке по умолчанию.
+type Error struct {
+	Key  string
+	Args []any
+}
+
+func userError(key string, args ...any) error { return &Error{Key: key, Args: args} }
+
+func (e *Error) Error() string            { return i18n.Default(e.Key, e.Args...) }
+func (e *Error) Message() (string, []any) { return e.Key, e.Args }
+
+// traced дописывает к неожиданной ошибке операцию и request id из ctx,
+// чтобы сбой в ответе или логе находился по журналу запросов.
+// Ожидаемые ошибки (не найдено, чужая попытка и т.п.) возвращаются как есть.
+func traced(ctx context.Context, op string, err error) error {
+	var short *NotEnoughQuestionsError
+	switch {
+	case err == nil,
+		errors.Is(err, sql.ErrNoRows),
+		errors.Is(err, ErrNotFound),
+		errors.Is(err, ErrAttemptNotFound),
+		errors.Is(err, ErrAttemptNotOwned),
+		errors.Is(err, ErrAttemptFinished),
+		errors.Is(err, ErrAttemptTransition),
+		errors.As(err, &short):
+		return err
+	}
+	if id := logx.RequestID(ctx); id != "" {
+		return fmt.Errorf("%s [request %s]: %w", op, id, err)
+	}
+	return fmt.Errorf("%s: %w", op, err)
+}
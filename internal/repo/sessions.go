@@ -0,0 +1,131 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+/*** sessions ***/
+
+type SessionRow struct {
+	ID         int64
+	UserID     int64
+	TokenHash  string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	ExpiresAt  time.Time
+	IP         string
+	UserAgent  string
+	CSRFToken  string
+}
+
+var ErrSessionNotFound = errors.New("сессия не найдена")
+
+func (r *Repo) CreateSession(ctx context.Context, s *SessionRow) error {
+	return r.DB.QueryRowContext(ctx, `
+		INSERT INTO sessions(user_id, token_hash, expires_at, ip, user_agent, csrf_token)
+		VALUES ($1,$2,$3,$4,$5,$6)
+		RETURNING id, created_at, last_seen_at
+	`, s.UserID, s.TokenHash, s.ExpiresAt, s.IP, s.UserAgent, s.CSRFToken,
+	).Scan(&s.ID, &s.CreatedAt, &s.LastSeenAt)
+}
+
+// SessionByTokenHash возвращает только действующую (не истёкшую) сессию.
+func (r *Repo) SessionByTokenHash(ctx context.Context, hash string) (*SessionRow, error) {
+	var s SessionRow
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT id, user_id, token_hash, created_at, last_seen_at, expires_at, ip, user_agent, csrf_token
+		FROM sessions
+		WHERE token_hash=$1 AND expires_at > now()
+	`, hash).Scan(&s.ID, &s.UserID, &s.TokenHash, &s.CreatedAt, &s.LastSeenAt, &s.ExpiresAt, &s.IP, &s.UserAgent, &s.CSRFToken)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// TouchSession отмечает активность и (при скользящем продлении) новый срок.
+func (r *Repo) TouchSession(ctx context.Context, id int64, lastSeen, expires time.Time) error {
+	_, err := r.DB.ExecContext(ctx,
+		`UPDATE sessions SET last_seen_at=$2, expires_at=$3 WHERE id=$1`,
+		id, lastSeen, expires,
+	)
+	return err
+}
+
+func (r *Repo) DeleteSessionByTokenHash(ctx context.Context, hash string) error {
+	_, err := r.DB.ExecContext(ctx, `DELETE FROM sessions WHERE token_hash=$1`, hash)
+	return err
+}
+
+// DeleteExpiredSessions чистит просроченные сессии.
+func (r *Repo) DeleteExpiredSessions(ctx context.Context) (int64, error) {
+	res, err := r.DB.ExecContext(ctx, `DELETE FROM sessions WHERE expires_at <= now()`)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// ListUserSessions — действующие сессии пользователя, свежие сверху.
+func (r *Repo) ListUserSessions(ctx context.Context, userID int64) ([]SessionRow, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT id, user_id, token_hash, created_at, last_seen_at, expires_at, ip, user_agent, csrf_token
+		FROM sessions
+		WHERE user_id=$1 AND expires_at > now()
+		ORDER BY last_seen_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []SessionRow
+	for rows.Next() {
+		var s SessionRow
+		if err := rows.Scan(&s.ID, &s.UserID, &s.TokenHash, &s.CreatedAt, &s.LastSeenAt, &s.ExpiresAt, &s.IP, &s.UserAgent, &s.CSRFToken); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+// DeleteUserSession отзывает одну сессию, только если она принадлежит пользователю.
+func (r *Repo) DeleteUserSession(ctx context.Context, userID, sessionID int64) error {
+	res, err := r.DB.ExecContext(ctx,
+		`DELETE FROM sessions WHERE id=$1 AND user_id=$2`,
+		sessionID, userID,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// DeleteUserSessions отзывает все сессии пользователя, кроме exceptID (0 — все).
+func (r *Repo) DeleteUserSessions(ctx context.Context, userID, exceptID int64) (int64, error) {
+	var n int64
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx,
+			`DELETE FROM sessions WHERE user_id=$1 AND id <> $2`,
+			userID, exceptID,
+		)
+		if err != nil {
+			return err
+		}
+		if n, err = res.RowsAffected(); err != nil || n == 0 {
+			return err
+		}
+		return audit(ctx, tx, "user.sessions_revoke", EntityUser, userID, Diff{"revoked_sessions": {New: n}})
+	})
+	return n, err
+}
@@ -0,0 +1,138 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+/*** одноразовые токены (сброс пароля, подтверждение email) ***/
+
+var ErrTokenInvalid = userError("token.invalid")
+
+// CreatePasswordReset сохраняет хэш токена сброса; прежние неиспользованные токены пользователя гасятся.
+func (r *Repo) CreatePasswordReset(ctx context.Context, userID int64, tokenHash string, expiresAt time.Time) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE password_resets SET used_at=now() WHERE user_id=$1 AND used_at IS NULL`,
+		userID,
+	); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO password_resets(user_id, token_hash, expires_at) VALUES ($1,$2,$3)`,
+		userID, tokenHash, expiresAt,
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CheckPasswordReset проверяет, что токен жив (для показа формы), не расходуя его.
+func (r *Repo) CheckPasswordReset(ctx context.Context, tokenHash string) error {
+	var id int64
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT id FROM password_resets
+		WHERE token_hash=$1 AND used_at IS NULL AND expires_at > now()
+	`, tokenHash).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrTokenInvalid
+	}
+	return err
+}
+
+// ConsumePasswordReset в одной транзакции гасит токен, меняет пароль
+// и завершает все сессии пользователя. Возвращает id пользователя.
+func (r *Repo) ConsumePasswordReset(ctx context.Context, tokenHash, newPassHash string) (int64, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var id, userID int64
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, user_id FROM password_resets
+		WHERE token_hash=$1 AND used_at IS NULL AND expires_at > now()
+		FOR UPDATE
+	`, tokenHash).Scan(&id, &userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrTokenInvalid
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE password_resets SET used_at=now() WHERE id=$1`, id); err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE users SET pass_hash=$2 WHERE id=$1`, userID, newPassHash); err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM sessions WHERE user_id=$1`, userID); err != nil {
+		return 0, err
+	}
+	return userID, tx.Commit()
+}
+
+// CreateEmailVerification сохраняет хэш токена подтверждения email; прежние токены гасятся.
+func (r *Repo) CreateEmailVerification(ctx context.Context, userID int64, tokenHash string, expiresAt time.Time) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE email_verifications SET used_at=now() WHERE user_id=$1 AND used_at IS NULL`,
+		userID,
+	); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO email_verifications(user_id, token_hash, expires_at) VALUES ($1,$2,$3)`,
+		userID, tokenHash, expiresAt,
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ConsumeEmailVerification гасит токен и отмечает email пользователя подтверждённым.
+func (r *Repo) ConsumeEmailVerification(ctx context.Context, tokenHash string) (int64, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var id, userID int64
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, user_id FROM email_verifications
+		WHERE token_hash=$1 AND used_at IS NULL AND expires_at > now()
+		FOR UPDATE
+	`, tokenHash).Scan(&id, &userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrTokenInvalid
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE email_verifications SET used_at=now() WHERE id=$1`, id); err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE users SET email_verified_at = COALESCE(email_verified_at, now()) WHERE id=$1`,
+		userID,
+	); err != nil {
+		return 0, err
+	}
+	return userID, tx.Commit()
+}
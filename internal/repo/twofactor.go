@@ -0,0 +1,218 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+/*** двухфакторная аутентификация (TOTP) ***/
+
+// TOTPState — состояние 2FA пользователя. Secret может быть задан при
+// пустом EnabledAt: подключение начато, но ещё не подтверждено кодом.
+type TOTPState struct {
+	Secret    string
+	EnabledAt *time.Time
+	LastStep  int64
+}
+
+func (t TOTPState) Enabled() bool { return t.EnabledAt != nil }
+
+func (r *Repo) GetTOTP(ctx context.Context, userID int64) (*TOTPState, error) {
+	var (
+		st     TOTPState
+		secret sql.NullString
+		step   sql.NullInt64
+	)
+	err := r.DB.QueryRowContext(ctx,
+		`SELECT totp_secret, totp_enabled_at, totp_last_step FROM users WHERE id=$1`,
+		userID,
+	).Scan(&secret, &st.EnabledAt, &step)
+	if err != nil {
+		return nil, err
+	}
+	st.Secret = secret.String
+	st.LastStep = step.Int64
+	return &st, nil
+}
+
+// ErrTOTPNotPending — подтверждать нечего: 2FA уже включена или подключение не начато.
+var ErrTOTPNotPending = userError("2fa.not_pending")
+
+// BeginTOTP сохраняет новый секрет для подключения. У уже включённой 2FA секрет не трогаем.
+func (r *Repo) BeginTOTP(ctx context.Context, userID int64, secret string) error {
+	_, err := r.DB.ExecContext(ctx,
+		`UPDATE users SET totp_secret=$2, totp_last_step=NULL
+         WHERE id=$1 AND totp_enabled_at IS NULL`,
+		userID, secret,
+	)
+	return err
+}
+
+// EnableTOTP подтверждает подключение и выдаёт новый набор кодов восстановления.
+func (r *Repo) EnableTOTP(ctx context.Context, userID, step int64, codeHashes []string) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx,
+		`UPDATE users SET totp_enabled_at=now(), totp_last_step=$2
+         WHERE id=$1 AND totp_secret IS NOT NULL AND totp_enabled_at IS NULL`,
+		userID, step,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrTOTPNotPending
+	}
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DisableTOTP выключает 2FA и удаляет коды восстановления.
+func (r *Repo) DisableTOTP(ctx context.Context, userID int64) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var enabledAt *time.Time
+	err = tx.QueryRowContext(ctx,
+		`SELECT totp_enabled_at FROM users WHERE id=$1 FOR UPDATE`, userID,
+	).Scan(&enabledAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE users SET totp_secret=NULL, totp_enabled_at=NULL, totp_last_step=NULL WHERE id=$1`,
+		userID,
+	); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id=$1`, userID); err != nil {
+		return err
+	}
+	// незавершённое подключение (секрет без enabled_at) в журнал не пишем
+	if enabledAt != nil {
+		if err := audit(ctx, tx, "user.2fa_disable", EntityUser, userID,
+			Diff{"totp_enabled_at": {Old: enabledAt, New: nil}}); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// UseTOTPStep запоминает принятый шаг. false — код этого или более позднего шага
+// уже использовался (повтор перехваченного кода).
+func (r *Repo) UseTOTPStep(ctx context.Context, userID, step int64) (bool, error) {
+	res, err := r.DB.ExecContext(ctx,
+		`UPDATE users SET totp_last_step=$2
+         WHERE id=$1 AND (totp_last_step IS NULL OR totp_last_step < $2)`,
+		userID, step,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userID int64, codeHashes []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id=$1`, userID); err != nil {
+		return err
+	}
+	for _, h := range codeHashes {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO recovery_codes(user_id, code_hash) VALUES ($1,$2)`,
+			userID, h,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReplaceRecoveryCodes — новый набор кодов восстановления вместо старого.
+func (r *Repo) ReplaceRecoveryCodes(ctx context.Context, userID int64, codeHashes []string) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UseRecoveryCode гасит код восстановления; false — такого неиспользованного кода нет.
+func (r *Repo) UseRecoveryCode(ctx context.Context, userID int64, codeHash string) (bool, error) {
+	res, err := r.DB.ExecContext(ctx,
+		`UPDATE recovery_codes SET used_at=now()
+         WHERE user_id=$1 AND code_hash=$2 AND used_at IS NULL`,
+		userID, codeHash,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *Repo) CountRecoveryCodes(ctx context.Context, userID int64) (int, error) {
+	var n int
+	err := r.DB.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM recovery_codes WHERE user_id=$1 AND used_at IS NULL`,
+		userID,
+	).Scan(&n)
+	return n, err
+}
+
+/*** второй шаг входа ***/
+
+// MaxChallengeAttempts — сколько неверных кодов можно ввести, прежде чем придётся заново вводить пароль.
+const MaxChallengeAttempts = 5
+
+// CreateLoginChallenge — пароль проверен, ждём второй фактор.
+func (r *Repo) CreateLoginChallenge(ctx context.Context, userID int64, tokenHash string, expiresAt time.Time) error {
+	_, err := r.DB.ExecContext(ctx,
+		`INSERT INTO login_challenges(user_id, token_hash, expires_at) VALUES ($1,$2,$3)`,
+		userID, tokenHash, expiresAt,
+	)
+	return err
+}
+
+// LoginChallengeUser возвращает пользователя по живому челленджу и учитывает попытку.
+func (r *Repo) LoginChallengeUser(ctx context.Context, tokenHash string) (int64, error) {
+	var userID int64
+	err := r.DB.QueryRowContext(ctx, `
+		UPDATE login_challenges SET attempts = attempts + 1
+		WHERE token_hash=$1 AND expires_at > now() AND attempts < $2
+		RETURNING user_id
+	`, tokenHash, MaxChallengeAttempts).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrTokenInvalid
+	}
+	return userID, err
+}
+
+func (r *Repo) DeleteLoginChallenge(ctx context.Context, tokenHash string) error {
+	_, err := r.DB.ExecContext(ctx, `DELETE FROM login_challenges WHERE token_hash=$1`, tokenHash)
+	return err
+}
+
+func (r *Repo) DeleteExpiredLoginChallenges(ctx context.Context) error {
+	_, err := r.DB.ExecContext(ctx, `DELETE FROM login_challenges WHERE expires_at <= now()`)
+	return err
+}
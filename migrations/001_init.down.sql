@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS answers;
+DROP TABLE IF EXISTS attempts;
+DROP TABLE IF EXISTS questions;
+DROP TABLE IF EXISTS quizzes;
+DROP TABLE IF EXISTS courses;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS roles;
@@ -66,14 +66,6 @@ CREATE TABLE IF NOT EXISTS attempts (
   total_score DOUBLE PRECISION
 );
 
--- duration + overtime columns (002 migration)
-ALTER TABLE attempts
-  ADD COLUMN IF NOT EXISTS duration_sec INT,
-  ADD COLUMN IF NOT EXISTS overtime BOOLEAN DEFAULT false;
-
-CREATE INDEX IF NOT EXISTS idx_attempts_user_quiz ON attempts (user_id, quiz_id);
-CREATE INDEX IF NOT EXISTS idx_attempts_finished_at ON attempts (finished_at);
-
 
 -- ========== ANSWERS ==========
 CREATE TABLE IF NOT EXISTS answers (
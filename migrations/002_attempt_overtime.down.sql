@@ -0,0 +1,3 @@
+ALTER TABLE attempts
+  DROP COLUMN IF EXISTS duration_sec,
+  DROP COLUMN IF EXISTS overtime;
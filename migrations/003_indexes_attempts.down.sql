@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_attempts_user_quiz;
+DROP INDEX IF EXISTS idx_attempts_finished_at;
@@ -0,0 +1,12 @@
+-- набор вопросов, выданный в попытке (снапшот payload на момент старта)
+CREATE TABLE IF NOT EXISTS attempt_questions (
+  attempt_id   BIGINT NOT NULL REFERENCES attempts(id) ON DELETE CASCADE,
+  ord          INT    NOT NULL,
+  question_id  BIGINT NOT NULL REFERENCES questions(id) ON DELETE CASCADE,
+  topic        TEXT   NOT NULL,
+  qtype        TEXT   NOT NULL,
+  difficulty   INT    NOT NULL,
+  payload_json JSONB  NOT NULL,
+  PRIMARY KEY (attempt_id, ord),
+  UNIQUE (attempt_id, question_id)
+);
@@ -0,0 +1,3 @@
+-- причина, по которой к попытке применена политика опоздания (reject/zero/penalty)
+ALTER TABLE attempts
+  ADD COLUMN IF NOT EXISTS late_reason TEXT;
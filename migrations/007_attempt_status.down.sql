@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_attempts_user_quiz_status;
+ALTER TABLE attempts DROP COLUMN IF EXISTS status;
@@ -0,0 +1,9 @@
+-- состояние попытки: in_progress -> submitted | expired | abandoned, любое -> voided
+ALTER TABLE attempts
+  ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'in_progress'
+    CHECK (status IN ('in_progress','submitted','expired','abandoned','voided'));
+
+UPDATE attempts SET status = 'submitted'
+ WHERE finished_at IS NOT NULL AND status = 'in_progress';
+
+CREATE INDEX IF NOT EXISTS idx_attempts_user_quiz_status ON attempts (user_id, quiz_id, status);
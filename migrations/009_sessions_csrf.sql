@@ -0,0 +1,3 @@
+-- CSRF-токен, привязанный к сессии
+ALTER TABLE sessions
+  ADD COLUMN IF NOT EXISTS csrf_token TEXT NOT NULL DEFAULT encode(gen_random_bytes(32), 'hex');
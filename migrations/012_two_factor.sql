@@ -0,0 +1,22 @@
+-- TOTP 2FA: секрет (до подтверждения enabled_at пустой), последний принятый шаг
+ALTER TABLE users
+  ADD COLUMN IF NOT EXISTS totp_secret     TEXT,
+  ADD COLUMN IF NOT EXISTS totp_enabled_at TIMESTAMPTZ,
+  ADD COLUMN IF NOT EXISTS totp_last_step  BIGINT;
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+  id        BIGSERIAL PRIMARY KEY,
+  user_id   BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  code_hash TEXT   NOT NULL,
+  used_at   TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_recovery_codes_user ON recovery_codes (user_id);
+
+-- второй шаг входа: пароль уже проверен, ждём код
+CREATE TABLE IF NOT EXISTS login_challenges (
+  id         BIGSERIAL PRIMARY KEY,
+  user_id    BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  token_hash TEXT   NOT NULL UNIQUE,
+  expires_at TIMESTAMPTZ NOT NULL,
+  attempts   INT NOT NULL DEFAULT 0
+);
@@ -0,0 +1,2 @@
+ALTER TABLE users DROP COLUMN IF EXISTS locked_until;
+DROP TABLE IF EXISTS rate_limits;
@@ -0,0 +1,10 @@
+-- счётчики rate limit: ключ вида "login:1.2.3.4", фиксированное окно до expires_at
+CREATE TABLE IF NOT EXISTS rate_limits (
+  key        TEXT PRIMARY KEY,
+  count      INT  NOT NULL,
+  expires_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_rate_limits_expires ON rate_limits (expires_at);
+
+-- блокировка аккаунта после серии неудачных входов
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locked_until TIMESTAMPTZ;
@@ -0,0 +1,12 @@
+-- персональные API-токены: в БД только sha256, токен показывается один раз
+CREATE TABLE IF NOT EXISTS api_tokens (
+  id           BIGSERIAL PRIMARY KEY,
+  user_id      BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name         TEXT   NOT NULL,
+  token_hash   TEXT   NOT NULL UNIQUE,
+  scopes       TEXT[] NOT NULL DEFAULT '{}',
+  created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+  last_used_at TIMESTAMPTZ,
+  expires_at   TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_api_tokens_user ON api_tokens (user_id);
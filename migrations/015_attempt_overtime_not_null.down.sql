@@ -0,0 +1 @@
+ALTER TABLE attempts ALTER COLUMN overtime DROP NOT NULL;
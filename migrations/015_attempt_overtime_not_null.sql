@@ -0,0 +1,6 @@
+-- в базах, созданных старой 001, overtime остался nullable (ALTER из 001 опережал 002)
+UPDATE attempts SET overtime = FALSE WHERE overtime IS NULL;
+
+ALTER TABLE attempts
+  ALTER COLUMN overtime SET DEFAULT FALSE,
+  ALTER COLUMN overtime SET NOT NULL;
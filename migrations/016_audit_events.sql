@@ -0,0 +1,18 @@
+-- журнал административных действий: кто, что и над чем сделал, с разницей
+-- значений до/после. actor — снимок email (или метка CLI): запись остаётся
+-- понятной и после удаления пользователя.
+CREATE TABLE IF NOT EXISTS audit_events (
+  id          BIGSERIAL PRIMARY KEY,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+  actor_id    BIGINT REFERENCES users(id) ON DELETE SET NULL,
+  actor       TEXT  NOT NULL DEFAULT '',
+  action      TEXT  NOT NULL,
+  entity_type TEXT  NOT NULL,
+  entity_id   BIGINT,
+  diff        JSONB NOT NULL DEFAULT '{}',
+  ip          TEXT  NOT NULL DEFAULT '',
+  request_id  TEXT  NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS idx_audit_events_created ON audit_events (created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_events_actor   ON audit_events (actor_id);
+CREATE INDEX IF NOT EXISTS idx_audit_events_entity  ON audit_events (entity_type, entity_id);
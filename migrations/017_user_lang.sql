@@ -0,0 +1,2 @@
+-- язык интерфейса, выбранный пользователем; NULL — по Accept-Language браузера
+ALTER TABLE users ADD COLUMN IF NOT EXISTS lang TEXT;
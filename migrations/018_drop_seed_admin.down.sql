@@ -0,0 +1 @@
+-- админа с известным паролем обратно не заводим
@@ -0,0 +1,10 @@
+-- 004 заводила admin@learny.local с паролем admin123. Файл 004 не меняем
+-- (его checksum уже записан в применённых базах), а сид убираем здесь:
+-- на новой установке админа не остаётся, на старой — только если пароль
+-- так и не сменили. Администратора создаёт learnyctl create-admin.
+DELETE FROM users
+WHERE email = 'admin@learny.local'
+  AND CASE WHEN pass_hash LIKE '$2a$%' -- crypt() падает на хеше не-bcrypt
+        THEN pass_hash = crypt('admin123', pass_hash)
+        ELSE FALSE
+      END;
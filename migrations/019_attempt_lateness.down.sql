@@ -0,0 +1,4 @@
+ALTER TABLE attempts
+  DROP COLUMN IF EXISTS late_policy,
+  DROP COLUMN IF EXISTS late_sec,
+  DROP COLUMN IF EXISTS late_penalty_pct;
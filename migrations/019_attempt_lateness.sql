@@ -0,0 +1,6 @@
+-- опоздание храним фактами, а не готовой фразой: текст собирается на языке
+-- того, кто смотрит попытку. late_reason остаётся у попыток, сданных раньше.
+ALTER TABLE attempts
+  ADD COLUMN IF NOT EXISTS late_policy      TEXT,
+  ADD COLUMN IF NOT EXISTS late_sec         INT,
+  ADD COLUMN IF NOT EXISTS late_penalty_pct DOUBLE PRECISION;
@@ -0,0 +1,8 @@
+// Package migrations встраивает SQL-миграции в бинарь.
+// NNN_name.sql — применение, NNN_name.down.sql — откат.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
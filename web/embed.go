@@ -0,0 +1,8 @@
+// Package web встраивает шаблоны страниц и статику в бинарь.
+// Каталоги на диске (template_dir, static_dir) нужны только при разработке.
+package web
+
+import "embed"
+
+//go:embed templates static
+var FS embed.FS